@@ -2,19 +2,27 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"Aoiler/services"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type App struct {
 	ctx            context.Context
 	serviceManager *services.ServiceManager
 	fileSearch     *services.FileSearchService
+	socket         *socketServer
 }
 
 type QueryRequest struct {
 	Query string `json:"query"`
+	// Confirm, sent instead of a query, runs the desktop change waiting
+	// under this pending ID
+	Confirm string `json:"confirm,omitempty"`
 }
 
 type QueryResponse struct {
@@ -22,6 +30,9 @@ type QueryResponse struct {
 	Service string      `json:"service"`
 	Result  interface{} `json:"result"`
 	Error   string      `json:"error,omitempty"`
+	JobID   string      `json:"jobId,omitempty"`
+	// Summary is the result in one line, for the CLI and other plain clients
+	Summary string `json:"summary,omitempty"`
 }
 
 // NewApp creates a new App application struct
@@ -35,37 +46,291 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	// a.services = services.NewServiceManager()
+	a.serviceManager.SetEmitter(func(name string, data interface{}) {
+		runtime.EventsEmit(ctx, name, data)
+		// A recording without a duration only ends from its stop button,
+		// so the window comes back while it runs
+		if fields, ok := data.(map[string]interface{}); ok && name == "recording:start" && fields["duration"] == 0.0 {
+			runtime.WindowShow(ctx)
+		}
+	})
+
+	// Serve the socket too, so CLI queries share this window's jobs and history
+	socket, err := listenSocket(a, socketPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	a.socket = socket
+	go socket.serve()
+}
+
+// shutdown is called when the window closes
+func (a *App) shutdown(ctx context.Context) {
+	// wf-recorder would otherwise keep recording after the window is gone
+	a.serviceManager.Screenshot().StopAll()
+	if a.socket != nil {
+		a.socket.close()
+	}
 }
 
-// ProcessQuery handles the main query processing
+// ProcessQuery runs a query as a job and waits for it to finish
 func (a *App) ProcessQuery(req QueryRequest) QueryResponse {
-	intent := a.serviceManager.ClassifyIntent(req.Query)
-	result, err := a.serviceManager.RouteToService(intent, req.Query)
+	job := a.serviceManager.Submit(req.Query)
+	job, err := a.serviceManager.Jobs().Wait(context.Background(), job.ID)
+	if err != nil {
+		return QueryResponse{
+			Success: false,
+			Service: job.Service,
+			Error:   err.Error(),
+			JobID:   job.ID,
+		}
+	}
+	return jobResponse(job)
+}
+
+// StartQuery runs a query in the background and returns its job right away.
+// Progress and the result arrive as "job:update" events. Screenshots and
+// recordings hide the window first so it isn't captured, and show it again
+// once they finish.
+func (a *App) StartQuery(req QueryRequest) services.JobInfo {
+	if a.ctx == nil || !a.serviceManager.HidesWindow(req.Query) {
+		return a.serviceManager.Submit(req.Query)
+	}
+
+	runtime.WindowHide(a.ctx)
+	job := a.serviceManager.Submit(req.Query)
+	go func() {
+		a.serviceManager.Jobs().Wait(context.Background(), job.ID)
+		runtime.WindowShow(a.ctx)
+	}()
+	return job
+}
+
+// ListJobs returns running and recent jobs for the jobs panel, newest first
+func (a *App) ListJobs() []services.JobInfo {
+	return a.serviceManager.Jobs().List()
+}
+
+// GetJob returns a job with its result as a query response
+func (a *App) GetJob(id string) QueryResponse {
+	job, err := a.serviceManager.Jobs().Get(id)
+	if err != nil {
+		return QueryResponse{Success: false, Error: err.Error(), JobID: id}
+	}
+	return jobResponse(job)
+}
+
+// CancelJob stops a running query, killing whatever tool it started
+func (a *App) CancelJob(id string) bool {
+	return a.serviceManager.Jobs().Cancel(id)
+}
+
+// SearchHistory finds past queries containing every term, pinned first
+func (a *App) SearchHistory(terms string) (services.QueryHistoryResult, error) {
+	return a.serviceManager.History().Search(terms)
+}
+
+// RecentQueries returns distinct past queries, newest first, for arrow-key
+// navigation in the input
+func (a *App) RecentQueries(limit int) ([]string, error) {
+	return a.serviceManager.History().Recent(limit)
+}
+
+// PinQuery marks a past query as a favorite, or unmarks it
+func (a *App) PinQuery(id string, pinned bool) (bool, error) {
+	return a.serviceManager.History().Pin(id, pinned)
+}
+
+// DeleteQuery removes a past query from the history
+func (a *App) DeleteQuery(id string) (bool, error) {
+	return a.serviceManager.History().Delete(id)
+}
+
+// GetSuggestions returns query patterns matching what's typed so far, or
+// featured ones for an empty input
+func (a *App) GetSuggestions(partial string) []services.QuerySuggestion {
+	return a.serviceManager.Help().GetSuggestions(partial)
+}
+
+// DetectTypos returns the query with misspelled service keywords corrected,
+// or nothing when it looks right
+func (a *App) DetectTypos(query string) []string {
+	return a.serviceManager.Help().DetectTypos(query)
+}
+
+// GetExamples returns example queries of every service by category
+func (a *App) GetExamples() []services.ExampleQueries {
+	return a.serviceManager.Help().GetExamplesByCategory()
+}
+
+// GetQuickHelp returns the command overview shown for "help"
+func (a *App) GetQuickHelp() string {
+	return a.serviceManager.Help().GetQuickHelp()
+}
+
+func jobResponse(job services.JobInfo) QueryResponse {
+	return QueryResponse{
+		Success: job.Status == services.JobSucceeded,
+		Service: job.Service,
+		Result:  job.Result,
+		Error:   job.Error,
+		JobID:   job.ID,
+		Summary: services.SummarizeResult(job.Result),
+	}
+}
 
+// ApplyFormat writes a previewed formatter change to disk
+func (a *App) ApplyFormat(pendingID string) QueryResponse {
+	result, err := a.serviceManager.Linter().ApplyFormat(pendingID)
 	if err != nil {
 		return QueryResponse{
 			Success: false,
-			Service: intent.ServiceName,
+			Service: "linter",
 			Error:   err.Error(),
 		}
 	}
 
 	return QueryResponse{
 		Success: true,
-		Service: intent.ServiceName,
+		Service: "linter",
 		Result:  result,
 	}
 }
 
-// GetAvailableServices returns list of available services
+// DiscardFormat drops a previewed formatter change
+func (a *App) DiscardFormat(pendingID string) bool {
+	return a.serviceManager.Linter().DiscardFormat(pendingID)
+}
+
+// CopyToClipboard copies text from a result to the system clipboard
+func (a *App) CopyToClipboard(text string) error {
+	return services.CopyToClipboard(text)
+}
+
+// OpenURL opens a link found in a result in the default browser
+func (a *App) OpenURL(url string) error {
+	return services.OpenURL(url)
+}
+
+// OCRTable rebuilds a table from OCR word boxes as "csv" or "markdown"
+func (a *App) OCRTable(words []services.OCRWord, format string) (string, error) {
+	return services.BuildTable(words, format)
+}
+
+// DeleteOCRHistory removes a stored OCR capture
+func (a *App) DeleteOCRHistory(id string) (bool, error) {
+	return a.serviceManager.OCR().DeleteHistory(id)
+}
+
+// CopyClip puts a clipboard history entry back on the clipboard
+func (a *App) CopyClip(id string) error {
+	return a.serviceManager.Clipboard().Copy(context.Background(), id)
+}
+
+// PasteClip hides the window so the previous one gets focus back, then
+// pastes a clipboard history entry into it
+func (a *App) PasteClip(id string) error {
+	if a.ctx != nil {
+		runtime.WindowHide(a.ctx)
+	}
+	return a.serviceManager.Clipboard().Paste(context.Background(), id)
+}
+
+// PinClip keeps a clipboard entry after cliphist drops it, or unpins it
+func (a *App) PinClip(id string, pinned bool) (bool, error) {
+	return a.serviceManager.Clipboard().Pin(context.Background(), id, pinned)
+}
+
+// DeleteClip removes an entry from the clipboard history
+func (a *App) DeleteClip(id string) (bool, error) {
+	return a.serviceManager.Clipboard().Delete(context.Background(), id)
+}
+
+// LaunchApp starts another of a launcher result's matches
+func (a *App) LaunchApp(id string) error {
+	return a.serviceManager.Launcher().LaunchApp(context.Background(), id)
+}
+
+// ConfirmDesktopAction runs a disruptive desktop change, like a theme switch,
+// that the user confirmed
+func (a *App) ConfirmDesktopAction(pendingID string) QueryResponse {
+	result, err := a.serviceManager.Desktop().ConfirmAction(context.Background(), pendingID)
+	if err != nil {
+		return QueryResponse{
+			Success: false,
+			Service: "desktop",
+			Result:  result,
+			Error:   err.Error(),
+		}
+	}
+
+	return QueryResponse{
+		Success: true,
+		Service: "desktop",
+		Result:  result,
+		Summary: services.SummarizeResult(result),
+	}
+}
+
+// DiscardDesktopAction drops a desktop change waiting for confirmation
+func (a *App) DiscardDesktopAction(pendingID string) bool {
+	return a.serviceManager.Desktop().DiscardAction(pendingID)
+}
+
+// StopRecording ends a running screen recording and keeps the file, unlike
+// cancelling its job
+func (a *App) StopRecording(id string) bool {
+	return a.serviceManager.Screenshot().StopRecording(id)
+}
+
+// PasteNote hides the window so the previous one gets focus back, then
+// pastes a note or snippet into it
+func (a *App) PasteNote(id string) error {
+	note, err := a.serviceManager.Notes().Get(id)
+	if err != nil {
+		return err
+	}
+	if a.ctx != nil {
+		runtime.WindowHide(a.ctx)
+	}
+	return a.serviceManager.Clipboard().PasteText(context.Background(), note.Content)
+}
+
+// DeleteNote removes a note or snippet file
+func (a *App) DeleteNote(id string) (bool, error) {
+	return a.serviceManager.Notes().Delete(id)
+}
+
+// CancelConversion stops a running ffmpeg job and removes its partial output.
+// Given a batch run ID it stops every file in the batch.
+func (a *App) CancelConversion(id string) bool {
+	return a.serviceManager.Converter().Cancel(id)
+}
+
+// GetAvailableServices returns list of available services. The document
+// service lists its conversions with the tools each one is missing.
 func (a *App) GetAvailableServices() []ServiceInfo {
+	document := a.serviceManager.Document()
 	return []ServiceInfo{
-		{Name: "filesearch", Description: "Find files and directories"},
-		{Name: "organizer", Description: "Organize files with Tyr"},
-		{Name: "linter", Description: "Lint and format code files"},
-		{Name: "ocr", Description: "Extract text from screen area"},
-		{Name: "converter", Description: "Convert media files with ffmpeg"},
-		{Name: "llm", Description: "Query LLM for assistance"},
+		{Name: "filesearch", Description: "Find files and directories", Available: true},
+		{Name: "organizer", Description: "Organize files with Tyr", Available: true},
+		{Name: "linter", Description: "Lint and format code files", Available: true},
+		{Name: "ocr", Description: "Extract text from screen area", Available: true},
+		{Name: "converter", Description: "Convert media files with ffmpeg", Available: true},
+		{
+			Name:        "document",
+			Description: "Convert documents with pandoc, LibreOffice and poppler",
+			Available:   document.Available(),
+			Conversions: document.Conversions(),
+		},
+		{Name: "clipboard", Description: "Search, paste and pin clipboard history", Available: a.serviceManager.Clipboard().Available()},
+		{Name: "calculator", Description: "Calculate and convert units offline", Available: true},
+		{Name: "launcher", Description: "Launch applications and their actions", Available: a.serviceManager.Launcher().Available()},
+		{Name: "desktop", Description: "Control volume, themes, wallpaper and windows", Available: true},
+		{Name: "screenshot", Description: "Take screenshots and record the screen", Available: a.serviceManager.Screenshot().Available()},
+		{Name: "notes", Description: "Save and search notes and snippets", Available: true},
+		{Name: "llm", Description: "Query LLM for assistance", Available: true},
 	}
 }
 
@@ -80,8 +345,10 @@ func (a *App) GetPathSuggestions(input string) services.AutoCompleteResult {
 	return result
 }
 type ServiceInfo struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name        string                        `json:"name"`
+	Description string                        `json:"description"`
+	Available   bool                          `json:"available"`
+	Conversions []services.DocumentConversion `json:"conversions,omitempty"`
 }
 
 // PickFile opens a file picker dialog using yad
@@ -93,7 +360,7 @@ func (a *App) PickFile(fileType string) string {
         cmd = exec.Command("yad", "--file", "--directory", "--title=Select Directory")
     case "image":
         cmd = exec.Command("yad", "--file", "--title=Select Image",
-            "--file-filter=Images | *.png *.jpg *.jpeg *.bmp *.gif *.tiff")
+            "--file-filter=Images | *.png *.jpg *.jpeg *.bmp *.gif *.tiff *.webp *.pdf")
     default:
         cmd = exec.Command("yad", "--file", "--title=Select File")
     }
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const cliUsage = `Usage:
+  aoiler                                    open the window
+  aoiler query "<query>" [--json] [--yes]   run one query and print the result
+  aoiler confirm <id> [--json]              run a desktop change waiting for confirmation
+  aoiler daemon                             serve queries on the socket without a window
+
+Queries go to a running Aoiler (window or daemon) when one is listening on
+%s, and run in this process otherwise. Theme, wallpaper and close commands
+wait for confirmation: confirm the id they print, or pass --yes to run them
+right away.
+`
+
+// isCLICommand reports whether the arguments ask for a headless mode rather
+// than the window
+func isCLICommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "query", "confirm", "daemon", "help", "-h", "--help":
+		return true
+	}
+	return false
+}
+
+// runCLI runs a headless subcommand and returns the process exit code:
+// 0 on success, 1 when the query failed and 2 for usage errors
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	switch args[0] {
+	case "query":
+		return runQueryCommand(args[1:], stdout, stderr)
+	case "confirm":
+		return runConfirmCommand(args[1:], stdout, stderr)
+	case "daemon":
+		return runDaemon(stderr)
+	default:
+		fmt.Fprintf(stdout, cliUsage, socketPath())
+		return 0
+	}
+}
+
+func runQueryCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the QueryResponse as JSON")
+	local := flags.Bool("local", false, "run in this process even if a daemon is listening")
+	yes := flags.Bool("yes", false, "run theme, wallpaper and close commands without waiting for confirmation")
+
+	words, ok := parseWords(flags, args)
+	if !ok {
+		return 2
+	}
+	query := strings.TrimSpace(strings.Join(words, " "))
+	if query == "" {
+		fmt.Fprintf(stderr, cliUsage, socketPath())
+		return 2
+	}
+
+	req := QueryRequest{Query: query}
+	var resp QueryResponse
+	// app is only set when the query runs here, and a change it offers
+	// can only be confirmed here too
+	var app *App
+	if *local {
+		app = NewApp()
+	} else {
+		var err error
+		resp, err = querySocket(socketPath(), req)
+		if errors.Is(err, errNoDaemon) {
+			app = NewApp()
+		} else if err != nil {
+			// The daemon got the request, so running it again here could
+			// repeat its side effects
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	if app != nil {
+		resp = app.ProcessQuery(req)
+	}
+
+	hint := ""
+	if id := pendingDesktopID(resp); id != "" {
+		switch {
+		case *yes && app != nil:
+			resp = app.ConfirmDesktopAction(id)
+		case *yes:
+			var err error
+			if resp, err = querySocket(socketPath(), QueryRequest{Confirm: id}); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+		case app != nil:
+			// The change is gone once this process exits
+			hint = "Run the query again with --yes to apply it"
+		default:
+			hint = "Run aoiler confirm " + id + " to apply it"
+		}
+	}
+
+	code := printResponse(resp, *asJSON, stdout, stderr)
+	if hint != "" {
+		fmt.Fprintln(stderr, hint)
+	}
+	return code
+}
+
+// runConfirmCommand runs a desktop change a running Aoiler is holding for
+// confirmation, by the id "aoiler query" printed for it
+func runConfirmCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("confirm", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the QueryResponse as JSON")
+
+	words, ok := parseWords(flags, args)
+	if !ok {
+		return 2
+	}
+	if len(words) != 1 {
+		fmt.Fprintf(stderr, cliUsage, socketPath())
+		return 2
+	}
+
+	resp, err := querySocket(socketPath(), QueryRequest{Confirm: words[0]})
+	if errors.Is(err, errNoDaemon) {
+		// Pending changes only live in the Aoiler that offered them
+		fmt.Fprintf(stderr, "%v, use aoiler query --yes to run the change in this process\n", err)
+		return 1
+	} else if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return printResponse(resp, *asJSON, stdout, stderr)
+}
+
+// parseWords parses flags that may come before or after the other words
+func parseWords(flags *flag.FlagSet, args []string) ([]string, bool) {
+	var words []string
+	for len(args) > 0 {
+		if err := flags.Parse(args); err != nil {
+			return nil, false
+		}
+		args = flags.Args()
+		if len(args) > 0 {
+			words = append(words, args[0])
+			args = args[1:]
+		}
+	}
+	return words, true
+}
+
+// pendingDesktopID returns the id of a desktop change the response is
+// waiting to have confirmed, if any
+func pendingDesktopID(resp QueryResponse) string {
+	if !resp.Success || resp.Service != "desktop" {
+		return ""
+	}
+	// Responses from the socket arrive as plain JSON, so read both kinds alike
+	var result struct {
+		PendingID string `json:"pendingId"`
+	}
+	if data, err := json.Marshal(resp.Result); err == nil {
+		json.Unmarshal(data, &result)
+	}
+	return result.PendingID
+}
+
+// printResponse prints a response and returns the exit code for it
+func printResponse(resp QueryResponse, asJSON bool, stdout, stderr io.Writer) int {
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(resp)
+	} else if resp.Success {
+		fmt.Fprintln(stdout, plainResult(resp))
+	} else {
+		fmt.Fprintf(stderr, "%s: %s\n", resp.Service, resp.Error)
+	}
+
+	if !resp.Success {
+		return 1
+	}
+	return 0
+}
+
+// plainResult prints text results in full and everything else as its summary
+func plainResult(resp QueryResponse) string {
+	// Responses from the socket arrive as plain JSON, so read both kinds alike
+	var fields map[string]interface{}
+	if data, err := json.Marshal(resp.Result); err == nil {
+		json.Unmarshal(data, &fields)
+	}
+	// A pipeline prints what its last stage produced
+	if stages, ok := fields["stages"].([]interface{}); ok && len(stages) > 0 {
+		if last, ok := stages[len(stages)-1].(map[string]interface{}); ok {
+			fields, _ = last["result"].(map[string]interface{})
+		}
+	}
+	for _, key := range []string{"response", "text"} {
+		if text, ok := fields[key].(string); ok && text != "" {
+			return strings.TrimRight(text, "\n")
+		}
+	}
+	if resp.Summary != "" {
+		return resp.Summary
+	}
+	return "done"
+}
+
+// runDaemon serves the socket until interrupted
+func runDaemon(stderr io.Writer) int {
+	server, err := listenSocket(NewApp(), socketPath())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stderr, "Listening on %s\n", server.path)
+
+	go server.serve()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	server.close()
+	return 0
+}
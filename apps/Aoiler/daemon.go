@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxRequestSize bounds one JSON request line on the socket
+const maxRequestSize = 1 << 20
+
+// socketPath is where the daemon listens. Both the GUI and `aoiler daemon`
+// serve it, so keybinds and bars share one ServiceManager with the window.
+func socketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "aoiler.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("aoiler-%d.sock", os.Getuid()))
+}
+
+// socketServer answers newline-delimited QueryRequest JSON with one
+// QueryResponse line each. A connection may send any number of requests.
+type socketServer struct {
+	app      *App
+	path     string
+	listener net.Listener
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+}
+
+// listenSocket takes over a stale socket left by a crashed process, but
+// refuses to replace one another Aoiler is still answering on
+func listenSocket(app *App, path string) (*socketServer, error) {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another Aoiler is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// Queries can read and change files, so only the owner may connect
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &socketServer{app: app, path: path, listener: listener, conns: make(map[net.Conn]bool)}, nil
+}
+
+// serve accepts connections until close is called
+func (s *socketServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *socketServer) handle(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxRequestSize)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var req QueryRequest
+		var resp QueryResponse
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = QueryResponse{Success: false, Error: fmt.Sprintf("invalid request: %v", err)}
+		} else if req.Confirm != "" {
+			resp = s.app.ConfirmDesktopAction(req.Confirm)
+		} else if req.Query == "" {
+			resp = QueryResponse{Success: false, Error: "empty query"}
+		} else {
+			resp = s.app.ProcessQuery(req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// close stops accepting, drops open connections and removes the socket
+func (s *socketServer) close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	os.Remove(s.path)
+}
+
+// errNoDaemon means nothing answered on the socket, so the request was never
+// delivered and is safe to run in this process instead
+var errNoDaemon = errors.New("no Aoiler is listening")
+
+// querySocket sends one request to a running Aoiler. It fails fast with
+// errNoDaemon when nothing is listening so the caller can run the query
+// itself; any later error means the daemon may already have run it.
+func querySocket(path string, req QueryRequest) (QueryResponse, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return QueryResponse{}, fmt.Errorf("%w on %s: %v", errNoDaemon, path, err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return QueryResponse{}, err
+	}
+
+	var resp QueryResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return QueryResponse{}, fmt.Errorf("no response from %s: %w", path, err)
+	}
+	return resp, nil
+}
@@ -1,6 +1,7 @@
 import { useState, useRef, useEffect } from 'react';
-import { Send, Loader2, Search, FolderTree, Code, ScanText, Film, Sparkles, HelpCircle, FileText } from 'lucide-react';
-import { ProcessQuery, GetPathSuggestions, PickFile } from '../wailsjs/go/main/App';
+import { Send, Loader2, Search, FolderTree, Code, ScanText, Film, Sparkles, HelpCircle, FileText, ListChecks, History, Star, Play, Trash2 } from 'lucide-react';
+import { StartQuery, ListJobs, CancelJob, SearchHistory, RecentQueries, PinQuery, DeleteQuery, GetSuggestions, DetectTypos, GetExamples, GetPathSuggestions, PickFile, ApplyFormat, DiscardFormat, CopyToClipboard, OpenURL, OCRTable, DeleteOCRHistory, CancelConversion, CopyClip, PasteClip, PinClip, DeleteClip, LaunchApp, ConfirmDesktopAction, DiscardDesktopAction, StopRecording, PasteNote, DeleteNote } from '../wailsjs/go/main/App';
+import { EventsOn } from '../wailsjs/runtime/runtime';
 
 interface Message {
   id: string;
@@ -17,6 +18,51 @@ interface QueryResponse {
   service: string;
   result: any;
   error?: string;
+  jobId?: string;
+}
+
+// JobInfo mirrors services.JobInfo; every query runs as one
+interface JobInfo {
+  id: string;
+  query: string;
+  service: string;
+  status: 'running' | 'succeeded' | 'failed' | 'cancelled' | 'timedout';
+  progress: number;
+  message?: string;
+  startedAt: string;
+  durationMs: number;
+  result?: any;
+  error?: string;
+}
+
+// HistoryEntry mirrors services.QueryHistoryEntry
+interface HistoryEntry {
+  id: string;
+  timestamp: string;
+  query: string;
+  service: string;
+  status: string;
+  success: boolean;
+  durationMs: number;
+  summary?: string;
+  error?: string;
+  pinned?: boolean;
+  redacted?: boolean;
+}
+
+// QuerySuggestion mirrors services.QuerySuggestion, generated from the services
+interface QuerySuggestion {
+  query: string;
+  description: string;
+  category: string;
+  service: string;
+  examples: string[];
+}
+
+interface ExampleQueries {
+  category: string;
+  icon: string;
+  queries: string[];
 }
 
 interface AutoCompleteResult {
@@ -24,6 +70,32 @@ interface AutoCompleteResult {
   isPath: boolean;
 }
 
+// ProgressRun tracks a streamed directory run (lint or OCR) while it's in flight
+interface ProgressRun {
+  runId: string;
+  root: string;
+  total: number;
+  processed: number;
+  recent: any[];
+}
+
+interface ConversionProgress {
+  jobId: string;
+  output: string;
+  percent: number;
+  speed: number;
+  etaSeconds: number;
+  duration: number;
+  pass: number;
+  passes: number;
+}
+
+interface RecordingProgress {
+  recordingId: string;
+  output: string;
+  duration: number;
+}
+
 interface QuickAction {
   id: string;
   label: string;
@@ -38,13 +110,34 @@ interface QuickAction {
 function App() {
   const [messages, setMessages] = useState<Message[]>([]);
   const [input, setInput] = useState('');
-  const [loading, setLoading] = useState(false);
+  // Queries run as background jobs, so several can be in flight at once
+  const [jobs, setJobs] = useState<JobInfo[]>([]);
+  const [showJobs, setShowJobs] = useState(false);
+  // recentQueries are stepped through with the arrow keys; -1 is the user's own input
+  const [recentQueries, setRecentQueries] = useState<string[]>([]);
+  const [historyIndex, setHistoryIndex] = useState(-1);
+  const [showHistory, setShowHistory] = useState(false);
+  const [historyTerms, setHistoryTerms] = useState('');
+  const [historyEntries, setHistoryEntries] = useState<HistoryEntry[]>([]);
   const [suggestions, setSuggestions] = useState<string[]>([]);
   const [showSuggestions, setShowSuggestions] = useState(false);
+  // Query patterns and a typo fix for what's typed, shown when no path is being completed
+  const [querySuggestions, setQuerySuggestions] = useState<QuerySuggestion[]>([]);
+  const [typoFix, setTypoFix] = useState<string | null>(null);
+  const [examples, setExamples] = useState<ExampleQueries[]>([]);
   const [selectedIndex, setSelectedIndex] = useState(0);
   const [showQuickActions, setShowQuickActions] = useState(true);
   const [selectedCategory, setSelectedCategory] = useState<string>('all');
+  const [linterRun, setLinterRun] = useState<ProgressRun | null>(null);
+  const [ocrRun, setOcrRun] = useState<ProgressRun | null>(null);
+  // Batches run several conversions at once, so progress is kept per job
+  const [conversions, setConversions] = useState<Record<string, ConversionProgress>>({});
+  const [conversionRun, setConversionRun] = useState<ProgressRun | null>(null);
+  // Recordings run until their time is up or they're stopped from here
+  const [recordings, setRecordings] = useState<Record<string, RecordingProgress>>({});
   const messagesEndRef = useRef<HTMLDivElement>(null);
+  const runningJobs = jobs.filter(job => job.status === 'running');
+  const loading = runningJobs.length > 0;
   const inputRef = useRef<HTMLTextAreaElement>(null);
 
   const quickActions: QuickAction[] = [
@@ -100,12 +193,22 @@ function App() {
       id: 'lint-code',
       label: 'Lint Code',
       icon: Code,
-      description: 'Check and fix code style',
+      description: 'Run linters and list issues',
       category: 'Code Tools',
       query: 'lint {path}',
       needsFile: true,
       fileType: 'file',
     },
+    {
+      id: 'lint-project',
+      label: 'Lint Project',
+      icon: Code,
+      description: 'Lint every file in a folder',
+      category: 'Code Tools',
+      query: 'lint {path}',
+      needsFile: true,
+      fileType: 'directory',
+    },
     {
       id: 'ocr-screen',
       label: 'OCR Screen',
@@ -125,6 +228,26 @@ function App() {
       needsFile: true,
       fileType: 'image',
     },
+    {
+      id: 'ocr-folder',
+      label: 'OCR Folder',
+      icon: ScanText,
+      description: 'Read all images and PDFs in a folder',
+      category: 'OCR & Text',
+      query: 'ocr {path}',
+      needsFile: true,
+      fileType: 'directory',
+    },
+    {
+      id: 'convert-document',
+      label: 'Convert Document',
+      icon: FileText,
+      description: 'Markdown, office and PDF files',
+      category: 'Media Conversion',
+      query: 'convert {path} to pdf',
+      needsFile: true,
+      fileType: 'file',
+    },
     {
       id: 'convert-media',
       label: 'Convert Media',
@@ -151,6 +274,134 @@ function App() {
     setSelectedIndex(0);
   }, [suggestions]);
 
+  useEffect(() => {
+    GetExamples().then(setExamples).catch(() => setExamples([]));
+  }, []);
+
+  useEffect(() => {
+    const offStart = EventsOn('linter:start', (data: any) => {
+      setLinterRun({ runId: data.runId, root: data.root, total: data.total, processed: 0, recent: [] });
+    });
+    const offFile = EventsOn('linter:file', (data: any) => {
+      setLinterRun(prev => prev && prev.runId === data.runId
+        ? { ...prev, processed: data.processed, recent: [data.result, ...prev.recent].slice(0, 5) }
+        : prev);
+    });
+    const offDone = EventsOn('linter:done', () => setLinterRun(null));
+    const offOcrStart = EventsOn('ocr:start', (data: any) => {
+      setOcrRun({ runId: data.runId, root: data.root, total: data.total, processed: 0, recent: [] });
+    });
+    const offOcrFile = EventsOn('ocr:file', (data: any) => {
+      setOcrRun(prev => prev && prev.runId === data.runId
+        ? { ...prev, processed: data.processed, recent: [data.file, ...prev.recent].slice(0, 5) }
+        : prev);
+    });
+    const offOcrDone = EventsOn('ocr:done', () => setOcrRun(null));
+    const offConvStart = EventsOn('converter:start', (data: any) => {
+      setConversions(prev => ({
+        ...prev,
+        [data.jobId]: { jobId: data.jobId, output: data.output, duration: data.duration, percent: 0, speed: 0, etaSeconds: 0, pass: 1, passes: 1 },
+      }));
+    });
+    const offConvProgress = EventsOn('converter:progress', (data: any) => {
+      setConversions(prev => prev[data.jobId]
+        ? { ...prev, [data.jobId]: { ...prev[data.jobId], percent: data.percent, speed: data.speed, etaSeconds: data.etaSeconds, pass: data.pass, passes: data.passes } }
+        : prev);
+    });
+    const offConvDone = EventsOn('converter:done', (data: any) => {
+      setConversions(prev => {
+        const { [data.jobId]: _, ...rest } = prev;
+        return rest;
+      });
+    });
+    const offBatchStart = EventsOn('converter:batchstart', (data: any) => {
+      setConversionRun({ runId: data.runId, root: data.root, total: data.total, processed: 0, recent: [] });
+    });
+    const offBatchFile = EventsOn('converter:file', (data: any) => {
+      setConversionRun(prev => prev && prev.runId === data.runId
+        ? { ...prev, processed: data.processed, recent: [data.file, ...prev.recent].slice(0, 5) }
+        : prev);
+    });
+    const offBatchDone = EventsOn('converter:batchdone', () => setConversionRun(null));
+    const offRecStart = EventsOn('recording:start', (data: any) => {
+      setRecordings(prev => ({
+        ...prev,
+        [data.recordingId]: { recordingId: data.recordingId, output: data.output, duration: data.duration },
+      }));
+    });
+    const offRecDone = EventsOn('recording:done', (data: any) => {
+      setRecordings(prev => {
+        const { [data.recordingId]: _, ...rest } = prev;
+        return rest;
+      });
+    });
+    const offJob = EventsOn('job:update', (job: JobInfo) => {
+      // Results can be large, the message keeps them and the panel doesn't need them
+      const entry = { ...job, result: undefined };
+      setJobs(prev => prev.some(j => j.id === job.id)
+        ? prev.map(j => (j.id === job.id ? entry : j))
+        : [entry, ...prev].slice(0, 100));
+      if (job.status !== 'running') {
+        finishJob(job);
+      }
+    });
+    return () => {
+      offStart();
+      offFile();
+      offDone();
+      offOcrStart();
+      offOcrFile();
+      offOcrDone();
+      offConvStart();
+      offConvProgress();
+      offConvDone();
+      offBatchStart();
+      offBatchFile();
+      offBatchDone();
+      offRecStart();
+      offRecDone();
+      offJob();
+    };
+  }, []);
+
+  const loadRecentQueries = () => {
+    RecentQueries(200).then(queries => setRecentQueries(queries || [])).catch(() => {});
+  };
+
+  useEffect(loadRecentQueries, []);
+
+  // The panel refreshes as queries finish, which adds messages
+  useEffect(() => {
+    if (!showHistory) return;
+    SearchHistory(historyTerms)
+      .then(result => setHistoryEntries(result.entries || []))
+      .catch(() => setHistoryEntries([]));
+  }, [showHistory, historyTerms, messages.length]);
+
+  const togglePin = async (entry: HistoryEntry) => {
+    await PinQuery(entry.id, !entry.pinned);
+    setHistoryEntries(prev => prev.map(e => (e.id === entry.id ? { ...e, pinned: !entry.pinned } : e)));
+  };
+
+  const deleteHistoryEntry = async (entry: HistoryEntry) => {
+    await DeleteQuery(entry.id);
+    setHistoryEntries(prev => prev.filter(e => e.id !== entry.id));
+    loadRecentQueries();
+  };
+
+  const editHistoryEntry = (entry: HistoryEntry) => {
+    setInput(entry.query);
+    setHistoryIndex(-1);
+    inputRef.current?.focus();
+  };
+
+  const toggleJobs = async () => {
+    if (!showJobs) {
+      setJobs(await ListJobs());
+    }
+    setShowJobs(!showJobs);
+  };
+
   useEffect(() => {
     if (messages.length > 0) {
       setShowQuickActions(false);
@@ -159,9 +410,11 @@ function App() {
 
   useEffect(() => {
     const getAutoComplete = async () => {
-      if (input.length === 0) {
+      if (input.length === 0 || historyIndex >= 0) {
         setShowSuggestions(false);
         setSuggestions([]);
+        setQuerySuggestions([]);
+        setTypoFix(null);
         return;
       }
 
@@ -171,9 +424,14 @@ function App() {
         if (result.isPath && result.suggestions && result.suggestions.length > 0) {
           setSuggestions(result.suggestions);
           setShowSuggestions(true);
+          setQuerySuggestions([]);
+          setTypoFix(null);
         } else {
           setSuggestions([]);
           setShowSuggestions(false);
+          const [matches, typos] = await Promise.all([GetSuggestions(input), DetectTypos(input)]);
+          setQuerySuggestions((matches || []).slice(0, 4));
+          setTypoFix(typos && typos.length > 0 ? typos[0] : null);
         }
       } catch (error) {
         console.error('Autocomplete error:', error);
@@ -184,7 +442,7 @@ function App() {
 
     const debounce = setTimeout(getAutoComplete, 300);
     return () => clearTimeout(debounce);
-  }, [input]);
+  }, [input, historyIndex]);
 
   const openFilePicker = async (fileType: 'file' | 'directory' | 'image'): Promise<string | null> => {
     try {
@@ -225,9 +483,135 @@ function App() {
     setTimeout(() => handleSubmit(finalQuery), 100);
   };
 
+  // finishJob turns a finished job into the assistant reply for its query
+  const finishJob = (job: JobInfo) => {
+    const response: QueryResponse = {
+      success: job.status === 'succeeded',
+      service: job.service,
+      result: job.result,
+      error: job.error,
+      jobId: job.id,
+    };
+
+    let assistantContent = '';
+
+    if (response.success) {
+      switch (response.service) {
+        case 'filesearch':
+          assistantContent = response.result?.found
+            ? `Found: ${response.result.path}`
+            : `Could not find the file.`;
+          break;
+        case 'organizer':
+          assistantContent = `Files organized successfully.`;
+          break;
+        case 'linter':
+          if (response.result?.summary) {
+            const summary = response.result.summary;
+            assistantContent = summary.mode === 'check'
+              ? `Checked ${summary.filesProcessed} files: ${summary.diagnostics.error} errors, ${summary.diagnostics.warning} warnings.`
+              : `Formatted ${summary.filesProcessed} files, ${summary.filesChanged} would change.`;
+          } else if (response.result?.mode === 'check') {
+            const issues = response.result?.diagnostics?.length || 0;
+            assistantContent = issues === 0
+              ? `No issues found by ${response.result?.linterUsed}.`
+              : `${response.result?.linterUsed} found ${issues} issue${issues === 1 ? '' : 's'}.`;
+          } else {
+            assistantContent = response.result?.changed
+              ? `Review the changes from ${response.result?.linterUsed} before applying.`
+              : `File is already formatted.`;
+          }
+          break;
+        case 'ocr':
+          assistantContent = `Text extracted from ${response.result?.source || 'screen'}.`;
+          break;
+        case 'converter':
+          if (response.result?.summary) {
+            const summary = response.result.summary;
+            assistantContent = summary.cancelled
+              ? `Batch cancelled after converting ${summary.filesConverted} of ${summary.filesProcessed} files.`
+              : `Converted ${summary.filesConverted} files to ${summary.format}.`;
+          } else {
+            assistantContent = response.result?.streams
+              ? `Media info for ${response.result.path}.`
+              : `Conversion completed.`;
+          }
+          break;
+        case 'document':
+          assistantContent = `Converted ${response.result?.inputFormat} to ${response.result?.outputFormat}.`;
+          break;
+        case 'llm':
+          assistantContent = response.result?.response || 'Response received.';
+          break;
+        case 'clipboard':
+          assistantContent = response.result?.total
+            ? `Found ${response.result.total} clipboard ${response.result.total === 1 ? 'entry' : 'entries'}.`
+            : 'Nothing in the clipboard history matches.';
+          break;
+        case 'calculator':
+          assistantContent = response.result?.variable
+            ? `${response.result.variable} = ${response.result.result}`
+            : `${response.result?.result}`;
+          break;
+        case 'launcher': {
+          const app = response.result?.apps?.find((a: any) => a.id === response.result.launched);
+          assistantContent = app ? `Launched ${app.name}${app.action ? ` · ${app.action}` : ''}.` : 'Launched.';
+          break;
+        }
+        case 'desktop':
+          assistantContent = response.result?.pendingId
+            ? `${response.result.description}?`
+            : response.result?.action === 'volume'
+              ? `Volume ${response.result.volume ?? 0}%${response.result.muted ? ' (muted)' : ''}.`
+              : `${response.result?.description}.`;
+          break;
+        case 'notes':
+          if (response.result?.action === 'saved') {
+            assistantContent = `Saved ${response.result.kind} "${response.result.notes?.[0]?.title}".`;
+          } else {
+            assistantContent = response.result?.total
+              ? `Found ${response.result.total} ${response.result.kind === 'snippet' ? 'snippet' : 'note'}${response.result.total === 1 ? '' : 's'}.`
+              : 'No notes match.';
+          }
+          break;
+        case 'screenshot':
+          assistantContent = response.result?.action === 'stop'
+            ? `Stopped ${response.result.stopped || 0} ${response.result.stopped === 1 ? 'recording' : 'recordings'}.`
+            : response.result?.clipboard
+              ? 'Screenshot copied to the clipboard.'
+              : `Saved ${response.result?.action === 'recording' ? 'recording' : 'screenshot'}.`;
+          break;
+        case 'help':
+          assistantContent = response.result?.text || 'No help available.';
+          break;
+        case 'pipeline':
+          assistantContent = `Ran ${response.result?.stages?.length || 0} steps.`;
+          break;
+        default:
+          assistantContent = `Request processed.`;
+      }
+    } else {
+      assistantContent = job.status === 'cancelled' ? 'Cancelled.' : response.error || 'An error occurred.';
+    }
+
+    const assistantMessage: Message = {
+      id: job.id,
+      type: 'assistant',
+      content: assistantContent,
+      service: response.service,
+      // A failed pipeline still shows the steps that completed
+      result: response.success || response.service === 'pipeline' ? response.result : null,
+      error: response.error,
+      timestamp: new Date(),
+    };
+
+    setMessages(prev => (prev.some(m => m.id === job.id) ? prev : [...prev, assistantMessage]));
+    loadRecentQueries();
+  };
+
   const handleSubmit = async (queryOverride?: string) => {
     const queryToSubmit = queryOverride || input;
-    if (!queryToSubmit.trim() || loading) return;
+    if (!queryToSubmit.trim()) return;
 
     const userMessage: Message = {
       id: Date.now().toString(),
@@ -238,57 +622,13 @@ function App() {
 
     setMessages(prev => [...prev, userMessage]);
     setInput('');
-    setLoading(true);
+    setHistoryIndex(-1);
     setShowSuggestions(false);
     setSuggestions([]);
 
     try {
-      const response: QueryResponse = await ProcessQuery({ query: queryToSubmit });
-
-      let assistantContent = '';
-
-      if (response.success) {
-        switch (response.service) {
-          case 'filesearch':
-            assistantContent = response.result?.found
-              ? `Found: ${response.result.path}`
-              : `Could not find the file.`;
-            break;
-          case 'organizer':
-            assistantContent = `Files organized successfully.`;
-            break;
-          case 'linter':
-            assistantContent = response.result?.fixed
-              ? `Code formatted successfully.`
-              : `Formatting completed.`;
-            break;
-          case 'ocr':
-            assistantContent = `Text extracted from ${response.result?.source || 'image'}.`;
-            break;
-          case 'converter':
-            assistantContent = `Conversion completed.`;
-            break;
-          case 'llm':
-            assistantContent = response.result?.response || 'Response received.';
-            break;
-          default:
-            assistantContent = `Request processed.`;
-        }
-      } else {
-        assistantContent = response.error || 'An error occurred.';
-      }
-
-      const assistantMessage: Message = {
-        id: (Date.now() + 1).toString(),
-        type: 'assistant',
-        content: assistantContent,
-        service: response.service,
-        result: response.success ? response.result : null,
-        error: response.error,
-        timestamp: new Date(),
-      };
-
-      setMessages(prev => [...prev, assistantMessage]);
+      // The job:update listener adds the reply once the job finishes
+      await StartQuery({ query: queryToSubmit });
     } catch (err) {
       const errorMessage: Message = {
         id: (Date.now() + 1).toString(),
@@ -298,8 +638,6 @@ function App() {
         timestamp: new Date(),
       };
       setMessages(prev => [...prev, errorMessage]);
-    } finally {
-      setLoading(false);
     }
   };
 
@@ -331,6 +669,21 @@ function App() {
       }
     }
 
+    // Like a shell: up steps back through past queries from an empty input
+    if (e.key === 'ArrowUp' && (input === '' || historyIndex >= 0) && historyIndex < recentQueries.length - 1) {
+      e.preventDefault();
+      setHistoryIndex(historyIndex + 1);
+      setInput(recentQueries[historyIndex + 1]);
+      return;
+    }
+
+    if (e.key === 'ArrowDown' && historyIndex >= 0) {
+      e.preventDefault();
+      setHistoryIndex(historyIndex - 1);
+      setInput(historyIndex > 0 ? recentQueries[historyIndex - 1] : '');
+      return;
+    }
+
     if (e.key === 'Enter' && !e.shiftKey && !showSuggestions) {
       e.preventDefault();
       handleSubmit();
@@ -359,7 +712,617 @@ function App() {
     inputRef.current?.focus();
   };
 
-  const renderResult = (msg: Message) => {
+  const jobStatusColor = (status: string) => {
+    switch (status) {
+      case 'running': return 'text-cyan-400 w-16 flex-shrink-0';
+      case 'succeeded': return 'text-green-400 w-16 flex-shrink-0';
+      case 'cancelled': return 'text-gray-500 w-16 flex-shrink-0';
+      default: return 'text-red-400 w-16 flex-shrink-0';
+    }
+  };
+
+  const formatETA = (seconds: number) => {
+    const s = Math.round(seconds);
+    return s >= 60 ? `${Math.floor(s / 60)}m ${s % 60}s` : `${s}s`;
+  };
+
+  // describeConversion turns converter options into short chips like "480p" or "96k audio"
+  const describeConversion = (opts: any): string[] => {
+    if (!opts) return [];
+    const labels: string[] = [];
+    if (opts.width && opts.height) labels.push(`${opts.width}x${opts.height}`);
+    else if (opts.height) labels.push(`${opts.height}p`);
+    else if (opts.width) labels.push(`${opts.width}px wide`);
+    if (opts.fps) labels.push(`${opts.fps} fps`);
+    if (opts.videoCodec) labels.push(opts.videoCodec);
+    if (opts.audioCodec) labels.push(opts.audioCodec);
+    if (opts.videoBitrate) labels.push(`${opts.videoBitrate} video`);
+    if (opts.audioBitrate) labels.push(`${opts.audioBitrate} audio`);
+    if (opts.percent) labels.push(`${opts.percent}%`);
+    if (opts.quality) labels.push(`quality ${opts.quality}`);
+    if (opts.crf) labels.push(`crf ${opts.crf}`);
+    if (opts.start) labels.push(`from ${opts.start}s`);
+    if (opts.duration) labels.push(`${opts.duration}s long`);
+    if (opts.fromEnd) labels.push(`last ${opts.fromEnd}s`);
+    if (opts.targetSizeMB) labels.push(`≤ ${opts.targetSizeMB} MB`);
+    if (opts.audioOnly) labels.push('audio only');
+    if (opts.noAudio) labels.push('muted');
+    return labels;
+  };
+
+  // describeStream summarises an ffprobe stream, e.g. "h264 1920×1080 29.97 fps"
+  const describeStream = (stream: any): string => {
+    const parts: string[] = [stream.codec];
+    if (stream.width) parts.push(`${stream.width}×${stream.height}`);
+    if (stream.fps) parts.push(`${Math.round(stream.fps * 100) / 100} fps`);
+    if (stream.channels) parts.push(stream.channels === 1 ? 'mono' : stream.channels === 2 ? 'stereo' : `${stream.channels} ch`);
+    if (stream.sampleRate) parts.push(`${stream.sampleRate / 1000} kHz`);
+    if (stream.bitRate) parts.push(`${Math.round(stream.bitRate / 1000)} kb/s`);
+    if (stream.language && stream.language !== 'und') parts.push(stream.language);
+    if (stream.coverArt) parts.push('cover art');
+    return parts.join(' · ');
+  };
+
+  const renderMediaInfo = (info: any, label: string) => (
+    <div className="mt-2">
+      <p className="text-xs text-gray-500">
+        {label}: {info.container}
+        {info.duration > 0 && ` · ${formatETA(info.duration)}`}
+        {info.bitRate > 0 && ` · ${Math.round(info.bitRate / 1000)} kb/s`}
+        {info.size > 0 && ` · ${(info.size / 1024 / 1024).toFixed(1)} MB`}
+      </p>
+      {info.streams.map((stream: any) => (
+        <p key={stream.index} className="text-xs text-gray-400 font-mono">
+          #{stream.index} {stream.type}: {describeStream(stream)}
+        </p>
+      ))}
+    </div>
+  );
+
+  const updateMessageResult = (id: string, result: any, error?: string) => {
+    setMessages(prev => prev.map(m => (m.id === id ? { ...m, result, error } : m)));
+  };
+
+  const handleApplyFormat = async (msg: Message) => {
+    const response: QueryResponse = await ApplyFormat(msg.result.pendingId);
+    if (response.success) {
+      updateMessageResult(msg.id, { ...msg.result, pendingId: '', fixed: true });
+    } else {
+      updateMessageResult(msg.id, { ...msg.result, pendingId: '' }, response.error);
+    }
+  };
+
+  const handleDiscardFormat = async (msg: Message) => {
+    await DiscardFormat(msg.result.pendingId);
+    updateMessageResult(msg.id, { ...msg.result, pendingId: '', diff: '' });
+  };
+
+  const handleApplyAll = async (msg: Message) => {
+    const files = await Promise.all(msg.result.files.map(async (file: any) => {
+      if (!file.pendingId) return file;
+      const response: QueryResponse = await ApplyFormat(file.pendingId);
+      return response.success
+        ? { ...file, pendingId: '', fixed: true }
+        : { ...file, pendingId: '', error: response.error };
+    }));
+    updateMessageResult(msg.id, { ...msg.result, files });
+  };
+
+  const handleCopyText = async (msg: Message) => {
+    await CopyToClipboard(msg.result.text);
+    updateMessageResult(msg.id, { ...msg.result, copied: true });
+  };
+
+  const handleOCRTable = async (msg: Message, format: string) => {
+    try {
+      const table = await OCRTable(msg.result.words || [], format);
+      updateMessageResult(msg.id, { ...msg.result, table, tableFormat: format });
+    } catch (err) {
+      updateMessageResult(msg.id, msg.result, String(err));
+    }
+  };
+
+  const handleDeleteOCRHistory = async (msg: Message, id: string) => {
+    await DeleteOCRHistory(id);
+    const entries = msg.result.entries.filter((e: any) => e.id !== id);
+    updateMessageResult(msg.id, { ...msg.result, entries, total: msg.result.total - 1 });
+  };
+
+  const handleDeleteClip = async (msg: Message, id: string) => {
+    try {
+      await DeleteClip(id);
+      const entries = msg.result.entries.filter((e: any) => e.id !== id);
+      updateMessageResult(msg.id, { ...msg.result, entries, total: msg.result.total - 1 });
+    } catch (err) {
+      updateMessageResult(msg.id, msg.result, String(err));
+    }
+  };
+
+  const handlePinClip = async (msg: Message, id: string, pinned: boolean) => {
+    try {
+      await PinClip(id, pinned);
+      const entries = msg.result.entries.map((e: any) => (e.id === id ? { ...e, pinned } : e));
+      updateMessageResult(msg.id, { ...msg.result, entries });
+    } catch (err) {
+      updateMessageResult(msg.id, msg.result, String(err));
+    }
+  };
+
+  const handleLaunchApp = async (msg: Message, id: string) => {
+    try {
+      await LaunchApp(id);
+      const apps = msg.result.apps.map((a: any) => (a.id === id ? { ...a, launches: a.launches + 1 } : a));
+      updateMessageResult(msg.id, { ...msg.result, apps, launched: id });
+    } catch (err) {
+      updateMessageResult(msg.id, msg.result, String(err));
+    }
+  };
+
+  const renderLauncher = (msg: Message) => {
+    const result = msg.result;
+    return (
+      <>
+        <p className="font-medium text-orange-400 text-xs mb-2">
+          Apps matching "{result.query}"
+        </p>
+        <div className="space-y-1">
+          {result.apps.map((a: any) => (
+            <div key={a.id} className="flex items-center gap-2 p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
+              {a.icon ? (
+                <img src={a.icon} alt="" className="w-6 h-6 flex-shrink-0" />
+              ) : (
+                <div className="w-6 h-6 flex-shrink-0 rounded bg-gray-800" />
+              )}
+              <div className="flex-1 min-w-0">
+                <p className="text-xs text-gray-200 truncate">
+                  {a.name}
+                  {a.action && <span className="text-gray-400"> · {a.action}</span>}
+                </p>
+                <p className="text-xs text-gray-500 truncate">
+                  {a.comment || a.genericName || a.exec}
+                  {a.launches > 0 && ` · ${a.launches} ${a.launches === 1 ? 'launch' : 'launches'}`}
+                </p>
+              </div>
+              {a.id === result.launched ? (
+                <span className="text-xs text-orange-400 flex-shrink-0">Launched</span>
+              ) : (
+                <button onClick={() => handleLaunchApp(msg, a.id)} className="text-xs text-gray-400 hover:text-gray-200 flex-shrink-0">
+                  Launch
+                </button>
+              )}
+            </div>
+          ))}
+        </div>
+      </>
+    );
+  };
+
+  const handleConfirmDesktop = async (msg: Message) => {
+    const response: QueryResponse = await ConfirmDesktopAction(msg.result.pendingId);
+    updateMessageResult(msg.id, { ...msg.result, pendingId: '', done: response.success }, response.error);
+  };
+
+  const handleDiscardDesktop = async (msg: Message) => {
+    await DiscardDesktopAction(msg.result.pendingId);
+    updateMessageResult(msg.id, { ...msg.result, pendingId: '', discarded: true });
+  };
+
+  const renderDesktop = (msg: Message) => {
+    const result = msg.result;
+    return (
+      <>
+        <p className="font-medium text-indigo-400 text-xs mb-2">{result.description}</p>
+        {result.action === 'volume' && (
+          <div className="flex items-center gap-2">
+            <div className="flex-1 h-1.5 rounded bg-gray-800 overflow-hidden">
+              <div className={`h-full ${result.muted ? 'bg-gray-600' : 'bg-indigo-500'}`} style={{ width: `${Math.min(result.volume || 0, 100)}%` }} />
+            </div>
+            <span className="text-xs text-gray-400 font-mono">{result.volume || 0}%{result.muted && ' muted'}</span>
+          </div>
+        )}
+        {result.themes?.length > 0 && (
+          <div className="space-y-1">
+            {result.themes.map((t: any) => (
+              <div key={t.name} className="flex items-center gap-2 p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
+                <div className="flex gap-0.5 flex-shrink-0">
+                  {['background', 'color1', 'color2', 'color4', 'color5'].map(key => (
+                    <span key={key} className="w-3 h-3 rounded-sm" style={{ backgroundColor: t.colors[key] }} />
+                  ))}
+                </div>
+                <div className="flex-1 min-w-0">
+                  <p className="text-xs text-gray-200">{t.name}</p>
+                  <p className="text-xs text-gray-500 truncate">{t.description}</p>
+                </div>
+                {t.name === result.currentTheme ? (
+                  <span className="text-xs text-indigo-400 flex-shrink-0">Current</span>
+                ) : (
+                  <button onClick={() => handleSubmit(`switch to ${t.name} theme`)} className="text-xs text-gray-400 hover:text-gray-200 flex-shrink-0">
+                    Apply
+                  </button>
+                )}
+              </div>
+            ))}
+          </div>
+        )}
+        {result.pendingId && (
+          <div className="flex gap-2 mt-2">
+            <button
+              onClick={() => handleConfirmDesktop(msg)}
+              className="px-3 py-1 rounded text-xs bg-indigo-700 text-white hover:opacity-80"
+            >
+              Confirm
+            </button>
+            <button
+              onClick={() => handleDiscardDesktop(msg)}
+              className="px-3 py-1 rounded text-xs bg-gray-800 text-gray-300 hover:bg-gray-700"
+            >
+              Cancel
+            </button>
+          </div>
+        )}
+        {!result.pendingId && result.discarded && <p className="text-xs text-gray-500">Cancelled</p>}
+        {!result.pendingId && result.done && result.action !== 'volume' && !result.themes && <p className="text-xs text-gray-500">Done</p>}
+      </>
+    );
+  };
+
+  const renderScreenshot = (msg: Message) => {
+    const result = msg.result;
+    return (
+      <>
+        <p className="font-medium text-rose-400 text-xs mb-2">
+          {result.action === 'recording' ? 'Recording' : 'Screenshot'} · {result.mode}
+          {result.action === 'recording' && ` · ${formatETA(result.seconds || 0)}`}
+          {result.audio && ' · audio'}
+        </p>
+        <p className="text-xs text-gray-300 font-mono break-all">{result.path}</p>
+        <div className="flex items-center gap-3 mt-2 text-xs">
+          {result.size > 0 && <span className="text-gray-500">{(result.size / 1024).toFixed(0)} KB</span>}
+          <button onClick={() => CopyToClipboard(result.path)} className="text-gray-400 hover:text-gray-200">
+            Copy path
+          </button>
+          <button
+            onClick={() => {
+              setInput(`convert ${result.path} to ${result.action === 'recording' ? 'gif' : 'jpg'}`);
+              inputRef.current?.focus();
+            }}
+            className="text-gray-400 hover:text-gray-200"
+          >
+            Convert
+          </button>
+        </div>
+      </>
+    );
+  };
+
+  const handleDeleteNote = async (msg: Message, id: string) => {
+    try {
+      await DeleteNote(id);
+      const notes = msg.result.notes.filter((n: any) => n.id !== id);
+      updateMessageResult(msg.id, { ...msg.result, notes, total: msg.result.total - 1 });
+    } catch (err) {
+      updateMessageResult(msg.id, msg.result, String(err));
+    }
+  };
+
+  const renderNotes = (msg: Message) => {
+    const result = msg.result;
+    return (
+      <>
+        {result.action === 'search' && (
+          <p className="font-medium text-yellow-400 text-xs mb-2">
+            {result.kind === 'snippet' ? 'Snippets' : 'Notes'} · {result.total}
+            {result.terms && <span className="text-gray-500"> matching "{result.terms}"</span>}
+          </p>
+        )}
+        <div className="space-y-2">
+          {result.notes.map((n: any) => (
+            <div key={n.id} className="p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
+              <div className="flex items-center justify-between gap-2">
+                <span className="text-xs text-gray-200 truncate">
+                  {n.kind === 'snippet' && <span className="text-yellow-400">{'</>'} </span>}
+                  {n.title}
+                </span>
+                <div className="flex gap-2 flex-shrink-0">
+                  <button onClick={() => PasteNote(n.id).catch(err => updateMessageResult(msg.id, msg.result, String(err)))} className="text-xs text-gray-400 hover:text-gray-200">
+                    Paste
+                  </button>
+                  <button onClick={() => CopyToClipboard(n.content)} className="text-xs text-gray-400 hover:text-gray-200">
+                    Copy
+                  </button>
+                  <button onClick={() => handleDeleteNote(msg, n.id)} className="text-xs text-gray-500 hover:text-red-400">
+                    Delete
+                  </button>
+                </div>
+              </div>
+              <p className="text-xs text-gray-500 mt-0.5">
+                {new Date(n.updated).toLocaleString()}
+                {n.tags?.map((tag: string) => (
+                  <button key={tag} onClick={() => handleSubmit(`notes #${tag}`)} className="ml-2 text-yellow-600 hover:text-yellow-400">
+                    #{tag}
+                  </button>
+                ))}
+              </p>
+              {n.content !== n.title && (
+                <p className={`text-xs text-gray-300 break-words mt-1 line-clamp-3 ${n.kind === 'snippet' ? 'font-mono whitespace-pre-wrap' : ''}`}>{n.content}</p>
+              )}
+            </div>
+          ))}
+        </div>
+      </>
+    );
+  };
+
+  const renderClipboard = (msg: Message) => {
+    const result = msg.result;
+    return (
+      <>
+        <p className="font-medium text-teal-400 text-xs mb-2">
+          Clipboard · {result.total} {result.total === 1 ? 'entry' : 'entries'}
+          {result.terms && <span className="text-gray-500"> matching "{result.terms}"</span>}
+        </p>
+        <div className="space-y-2">
+          {result.entries.map((e: any) => (
+            <div key={e.id} className="flex gap-2 p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
+              {e.image && (
+                <img src={e.image} alt="" className="w-16 h-16 object-cover rounded flex-shrink-0" />
+              )}
+              <div className="flex-1 min-w-0">
+                <div className="flex items-center justify-between gap-2">
+                  <span className="text-xs text-gray-500">
+                    {e.kind === 'image' ? `${e.format} · ${e.dimensions || e.size}` : `#${e.id}`}
+                  </span>
+                  <div className="flex gap-2 flex-shrink-0">
+                    <button onClick={() => PasteClip(e.id).catch(err => updateMessageResult(msg.id, msg.result, String(err)))} className="text-xs text-gray-400 hover:text-gray-200">
+                      Paste
+                    </button>
+                    <button onClick={() => CopyClip(e.id).catch(err => updateMessageResult(msg.id, msg.result, String(err)))} className="text-xs text-gray-400 hover:text-gray-200">
+                      Copy
+                    </button>
+                    <button
+                      onClick={() => handlePinClip(msg, e.id, !e.pinned)}
+                      className={e.pinned ? 'text-yellow-400' : 'text-gray-500 hover:text-yellow-400'}
+                      title={e.pinned ? 'Unpin' : 'Pin'}
+                    >
+                      <Star size={12} fill={e.pinned ? 'currentColor' : 'none'} />
+                    </button>
+                    <button
+                      onClick={() => handleDeleteClip(msg, e.id)}
+                      className="text-xs text-gray-500 hover:text-red-400"
+                    >
+                      Delete
+                    </button>
+                  </div>
+                </div>
+                {e.kind !== 'image' && (
+                  <p className="text-xs text-gray-300 break-words mt-1 font-mono line-clamp-3">{e.text || e.preview}</p>
+                )}
+              </div>
+            </div>
+          ))}
+        </div>
+      </>
+    );
+  };
+
+  const renderOCRHistory = (msg: Message) => {
+    const result = msg.result;
+    return (
+      <>
+        <p className="font-medium text-amber-400 text-xs mb-2">
+          OCR History · {result.total} {result.total === 1 ? 'capture' : 'captures'}
+          {result.query && <span className="text-gray-500"> matching "{result.query}"</span>}
+        </p>
+        <div className="space-y-2">
+          {result.entries.map((e: any) => (
+            <div key={e.id} className="flex gap-2 p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
+              {e.preview && (
+                <img src={e.preview} alt="" className="w-16 h-16 object-cover rounded flex-shrink-0" />
+              )}
+              <div className="flex-1 min-w-0">
+                <div className="flex items-center justify-between">
+                  <span className="text-xs text-gray-500">
+                    {new Date(e.timestamp).toLocaleString()} · {e.mode}
+                    {e.source && ` · ${e.source}`}
+                  </span>
+                  <div className="flex gap-2">
+                    <button
+                      onClick={() => CopyToClipboard(e.text)}
+                      className="text-xs text-gray-400 hover:text-gray-200"
+                    >
+                      Copy
+                    </button>
+                    <button
+                      onClick={() => handleDeleteOCRHistory(msg, e.id)}
+                      className="text-xs text-gray-500 hover:text-red-400"
+                    >
+                      Delete
+                    </button>
+                  </div>
+                </div>
+                <p className="text-xs text-gray-300 break-words mt-1">{e.snippet}</p>
+              </div>
+            </div>
+          ))}
+        </div>
+      </>
+    );
+  };
+
+  const renderOCRBatch = (msg: Message) => {
+    const { summary, files } = msg.result;
+    const failed = files.filter((f: any) => f.error);
+    return (
+      <>
+        <p className="text-xs text-gray-300 break-all mb-1 font-mono">{summary.root}</p>
+        <div className="text-xs text-gray-400 space-y-0.5">
+          <p>
+            {summary.filesProcessed - summary.filesSkipped - summary.filesFailed} files
+            ({summary.pagesProcessed} pages, {summary.wordCount} words) in {(summary.durationMs / 1000).toFixed(1)}s
+          </p>
+          {summary.filesSkipped > 0 && <p>{summary.filesSkipped} already up to date</p>}
+          {summary.filesFailed > 0 && <p className="text-red-400">{summary.filesFailed} failed</p>}
+        </div>
+        {failed.length > 0 && (
+          <div className="mt-2 space-y-0.5">
+            {failed.map((f: any) => (
+              <p key={f.path} className="text-xs font-mono truncate">
+                <span className="text-red-400">✗</span> {f.path.replace(summary.root + '/', '')}
+                <span className="text-gray-500"> {f.error}</span>
+              </p>
+            ))}
+          </div>
+        )}
+        <p className="text-xs text-gray-500 mt-2">Text saved next to each file as .txt · search with "ocr history"</p>
+      </>
+    );
+  };
+
+  const renderConversionBatch = (msg: Message) => {
+    const { summary, files } = msg.result;
+    const failed = files.filter((f: any) => f.error);
+    const relative = (path: string) => path.replace(summary.root + '/', '');
+    return (
+      <>
+        <p className="text-xs text-gray-300 break-all mb-1 font-mono">
+          {summary.root} → {summary.outputDir || 'same folder'}
+        </p>
+        <div className="text-xs text-gray-400 space-y-0.5">
+          <p>
+            {summary.filesConverted} files to {summary.format} in {(summary.durationMs / 1000).toFixed(1)}s
+            {summary.bytesIn > 0 && ` · ${(summary.bytesIn / 1024 / 1024).toFixed(1)} MB → ${(summary.bytesOut / 1024 / 1024).toFixed(1)} MB`}
+          </p>
+          {summary.filesSkipped > 0 && <p>{summary.filesSkipped} skipped, output already exists</p>}
+          {summary.filesFailed > 0 && (
+            <p className="text-red-400">{summary.filesFailed} {summary.cancelled ? 'failed or cancelled' : 'failed'}</p>
+          )}
+        </div>
+        <div className="mt-2 space-y-0.5 max-h-48 overflow-y-auto">
+          {files.filter((f: any) => !f.error).map((f: any) => (
+            <p key={f.input} className="text-xs font-mono truncate text-gray-400">
+              {f.skipped ? '–' : '✓'} {relative(f.input)} → {f.output}
+            </p>
+          ))}
+          {failed.map((f: any) => (
+            <p key={f.input} className="text-xs font-mono truncate">
+              <span className="text-red-400">✗</span> {relative(f.input)}
+              <span className="text-gray-500"> {f.error}</span>
+            </p>
+          ))}
+        </div>
+      </>
+    );
+  };
+
+  const renderLinterRun = (msg: Message) => {
+    const result = msg.result;
+    const summary = result.summary;
+    const pending = result.files.filter((f: any) => f.pendingId).length;
+    const interesting = result.files.filter((f: any) => f.error || f.changed || f.diagnostics?.length > 0);
+
+    return (
+      <>
+        <p className="text-xs text-gray-300 break-all mb-1 font-mono">{summary.root}</p>
+        <div className="text-xs text-gray-400 space-y-0.5">
+          <p>{summary.filesProcessed} files in {(summary.durationMs / 1000).toFixed(1)}s</p>
+          {summary.mode === 'format'
+            ? <p>{summary.filesChanged} would change</p>
+            : <p>
+                <span className="text-red-400">{summary.diagnostics.error} errors</span>
+                {' · '}
+                <span className="text-amber-400">{summary.diagnostics.warning} warnings</span>
+                {' · '}
+                {summary.diagnostics.info} info
+              </p>}
+          {summary.filesFailed > 0 && <p className="text-red-400">{summary.filesFailed} failed</p>}
+          {summary.toolsMissing?.length > 0 && (
+            <p className="text-amber-400">Missing: {summary.toolsMissing.join(', ')}</p>
+          )}
+        </div>
+        {pending > 0 && (
+          <button
+            onClick={() => handleApplyAll(msg)}
+            className="mt-2 px-3 py-1 rounded text-xs bg-purple-700 text-white hover:opacity-80"
+          >
+            Apply {pending} change{pending === 1 ? '' : 's'}
+          </button>
+        )}
+        <div className="mt-2 space-y-2 max-h-96 overflow-y-auto">
+          {interesting.map((file: any) => (
+            <div key={file.filePath} className="p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
+              <p className="text-xs font-mono text-gray-300 break-all">{file.filePath.replace(summary.root + '/', '')}</p>
+              {file.error && <p className="text-xs text-red-400">{file.error}</p>}
+              {file.diagnostics?.map((d: any, idx: number) => (
+                <p key={idx} className="text-xs font-mono text-gray-400">
+                  <span className={d.severity === 'error' ? 'text-red-400' : 'text-amber-400'}>{d.line}:{d.column}</span> {d.message}
+                </p>
+              ))}
+              {file.diff && file.pendingId && renderDiff(file.diff)}
+            </div>
+          ))}
+        </div>
+      </>
+    );
+  };
+
+  const renderDiff = (diff: string) => (
+    <pre className="text-xs mt-2 p-2 rounded overflow-x-auto font-mono" style={{ backgroundColor: '#0A0E10' }}>
+      {diff.split('\n').map((line, idx) => (
+        <div
+          key={idx}
+          className={
+            line.startsWith('+') && !line.startsWith('+++') ? 'text-emerald-400'
+              : line.startsWith('-') && !line.startsWith('---') ? 'text-red-400'
+              : line.startsWith('@@') ? 'text-blue-400'
+              : 'text-gray-400'
+          }
+        >
+          {line || ' '}
+        </div>
+      ))}
+    </pre>
+  );
+
+  const renderPipeline = (msg: Message) => (
+    <div className="space-y-2">
+      {msg.result.stages.map((stage: any, i: number) => (
+        <div key={i}>
+          <div className="flex items-center gap-2 text-xs">
+            <span className="text-gray-500">{i + 1}.</span>
+            <span className="px-2 py-0.5 rounded bg-gray-800 text-gray-400">{stage.service}</span>
+            <span className="text-gray-400 font-mono truncate" title={stage.query}>
+              {stage.query.split('\n')[0]}
+            </span>
+            <span className="text-gray-600 ml-auto flex-shrink-0">{stage.durationMs}ms</span>
+          </div>
+          {stage.success
+            ? renderResult({ ...msg, service: stage.service, result: stage.result, error: undefined })
+            : renderResult({ ...msg, service: stage.service, result: null, error: stage.error })}
+        </div>
+      ))}
+      {msg.result.stages.length < msg.result.total && (
+        <p className="text-xs text-gray-500">
+          {msg.result.total - msg.result.stages.length} more step(s) skipped
+        </p>
+      )}
+    </div>
+  );
+
+  const renderResult = (msg: Message): JSX.Element | null => {
+    if (msg.service === 'pipeline' && msg.result?.stages) {
+      return <div className="mt-2">{renderPipeline(msg)}</div>;
+    }
+
+    // Help is plain text, already the message itself
+    if (msg.service === 'help' && !msg.error) {
+      return null;
+    }
+
+    // Stopping recordings and clipboard screenshots have no file to show
+    if (msg.service === 'screenshot' && !msg.error && !msg.result?.path) {
+      return null;
+    }
+
     if (!msg.result || msg.error) {
       if (msg.error) {
         return (
@@ -378,7 +1341,14 @@ function App() {
       linter: { border: 'border-purple-900/30', bg: '#0F1416', accent: 'text-purple-400' },
       ocr: { border: 'border-amber-900/30', bg: '#0F1416', accent: 'text-amber-400' },
       converter: { border: 'border-cyan-900/30', bg: '#0F1416', accent: 'text-cyan-400' },
+      document: { border: 'border-sky-900/30', bg: '#0F1416', accent: 'text-sky-400' },
       llm: { border: 'border-pink-900/30', bg: '#0F1416', accent: 'text-pink-400' },
+      clipboard: { border: 'border-teal-900/30', bg: '#0F1416', accent: 'text-teal-400' },
+      calculator: { border: 'border-lime-900/30', bg: '#0F1416', accent: 'text-lime-400' },
+      launcher: { border: 'border-orange-900/30', bg: '#0F1416', accent: 'text-orange-400' },
+      desktop: { border: 'border-indigo-900/30', bg: '#0F1416', accent: 'text-indigo-400' },
+      screenshot: { border: 'border-rose-900/30', bg: '#0F1416', accent: 'text-rose-400' },
+      notes: { border: 'border-yellow-900/30', bg: '#0F1416', accent: 'text-yellow-400' },
     };
 
     const style = resultStyles[msg.service as keyof typeof resultStyles] || resultStyles.llm;
@@ -392,6 +1362,13 @@ function App() {
               {msg.result.path}
             </p>
             <p className="text-xs text-gray-500 mt-1">Type: {msg.result.type}</p>
+            {msg.result.matches?.length > 0 && (
+              <div className="mt-2 p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
+                {msg.result.matches.map((line: string, i: number) => (
+                  <p key={i} className="text-xs text-gray-400 break-words">{line}</p>
+                ))}
+              </div>
+            )}
           </>
         )}
 
@@ -404,15 +1381,47 @@ function App() {
           </>
         )}
 
-        {msg.service === 'linter' && (
+        {msg.service === 'linter' && msg.result.summary && renderLinterRun(msg)}
+
+        {msg.service === 'linter' && !msg.result.summary && (
           <>
             <p className={`font-medium ${style.accent} text-xs mb-2`}>
-              {msg.result.fixed ? 'Formatted' : 'Checked'}
+              {msg.result.fixed ? 'Formatted' : msg.result.pendingId ? 'Preview' : 'Checked'}
             </p>
             <p className="text-xs text-gray-300 break-all mb-1 font-mono">
               {msg.result.filePath}
             </p>
-            {msg.result.output && (
+            {msg.result.mode === 'check' && msg.result.diagnostics?.length > 0 && (
+              <div className="mt-2 space-y-1">
+                {msg.result.diagnostics.map((d: any, idx: number) => (
+                  <div key={idx} className="text-xs font-mono p-1.5 rounded" style={{ backgroundColor: '#0A0E10' }}>
+                    <span className={d.severity === 'error' ? 'text-red-400' : d.severity === 'warning' ? 'text-amber-400' : 'text-gray-400'}>
+                      {d.line}:{d.column} {d.severity}
+                    </span>
+                    {d.rule && <span className="text-gray-500"> [{d.rule}]</span>}
+                    <p className="text-gray-300 break-words">{d.message}</p>
+                  </div>
+                ))}
+              </div>
+            )}
+            {msg.result.diff && renderDiff(msg.result.diff)}
+            {msg.result.pendingId && (
+              <div className="flex gap-2 mt-2">
+                <button
+                  onClick={() => handleApplyFormat(msg)}
+                  className="px-3 py-1 rounded text-xs bg-purple-700 text-white hover:opacity-80"
+                >
+                  Apply
+                </button>
+                <button
+                  onClick={() => handleDiscardFormat(msg)}
+                  className="px-3 py-1 rounded text-xs bg-gray-800 text-gray-300 hover:bg-gray-700"
+                >
+                  Discard
+                </button>
+              </div>
+            )}
+            {msg.result.mode !== 'check' && !msg.result.diff && msg.result.output && (
               <pre className="text-xs text-gray-400 mt-2 whitespace-pre-wrap font-mono">
                 {msg.result.output}
               </pre>
@@ -420,23 +1429,171 @@ function App() {
           </>
         )}
 
-        {msg.service === 'ocr' && (
+        {msg.service === 'ocr' && msg.result.entries && renderOCRHistory(msg)}
+
+        {msg.service === 'ocr' && msg.result.summary && renderOCRBatch(msg)}
+
+        {msg.service === 'ocr' && !msg.result.entries && !msg.result.summary && (
           <>
-            <p className={`font-medium ${style.accent} text-xs mb-2`}>Extracted Text</p>
+            <div className="flex items-center justify-between mb-2">
+              <p className={`font-medium ${style.accent} text-xs`}>Extracted Text</p>
+              <span className="text-xs text-gray-500">
+                {msg.result.confidence} ({Math.round(msg.result.meanConfidence || 0)}%) · {msg.result.language}
+              </span>
+            </div>
             <div className="p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
               <pre className="text-xs text-gray-300 whitespace-pre-wrap break-words">
                 {msg.result.text}
               </pre>
             </div>
+            {msg.result.entities?.length > 0 && (
+              <div className="flex flex-wrap gap-1 mt-2">
+                {msg.result.entities.map((e: any, i: number) => (
+                  <button
+                    key={i}
+                    onClick={() => (e.type === 'url' ? OpenURL(e.value) : CopyToClipboard(e.value))}
+                    title={e.type === 'url' ? 'Open link' : 'Copy'}
+                    className="text-xs px-2 py-0.5 rounded bg-gray-800 text-gray-300 hover:bg-gray-700 flex items-center gap-1"
+                  >
+                    {e.type === 'color' && (
+                      <span className="inline-block w-2 h-2 rounded-sm" style={{ backgroundColor: e.value }} />
+                    )}
+                    <span className="text-gray-500">{e.type}</span> {e.value}
+                  </button>
+                ))}
+              </div>
+            )}
+            <div className="flex gap-2 mt-2">
+              <button
+                onClick={() => handleCopyText(msg)}
+                className="text-xs px-2 py-1 rounded bg-gray-800 text-gray-300 hover:bg-gray-700"
+              >
+                {msg.result.copied ? 'Copied' : 'Copy'}
+              </button>
+              {msg.result.words?.length > 0 && (
+                <>
+                  <button
+                    onClick={() => handleOCRTable(msg, 'csv')}
+                    className="text-xs px-2 py-1 rounded bg-gray-800 text-gray-300 hover:bg-gray-700"
+                  >
+                    Table CSV
+                  </button>
+                  <button
+                    onClick={() => handleOCRTable(msg, 'markdown')}
+                    className="text-xs px-2 py-1 rounded bg-gray-800 text-gray-300 hover:bg-gray-700"
+                  >
+                    Markdown
+                  </button>
+                </>
+              )}
+            </div>
+            {msg.result.openedUrl && (
+              <p className="text-xs text-gray-500 mt-2 break-all">Opened {msg.result.openedUrl}</p>
+            )}
+            {msg.result.actionError && (
+              <p className="text-xs text-red-400 mt-2 break-all">{msg.result.actionError}</p>
+            )}
+            {msg.result.table && (
+              <div className="mt-2">
+                <div className="flex items-center justify-between mb-1">
+                  <p className="text-xs text-gray-500">Table ({msg.result.tableFormat})</p>
+                  <button
+                    onClick={() => CopyToClipboard(msg.result.table)}
+                    className="text-xs text-gray-400 hover:text-gray-200"
+                  >
+                    Copy table
+                  </button>
+                </div>
+                <pre className="text-xs text-gray-300 whitespace-pre p-2 rounded overflow-x-auto font-mono"
+                     style={{ backgroundColor: '#0A0E10' }}>
+                  {msg.result.table}
+                </pre>
+              </div>
+            )}
           </>
         )}
 
-        {msg.service === 'converter' && (
+        {msg.service === 'converter' && msg.result.summary && renderConversionBatch(msg)}
+
+        {msg.service === 'converter' && msg.result.streams && (
+          <>
+            <p className={`font-medium ${style.accent} text-xs mb-2`}>Media info</p>
+            <p className="text-xs text-gray-300 break-all font-mono">{msg.result.path}</p>
+            {renderMediaInfo(msg.result, 'Container')}
+          </>
+        )}
+
+        {msg.service === 'converter' && !msg.result.streams && !msg.result.summary && (
           <>
             <p className={`font-medium ${style.accent} text-xs mb-2`}>Converted</p>
             <p className="text-xs text-gray-300 break-all font-mono">
               {msg.result.outputPath}
             </p>
+            {msg.result.fileSize > 0 && (
+              <p className="text-xs text-gray-500 mt-1">
+                {(msg.result.fileSize / 1024 / 1024).toFixed(1)} MB
+                {msg.result.width > 0 && ` · ${msg.result.width}×${msg.result.height}`}
+                {msg.result.tool && msg.result.tool !== 'go' && ` · via ${msg.result.tool}`}
+              </p>
+            )}
+            {(msg.result.preset || describeConversion(msg.result.options).length > 0) && (
+              <div className="flex flex-wrap gap-1 mt-2">
+                {msg.result.preset && (
+                  <span className="text-xs px-2 py-0.5 rounded bg-cyan-900/40 text-cyan-300">{msg.result.preset}</span>
+                )}
+                {describeConversion(msg.result.options).map((label: string) => (
+                  <span key={label} className="text-xs px-2 py-0.5 rounded bg-gray-800 text-gray-400">{label}</span>
+                ))}
+              </div>
+            )}
+            {msg.result.source && renderMediaInfo(msg.result.source, 'Source')}
+            {msg.result.output && renderMediaInfo(msg.result.output, 'Output')}
+          </>
+        )}
+
+        {msg.service === 'document' && (
+          <>
+            <p className={`font-medium ${style.accent} text-xs mb-2`}>Converted</p>
+            <p className="text-xs text-gray-300 break-all font-mono">{msg.result.outputPath}</p>
+            <p className="text-xs text-gray-500 mt-1">
+              {msg.result.outputPaths?.length > 0 && `${msg.result.outputPaths.length} pages · `}
+              {msg.result.fileSize > 0 && `${(msg.result.fileSize / 1024).toFixed(0)} KB · `}
+              via {msg.result.tool}
+            </p>
+            {msg.result.outputPaths?.length > 0 && (
+              <div className="mt-2 space-y-0.5 max-h-32 overflow-y-auto">
+                {msg.result.outputPaths.map((path: string) => (
+                  <p key={path} className="text-xs text-gray-400 font-mono truncate">{path}</p>
+                ))}
+              </div>
+            )}
+          </>
+        )}
+
+        {msg.service === 'clipboard' && renderClipboard(msg)}
+
+        {msg.service === 'launcher' && renderLauncher(msg)}
+
+        {msg.service === 'desktop' && renderDesktop(msg)}
+
+        {msg.service === 'screenshot' && renderScreenshot(msg)}
+
+        {msg.service === 'notes' && renderNotes(msg)}
+
+        {msg.service === 'calculator' && (
+          <>
+            <div className="flex items-center justify-between mb-2">
+              <p className={`font-medium ${style.accent} text-xs`}>{msg.result.expression}</p>
+              <button onClick={() => CopyToClipboard(msg.result.result)} className="text-xs text-gray-400 hover:text-gray-200">
+                Copy
+              </button>
+            </div>
+            <p className="text-lg text-gray-100 font-mono break-all">{msg.result.result}</p>
+            {(msg.result.hex || msg.result.binary) && (
+              <p className="text-xs text-gray-500 font-mono mt-1 break-all">
+                {msg.result.hex} · {msg.result.binary}
+              </p>
+            )}
           </>
         )}
 
@@ -472,15 +1629,117 @@ function App() {
           <p className="text-xs text-gray-500 mt-0.5">intelligent command center</p>
         </div>
 
-        <button
-          onClick={() => setShowQuickActions(!showQuickActions)}
-          className="p-2 rounded-lg hover:bg-gray-800/50 transition-colors"
-          title="Toggle Quick Actions"
-        >
-          <HelpCircle size={18} className="text-gray-400" />
-        </button>
+        <div className="flex items-center gap-1">
+          <button
+            onClick={toggleJobs}
+            className="p-2 rounded-lg hover:bg-gray-800/50 transition-colors flex items-center gap-1"
+            title="Jobs"
+          >
+            <ListChecks size={18} className="text-gray-400" />
+            {runningJobs.length > 0 && <span className="text-xs text-cyan-400">{runningJobs.length}</span>}
+          </button>
+          <button
+            onClick={() => setShowHistory(!showHistory)}
+            className="p-2 rounded-lg hover:bg-gray-800/50 transition-colors"
+            title="History"
+          >
+            <History size={18} className="text-gray-400" />
+          </button>
+          <button
+            onClick={() => setShowQuickActions(!showQuickActions)}
+            className="p-2 rounded-lg hover:bg-gray-800/50 transition-colors"
+            title="Toggle Quick Actions"
+          >
+            <HelpCircle size={18} className="text-gray-400" />
+          </button>
+        </div>
       </div>
 
+      {/* History Panel */}
+      {showHistory && (
+        <div className="flex-shrink-0 border-b max-h-72 overflow-y-auto" style={{ backgroundColor: '#141B1E', borderColor: '#1E3A5F' }}>
+          <div className="max-w-4xl mx-auto px-4 py-3">
+            <input
+              value={historyTerms}
+              onChange={(e) => setHistoryTerms(e.target.value)}
+              placeholder="Search history..."
+              className="w-full mb-2 px-3 py-1.5 rounded-lg border outline-none text-xs"
+              style={{ backgroundColor: '#0F1416', borderColor: '#1E3A5F', color: '#e5e7eb' }}
+            />
+            {historyEntries.length === 0 ? (
+              <p className="text-xs text-gray-500">No past queries</p>
+            ) : (
+              historyEntries.map(entry => (
+                <div key={entry.id} className="flex items-center gap-2 py-1 text-xs group">
+                  <button onClick={() => togglePin(entry)} title={entry.pinned ? 'Unpin' : 'Pin'}>
+                    <Star size={12} className={entry.pinned ? 'text-yellow-400 fill-yellow-400' : 'text-gray-600 hover:text-yellow-400'} />
+                  </button>
+                  <span className={entry.success ? 'text-green-400' : 'text-red-400'}>{entry.success ? '✓' : '✗'}</span>
+                  {entry.redacted ? (
+                    <span className="font-mono text-gray-400 truncate" title="The saved text isn't kept in the history">
+                      {entry.query}
+                    </span>
+                  ) : (
+                    <button
+                      onClick={() => editHistoryEntry(entry)}
+                      className="font-mono text-gray-300 truncate text-left hover:text-blue-400"
+                      title="Edit"
+                    >
+                      {entry.query}
+                    </button>
+                  )}
+                  <span className="text-gray-500 truncate flex-1">{entry.success ? entry.summary : entry.error}</span>
+                  <span className="text-gray-600 flex-shrink-0">{new Date(entry.timestamp).toLocaleDateString()}</span>
+                  {!entry.redacted && (
+                    <button onClick={() => handleSubmit(entry.query)} title="Run again" className="text-gray-500 hover:text-blue-400">
+                      <Play size={12} />
+                    </button>
+                  )}
+                  <button onClick={() => deleteHistoryEntry(entry)} title="Delete" className="text-gray-500 hover:text-red-400">
+                    <Trash2 size={12} />
+                  </button>
+                </div>
+              ))
+            )}
+          </div>
+        </div>
+      )}
+
+      {/* Jobs Panel */}
+      {showJobs && (
+        <div className="flex-shrink-0 border-b max-h-56 overflow-y-auto" style={{ backgroundColor: '#141B1E', borderColor: '#1E3A5F' }}>
+          <div className="max-w-4xl mx-auto px-4 py-3">
+            {jobs.length === 0 ? (
+              <p className="text-xs text-gray-500">No jobs yet</p>
+            ) : (
+              jobs.map(job => (
+                <div key={job.id} className="flex items-center gap-3 py-1 text-xs">
+                  <span className={jobStatusColor(job.status)}>{job.status}</span>
+                  <span className="text-gray-500 w-16 flex-shrink-0">{job.service}</span>
+                  <span className="font-mono text-gray-300 truncate flex-1">{job.query}</span>
+                  {job.status === 'running' && job.progress >= 0 && (
+                    <div className="h-1.5 w-24 rounded bg-gray-800 overflow-hidden flex-shrink-0">
+                      <div className="h-full bg-cyan-500" style={{ width: `${job.progress * 100}%` }} />
+                    </div>
+                  )}
+                  <span className="text-gray-500 truncate max-w-[10rem]">
+                    {job.status === 'running' ? job.message : job.error}
+                  </span>
+                  <span className="text-gray-500 w-12 text-right flex-shrink-0">{formatETA(job.durationMs / 1000)}</span>
+                  {job.status === 'running' ? (
+                    <button onClick={() => CancelJob(job.id)} className="text-gray-500 hover:text-red-400 flex-shrink-0">
+                      Cancel
+                    </button>
+                  ) : (
+                    <span className="w-10 flex-shrink-0" />
+                  )}
+                </div>
+              ))
+            )}
+          </div>
+        </div>
+      )}
+
       {/* Messages Area */}
       <div className="flex-1 overflow-y-auto">
         {showQuickActions && (
@@ -547,6 +1806,25 @@ function App() {
             <p className="text-sm mb-6 text-gray-500 text-center max-w-md">
               Use quick actions above or type your command below
             </p>
+            <div className="grid grid-cols-2 md:grid-cols-3 gap-4 max-w-3xl w-full">
+              {examples.map(group => (
+                <div key={group.category}>
+                  <p className="text-xs font-medium text-gray-400 mb-1">{group.icon} {group.category}</p>
+                  {group.queries.slice(0, 3).map(query => (
+                    <button
+                      key={query}
+                      onClick={() => {
+                        setInput(query);
+                        inputRef.current?.focus();
+                      }}
+                      className="block text-left text-xs text-gray-500 hover:text-blue-400 font-mono truncate w-full"
+                    >
+                      {query}
+                    </button>
+                  ))}
+                </div>
+              ))}
+            </div>
           </div>
         ) : messages.length > 0 ? (
           <div className="max-w-4xl mx-auto px-4 py-6 space-y-3">
@@ -574,6 +1852,85 @@ function App() {
               <div className="flex justify-start">
                 <div className="rounded-lg px-4 py-2.5 rounded-bl-sm" style={{ backgroundColor: '#141B1E' }}>
                   <Loader2 className="animate-spin text-gray-500" size={16} />
+                  {runningJobs.map(job => (
+                    <div key={job.id} className="mt-2 text-xs text-gray-400 flex items-center justify-between gap-3 w-64">
+                      <span className="font-mono truncate">{job.message || job.query}</span>
+                      <button onClick={() => CancelJob(job.id)} className="text-gray-500 hover:text-red-400">
+                        Cancel
+                      </button>
+                    </div>
+                  ))}
+                  {linterRun && (
+                    <div className="mt-2 text-xs text-gray-400">
+                      <p>{linterRun.processed}/{linterRun.total} files in {linterRun.root}</p>
+                      {linterRun.recent.map((r: any) => (
+                        <p key={r.filePath} className="font-mono truncate">
+                          {r.error ? '✗' : r.diagnostics?.length ? '!' : '✓'} {r.filePath.replace(linterRun.root + '/', '')}
+                        </p>
+                      ))}
+                    </div>
+                  )}
+                  {conversionRun && (
+                    <div className="mt-2 text-xs text-gray-400 flex items-center justify-between w-64">
+                      <span>{conversionRun.processed}/{conversionRun.total} files</span>
+                      <button
+                        onClick={() => CancelConversion(conversionRun.runId)}
+                        className="text-gray-500 hover:text-red-400"
+                      >
+                        Cancel all
+                      </button>
+                    </div>
+                  )}
+                  {Object.values(conversions).map(conversion => (
+                    <div key={conversion.jobId} className="mt-2 text-xs text-gray-400 w-64">
+                      <p className="font-mono truncate">{conversion.output}</p>
+                      {conversion.duration > 0 && (
+                        <div className="h-1.5 rounded bg-gray-800 mt-1 overflow-hidden">
+                          <div className="h-full bg-cyan-500" style={{ width: `${conversion.percent}%` }} />
+                        </div>
+                      )}
+                      <div className="flex items-center justify-between mt-1">
+                        <span>
+                          {conversion.passes > 1 && `pass ${conversion.pass}/${conversion.passes} · `}
+                          {conversion.duration > 0 && `${Math.round(conversion.percent)}% · `}
+                          {conversion.speed > 0 && `${conversion.speed.toFixed(1)}x`}
+                          {conversion.etaSeconds > 0 && ` · ${formatETA(conversion.etaSeconds)} left`}
+                        </span>
+                        <button
+                          onClick={() => CancelConversion(conversion.jobId)}
+                          className="text-gray-500 hover:text-red-400"
+                        >
+                          Cancel
+                        </button>
+                      </div>
+                    </div>
+                  ))}
+                  {Object.values(recordings).map(rec => (
+                    <div key={rec.recordingId} className="mt-2 text-xs text-gray-400 flex items-center justify-between gap-3 w-64">
+                      <span className="font-mono truncate">
+                        <span className="text-rose-400">●</span> {rec.output.split('/').pop()}
+                        {rec.duration > 0 && ` · ${formatETA(rec.duration)}`}
+                      </span>
+                      <button onClick={() => StopRecording(rec.recordingId)} className="text-gray-500 hover:text-rose-400">
+                        Stop
+                      </button>
+                    </div>
+                  ))}
+                  {conversionRun?.recent.map((f: any) => (
+                    <p key={f.input} className="mt-0.5 text-xs text-gray-400 font-mono truncate w-64">
+                      {f.error ? '✗' : f.skipped ? '–' : '✓'} {f.input.replace(conversionRun.root + '/', '')}
+                    </p>
+                  ))}
+                  {ocrRun && (
+                    <div className="mt-2 text-xs text-gray-400">
+                      <p>{ocrRun.processed}/{ocrRun.total} files in {ocrRun.root}</p>
+                      {ocrRun.recent.map((f: any) => (
+                        <p key={f.path} className="font-mono truncate">
+                          {f.error ? '✗' : f.skipped ? '–' : '✓'} {f.path.replace(ocrRun.root + '/', '')}
+                        </p>
+                      ))}
+                    </div>
+                  )}
                 </div>
               </div>
             )}
@@ -612,15 +1969,66 @@ function App() {
               </div>
             )}
 
+            {/* Query suggestions and typo fix */}
+            {!showSuggestions && (typoFix || querySuggestions.length > 0) && (
+              <div
+                className="absolute bottom-full mb-2 w-full rounded-lg border shadow-lg"
+                style={{
+                  backgroundColor: '#0F1416',
+                  borderColor: '#1E3A5F'
+                }}
+              >
+                {typoFix && (
+                  <button
+                    onClick={() => setInput(typoFix)}
+                    className="w-full text-left px-3 py-2 text-xs border-b last:border-b-0 hover:bg-gray-800/50"
+                    style={{ borderColor: '#1E3A5F' }}
+                  >
+                    <span className="text-gray-500">Did you mean </span>
+                    <span className="font-mono text-amber-400">{typoFix}</span>
+                    <span className="text-gray-500">?</span>
+                  </button>
+                )}
+                {querySuggestions.map(suggestion => (
+                  <div
+                    key={suggestion.query}
+                    className="px-3 py-2 text-xs border-b last:border-b-0"
+                    style={{ borderColor: '#1E3A5F' }}
+                  >
+                    <div className="flex items-center justify-between gap-3">
+                      <span className="font-mono text-gray-200">{suggestion.query}</span>
+                      <span className="text-gray-500 truncate">{suggestion.description}</span>
+                    </div>
+                    <div className="flex flex-wrap gap-1 mt-1">
+                      {suggestion.examples.map(example => (
+                        <button
+                          key={example}
+                          onClick={() => {
+                            setInput(example);
+                            inputRef.current?.focus();
+                          }}
+                          className="px-2 py-0.5 rounded bg-gray-800 text-gray-400 hover:text-blue-400 font-mono"
+                        >
+                          {example}
+                        </button>
+                      ))}
+                    </div>
+                  </div>
+                ))}
+              </div>
+            )}
+
             {/* Input */}
             <div className="flex items-end gap-2">
               <textarea
                 ref={inputRef}
                 value={input}
-                onChange={(e) => setInput(e.target.value)}
+                onChange={(e) => {
+                  setInput(e.target.value);
+                  setHistoryIndex(-1);
+                }}
                 onKeyDown={handleKeyDown}
                 placeholder="Ask me anything or use quick actions above..."
-                disabled={loading}
                 rows={1}
                 className="flex-1 px-3 py-2.5 rounded-lg resize-none border outline-none text-sm"
                 style={{
@@ -632,15 +2040,11 @@ function App() {
               />
               <button
                 onClick={() => handleSubmit()}
-                disabled={loading || !input.trim()}
+                disabled={!input.trim()}
                 className="p-2.5 rounded-lg transition-all disabled:opacity-40 disabled:cursor-not-allowed flex-shrink-0 hover:opacity-80"
                 style={{ backgroundColor: '#1E3A5F' }}
               >
-                {loading ? (
-                  <Loader2 className="animate-spin text-gray-100" size={18} />
-                ) : (
-                  <Send size={18} className="text-gray-100" />
-                )}
+                <Send size={18} className="text-gray-100" />
               </button>
             </div>
           </div>
@@ -2,7 +2,11 @@ module Aoiler
 
 go 1.22.0
 
-require github.com/wailsapp/wails/v2 v2.10.2
+require (
+	github.com/wailsapp/wails/v2 v2.10.2
+	golang.org/x/image v0.24.0
+	shared v0.0.0
+)
 
 require (
 	github.com/bep/debounce v1.2.1 // indirect
@@ -35,3 +39,5 @@ require (
 )
 
 // replace github.com/wailsapp/wails/v2 v2.10.2 => /home/dawu/go/pkg/mod
+
+replace shared => ../shared
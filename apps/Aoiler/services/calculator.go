@@ -0,0 +1,815 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// calcPrefix is dropped before evaluating, "what is 2^10" is just "2^10"
+var calcPrefix = regexp.MustCompile(`(?i)^\s*(?:calc(?:ulate)?|what\s+is|what's|how\s+much\s+is|=)\s+`)
+
+// unitPattern matches "5 km to miles" and "convert 72°F in c"
+var unitPattern = regexp.MustCompile(`(?i)^(?:convert\s+)?([-+]?(?:\d[\d,]*\.?\d*|\.\d+)(?:e[-+]?\d+)?)\s*([a-z°µ]+(?:\s+[a-z]+)?)\s+(?:to|in|into|as)\s+([a-z°µ]+(?:\s+[a-z]+)?)$`)
+
+// basePattern matches "255 to hex" and "0xff + 1 in binary"
+var basePattern = regexp.MustCompile(`(?i)^(?:convert\s+)?(.+?)\s+(?:to|in|as)\s+(hex|hexadecimal|bin|binary|oct|octal|dec|decimal)$`)
+
+// assignPattern matches "rate = 0.19"
+var assignPattern = regexp.MustCompile(`^([a-zA-Z_]\w*)\s*=\s*(.+)$`)
+
+// daysPattern matches "days until 2026-12-24" and "weeks since 2025-01-01"
+var daysPattern = regexp.MustCompile(`(?i)^(days|weeks)\s+(until|till|to|since)\s+(.+)$`)
+
+// CalculatorResult is an evaluated expression or unit conversion
+type CalculatorResult struct {
+	Expression string `json:"expression"`
+	// Result is the value formatted for display, with its unit if it has one
+	Result string `json:"result"`
+	// Kind is "number", "date", "duration" or "unit"
+	Kind  string  `json:"kind"`
+	Value float64 `json:"value"`
+	// Variable is set when the expression assigned one
+	Variable string `json:"variable,omitempty"`
+	// Hex and Binary show integer results in other bases
+	Hex    string `json:"hex,omitempty"`
+	Binary string `json:"binary,omitempty"`
+}
+
+// CalculatorService evaluates expressions and converts units offline.
+// Variables, including "ans" for the last result, live as long as the app.
+type CalculatorService struct {
+	mu   sync.Mutex
+	vars map[string]calcValue
+}
+
+func NewCalculatorService() *CalculatorService {
+	return &CalculatorService{vars: make(map[string]calcValue)}
+}
+
+// Handles reports whether a query is a calculation or a unit conversion.
+// It evaluates the query without storing anything, so only queries that
+// would succeed are claimed; unit-shaped ones are claimed even when the
+// units don't match, so the error comes from here rather than ffmpeg.
+func (cs *CalculatorService) Handles(query string) bool {
+	expr := strings.TrimSpace(calcPrefix.ReplaceAllString(query, ""))
+	if m := unitPattern.FindStringSubmatch(expr); m != nil {
+		_, fromOK := lookupUnit(m[2])
+		_, toOK := lookupUnit(m[3])
+		if fromOK && toOK {
+			return true
+		}
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	_, err := cs.evaluateLocked(query, false)
+	return err == nil
+}
+
+// Evaluate runs a calculation. "x = 5" stores x for later queries.
+func (cs *CalculatorService) Evaluate(query string) (CalculatorResult, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.evaluateLocked(query, true)
+}
+
+func (cs *CalculatorService) evaluateLocked(query string, commit bool) (CalculatorResult, error) {
+	expr := strings.TrimSpace(calcPrefix.ReplaceAllString(query, ""))
+	expr = strings.TrimSpace(strings.TrimRight(expr, "?= "))
+	result := CalculatorResult{Expression: expr}
+	if expr == "" {
+		return result, fmt.Errorf("nothing to calculate")
+	}
+
+	if m := unitPattern.FindStringSubmatch(expr); m != nil {
+		if _, ok := lookupUnit(m[2]); ok {
+			return convertUnits(result, m[1], m[2], m[3])
+		}
+	}
+
+	if m := basePattern.FindStringSubmatch(expr); m != nil {
+		value, err := cs.parseLocked(m[1])
+		if err != nil {
+			return result, err
+		}
+		return formatBase(result, value, strings.ToLower(m[2]))
+	}
+
+	if m := daysPattern.FindStringSubmatch(expr); m != nil {
+		span := "(" + m[3] + ") - today"
+		if strings.EqualFold(m[2], "since") {
+			span = "today - (" + m[3] + ")"
+		}
+		value, err := cs.parseLocked(span)
+		if err != nil {
+			return result, err
+		}
+		if value.kind != calcSpan {
+			return result, fmt.Errorf("%s is not a date", m[3])
+		}
+		days := value.spanDays()
+		result.Kind, result.Value = "duration", days
+		result.Result = formatNumber(days) + " days"
+		if strings.EqualFold(m[1], "weeks") {
+			result.Value = days / 7
+			result.Result = formatNumber(days/7) + " weeks"
+		}
+		return result, nil
+	}
+
+	name := ""
+	if m := assignPattern.FindStringSubmatch(expr); m != nil {
+		if _, reserved := calcFunctions[strings.ToLower(m[1])]; reserved || isCalcConstant(m[1]) || m[1] == "ans" {
+			return result, fmt.Errorf("%s can't be used as a variable name", m[1])
+		}
+		name, expr = m[1], m[2]
+	} else if !hasOperator(expr) {
+		// A lone number or word isn't a calculation
+		return result, fmt.Errorf("no operator in %q", expr)
+	}
+
+	value, err := cs.parseLocked(expr)
+	if err != nil {
+		return result, err
+	}
+	result = describeValue(result, value)
+	if commit {
+		if name != "" {
+			cs.vars[name] = value
+			result.Variable = name
+		}
+		cs.vars["ans"] = value
+	}
+	return result, nil
+}
+
+func (cs *CalculatorService) parseLocked(expr string) (calcValue, error) {
+	tokens, err := tokenizeCalc(expr)
+	if err != nil {
+		return calcValue{}, err
+	}
+	p := &calcParser{tokens: tokens, vars: cs.vars}
+	value, err := p.parseExpr(0)
+	if err != nil {
+		return calcValue{}, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return calcValue{}, fmt.Errorf("unexpected %q", tok.text)
+	}
+	if err := checkFinite(value.num + value.seconds); err != nil {
+		return calcValue{}, err
+	}
+	return value, nil
+}
+
+// checkFinite turns the Inf and NaN float math gives for "2 ** 1024" or
+// "sqrt(-1)" into an error instead of showing them as a result
+func checkFinite(n float64) error {
+	switch {
+	case math.IsNaN(n):
+		return fmt.Errorf("result is undefined")
+	case math.IsInf(n, 0):
+		return fmt.Errorf("result is too large")
+	}
+	return nil
+}
+
+func hasOperator(expr string) bool {
+	return strings.ContainsAny(expr, "+-*/%^&|()×÷<>") || strings.Contains(strings.ToLower(expr), " mod ")
+}
+
+// describeValue formats an evaluated value into the result
+func describeValue(result CalculatorResult, value calcValue) CalculatorResult {
+	switch value.kind {
+	case calcDate:
+		result.Kind = "date"
+		result.Value = float64(value.date.Unix())
+		result.Result = formatDate(value.date)
+	case calcSpan:
+		result.Kind = "duration"
+		result.Value = value.spanDays()
+		result.Result = formatSpan(value)
+	default:
+		n := value.number()
+		result.Kind = "number"
+		result.Value = n
+		result.Result = formatNumber(n)
+		if isWholeNumber(n) && n != 0 {
+			result.Hex = formatInBase(int64(n), 16)
+			result.Binary = formatInBase(int64(n), 2)
+		}
+	}
+	return result
+}
+
+func formatBase(result CalculatorResult, value calcValue, base string) (CalculatorResult, error) {
+	if value.kind != calcNumber || !isWholeNumber(value.number()) {
+		return result, fmt.Errorf("only whole numbers can be shown in %s", base)
+	}
+	n := int64(value.number())
+	result.Kind, result.Value = "number", float64(n)
+	result.Hex, result.Binary = formatInBase(n, 16), formatInBase(n, 2)
+	switch base {
+	case "hex", "hexadecimal":
+		result.Result = result.Hex
+	case "bin", "binary":
+		result.Result = result.Binary
+	case "oct", "octal":
+		result.Result = formatInBase(n, 8)
+	default:
+		result.Result = strconv.FormatInt(n, 10)
+	}
+	return result, nil
+}
+
+func formatInBase(n int64, base int) string {
+	prefix := map[int]string{2: "0b", 8: "0o", 16: "0x"}[base]
+	if n < 0 {
+		return "-" + prefix + strconv.FormatInt(-n, base)
+	}
+	return prefix + strconv.FormatInt(n, base)
+}
+
+// isWholeNumber reports whether n is an integer that int64 holds exactly
+func isWholeNumber(n float64) bool {
+	return n == math.Trunc(n) && math.Abs(n) < 1<<53
+}
+
+// formatNumber rounds away float noise, 0.1+0.2 shows as 0.3
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'g', 12, 64)
+}
+
+func formatDate(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02 (Monday)")
+	}
+	return t.Format("2006-01-02 15:04 (Monday)")
+}
+
+func formatSpan(v calcValue) string {
+	var parts []string
+	if v.months != 0 {
+		years, months := v.months/12, v.months%12
+		if years != 0 {
+			parts = append(parts, plural(years, "year"))
+		}
+		if months != 0 {
+			parts = append(parts, plural(months, "month"))
+		}
+	}
+
+	seconds := v.seconds
+	sign := ""
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	days := int(seconds / 86400)
+	rest := time.Duration((seconds - float64(days)*86400) * float64(time.Second)).Round(time.Second)
+	if days != 0 {
+		parts = append(parts, sign+plural(days, "day"))
+		sign = ""
+	}
+	if rest != 0 {
+		parts = append(parts, sign+rest.String())
+	}
+	if len(parts) == 0 {
+		return "0 days"
+	}
+	return strings.Join(parts, " ")
+}
+
+func plural(n int, unit string) string {
+	if n == 1 || n == -1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// calcValue is a number, a point in time or a span between two
+type calcValue struct {
+	kind int
+	num  float64
+	// percent marks "15%", which adds to or takes from a number relatively
+	percent bool
+	date    time.Time
+	// Spans keep calendar months apart, a month isn't a fixed number of seconds
+	months  int
+	seconds float64
+}
+
+const (
+	calcNumber = iota
+	calcDate
+	calcSpan
+)
+
+func (v calcValue) number() float64 {
+	if v.percent {
+		return v.num / 100
+	}
+	return v.num
+}
+
+func (v calcValue) spanDays() float64 {
+	return float64(v.months)*30.436875 + v.seconds/86400
+}
+
+func numberValue(n float64) calcValue {
+	return calcValue{kind: calcNumber, num: n}
+}
+
+// spanUnits turns "3 weeks" after a number into a span
+var spanUnits = map[string]calcValue{
+	"second": {kind: calcSpan, seconds: 1},
+	"minute": {kind: calcSpan, seconds: 60},
+	"hour":   {kind: calcSpan, seconds: 3600},
+	"day":    {kind: calcSpan, seconds: 86400},
+	"week":   {kind: calcSpan, seconds: 7 * 86400},
+	"month":  {kind: calcSpan, months: 1},
+	"year":   {kind: calcSpan, months: 12},
+}
+
+func lookupSpanUnit(word string) (calcValue, bool) {
+	word = strings.ToLower(word)
+	unit, ok := spanUnits[strings.TrimSuffix(word, "s")]
+	return unit, ok
+}
+
+func scaleSpan(span calcValue, n float64) (calcValue, error) {
+	if span.months != 0 && !isWholeNumber(n) {
+		return calcValue{}, fmt.Errorf("months and years can only be whole numbers")
+	}
+	return calcValue{kind: calcSpan, months: span.months * int(n), seconds: span.seconds * n}, nil
+}
+
+func addValues(a, b calcValue, sign float64) (calcValue, error) {
+	switch {
+	case a.kind == calcNumber && b.kind == calcNumber:
+		// "240 + 15%" adds 15% of 240
+		if b.percent && !a.percent {
+			return numberValue(a.num * (1 + sign*b.num/100)), nil
+		}
+		return numberValue(a.number() + sign*b.number()), nil
+	case a.kind == calcDate && b.kind == calcSpan:
+		months, seconds := b.months, b.seconds
+		if sign < 0 {
+			months, seconds = -months, -seconds
+		}
+		return calcValue{kind: calcDate, date: addMonths(a.date, months).Add(time.Duration(seconds * float64(time.Second)))}, nil
+	case a.kind == calcSpan && b.kind == calcDate && sign > 0:
+		return addValues(b, a, sign)
+	case a.kind == calcDate && b.kind == calcDate && sign < 0:
+		return calcValue{kind: calcSpan, seconds: a.date.Sub(b.date).Seconds()}, nil
+	case a.kind == calcSpan && b.kind == calcSpan:
+		return calcValue{kind: calcSpan, months: a.months + int(sign)*b.months, seconds: a.seconds + sign*b.seconds}, nil
+	}
+	verb := "add"
+	if sign < 0 {
+		verb = "subtract"
+	}
+	return calcValue{}, fmt.Errorf("can't %s a %s and a %s", verb, kindName(a), kindName(b))
+}
+
+// addMonths moves t by calendar months and clamps to the last day of the
+// target month, so Jan 31 + 1 month is Feb 28 rather than Mar 3
+func addMonths(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	month += time.Month(months)
+	if last := time.Date(year, month+1, 0, 0, 0, 0, 0, t.Location()).Day(); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+func kindName(v calcValue) string {
+	return [...]string{"number", "date", "duration"}[v.kind]
+}
+
+// calcFunctions are called as "sqrt(16)" or "max(1, 2, 3)"
+var calcFunctions = map[string]func(args []float64) (float64, error){
+	"sqrt":  unary(math.Sqrt),
+	"abs":   unary(math.Abs),
+	"round": unary(math.Round),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"ln":    unary(math.Log),
+	"log":   unary(math.Log10),
+	"log2":  unary(math.Log2),
+	"exp":   unary(math.Exp),
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"tan":   unary(math.Tan),
+	"min": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("min needs at least one argument")
+		}
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Min(m, a)
+		}
+		return m, nil
+	},
+	"max": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("max needs at least one argument")
+		}
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Max(m, a)
+		}
+		return m, nil
+	},
+}
+
+func unary(fn func(float64) float64) func(args []float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("expected one argument, got %d", len(args))
+		}
+		return fn(args[0]), nil
+	}
+}
+
+func isCalcConstant(name string) bool {
+	switch strings.ToLower(name) {
+	case "pi", "e", "today", "now", "tomorrow", "yesterday":
+		return true
+	}
+	return false
+}
+
+func calcConstant(name string) (calcValue, bool) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(name) {
+	case "pi":
+		return numberValue(math.Pi), true
+	case "e":
+		return numberValue(math.E), true
+	case "now":
+		return calcValue{kind: calcDate, date: now.Truncate(time.Minute)}, true
+	case "today":
+		return calcValue{kind: calcDate, date: today}, true
+	case "tomorrow":
+		return calcValue{kind: calcDate, date: today.AddDate(0, 0, 1)}, true
+	case "yesterday":
+		return calcValue{kind: calcDate, date: today.AddDate(0, 0, -1)}, true
+	}
+	return calcValue{}, false
+}
+
+const (
+	tokEOF = iota
+	tokNumber
+	tokDate
+	tokIdent
+	tokOp
+)
+
+type calcToken struct {
+	kind int
+	text string
+	num  float64
+	date time.Time
+}
+
+var (
+	calcDateToken   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+	calcNumberToken = regexp.MustCompile(`^(?:0x[0-9a-fA-F]+|0b[01]+|0o[0-7]+|(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)`)
+	calcIdentToken  = regexp.MustCompile(`^[a-zA-Z_]\w*`)
+)
+
+func tokenizeCalc(expr string) ([]calcToken, error) {
+	var tokens []calcToken
+	for i := 0; i < len(expr); {
+		rest := expr[i:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t':
+			i++
+		case calcDateToken.MatchString(rest):
+			text := calcDateToken.FindString(rest)
+			date, err := time.ParseInLocation("2006-01-02", text, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date %s", text)
+			}
+			tokens = append(tokens, calcToken{kind: tokDate, text: text, date: date})
+			i += len(text)
+		case calcNumberToken.MatchString(rest):
+			text := calcNumberToken.FindString(rest)
+			n, err := parseCalcNumber(text)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, calcToken{kind: tokNumber, text: text, num: n})
+			i += len(text)
+		case calcIdentToken.MatchString(rest):
+			text := calcIdentToken.FindString(rest)
+			tokens = append(tokens, calcToken{kind: tokIdent, text: text})
+			i += len(text)
+		default:
+			op := ""
+			for _, candidate := range []string{"**", "<<", ">>", "×", "÷", "+", "-", "*", "/", "%", "^", "(", ")", ",", "&", "|"} {
+				if strings.HasPrefix(rest, candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q", string([]rune(rest)[0]))
+			}
+			i += len(op)
+			switch op {
+			case "×":
+				op = "*"
+			case "÷":
+				op = "/"
+			}
+			tokens = append(tokens, calcToken{kind: tokOp, text: op})
+		}
+	}
+	return tokens, nil
+}
+
+func parseCalcNumber(text string) (float64, error) {
+	lower := strings.ToLower(text)
+	for prefix, base := range map[string]int{"0x": 16, "0b": 2, "0o": 8} {
+		if strings.HasPrefix(lower, prefix) {
+			n, err := strconv.ParseInt(lower[2:], base, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid number %s", text)
+			}
+			return float64(n), nil
+		}
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %s", text)
+	}
+	return n, nil
+}
+
+// calcParser is a precedence-climbing parser that evaluates as it goes
+type calcParser struct {
+	tokens []calcToken
+	pos    int
+	vars   map[string]calcValue
+}
+
+// calcPrecedence of binary operators, higher binds tighter
+var calcPrecedence = map[string]int{
+	"|": 1, "&": 2, "<<": 3, ">>": 3,
+	"+": 4, "-": 4,
+	"*": 5, "/": 5, "%": 5, "mod": 5, "of": 5,
+	"^": 7, "**": 7,
+}
+
+func (p *calcParser) peek() calcToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return calcToken{kind: tokEOF}
+}
+
+func (p *calcParser) next() calcToken {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+// binaryOp returns the operator at the current token, if it is one
+func (p *calcParser) binaryOp() (string, int, bool) {
+	tok := p.peek()
+	op := strings.ToLower(tok.text)
+	if tok.kind == tokIdent && op != "mod" && op != "of" {
+		return "", 0, false
+	}
+	if tok.kind != tokOp && tok.kind != tokIdent {
+		return "", 0, false
+	}
+	prec, ok := calcPrecedence[op]
+	return op, prec, ok
+}
+
+func (p *calcParser) parseExpr(minPrec int) (calcValue, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return left, err
+	}
+
+	for {
+		op, prec, ok := p.binaryOp()
+		if !ok || prec < minPrec {
+			return left, nil
+		}
+		p.next()
+
+		// Power is right-associative
+		nextMin := prec + 1
+		if op == "^" || op == "**" {
+			nextMin = prec
+		}
+		right, err := p.parseExpr(nextMin)
+		if err != nil {
+			return left, err
+		}
+		if left, err = applyOp(op, left, right); err != nil {
+			return left, err
+		}
+	}
+}
+
+func (p *calcParser) parseUnary() (calcValue, error) {
+	if tok := p.peek(); tok.kind == tokOp && (tok.text == "-" || tok.text == "+") {
+		p.next()
+		// Binds looser than power, so -2^2 is -4
+		value, err := p.parseExpr(calcPrecedence["^"])
+		if err != nil || tok.text == "+" {
+			return value, err
+		}
+		if value.kind == calcNumber {
+			value.num = -value.num
+			return value, nil
+		}
+		if value.kind == calcSpan {
+			value.months, value.seconds = -value.months, -value.seconds
+			return value, nil
+		}
+		return value, fmt.Errorf("can't negate a date")
+	}
+	return p.parsePostfix()
+}
+
+func (p *calcParser) parsePostfix() (calcValue, error) {
+	value, err := p.parsePrimary()
+	if err != nil {
+		return value, err
+	}
+
+	// "3 days" is a span
+	if tok := p.peek(); value.kind == calcNumber && tok.kind == tokIdent {
+		if unit, ok := lookupSpanUnit(tok.text); ok {
+			p.next()
+			return scaleSpan(unit, value.num)
+		}
+	}
+
+	// "%" is a percentage unless an operand follows, then it's modulo
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "%" {
+		after := calcToken{kind: tokEOF}
+		if p.pos+1 < len(p.tokens) {
+			after = p.tokens[p.pos+1]
+		}
+		operandFollows := after.kind == tokNumber || after.kind == tokDate || after.text == "(" ||
+			(after.kind == tokIdent && !strings.EqualFold(after.text, "of") && !strings.EqualFold(after.text, "mod"))
+		if !operandFollows && value.kind == calcNumber {
+			p.next()
+			value.percent = true
+		}
+	}
+	return value, nil
+}
+
+func (p *calcParser) parsePrimary() (calcValue, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return numberValue(tok.num), nil
+	case tokDate:
+		return calcValue{kind: calcDate, date: tok.date}, nil
+	case tokIdent:
+		name := strings.ToLower(tok.text)
+		if fn, ok := calcFunctions[name]; ok && p.peek().text == "(" {
+			args, err := p.parseArgs()
+			if err != nil {
+				return calcValue{}, err
+			}
+			n, err := fn(args)
+			if err != nil {
+				return calcValue{}, fmt.Errorf("%s: %w", name, err)
+			}
+			return numberValue(n), nil
+		}
+		if value, ok := p.vars[tok.text]; ok {
+			return value, nil
+		}
+		if value, ok := calcConstant(name); ok {
+			return value, nil
+		}
+		return calcValue{}, fmt.Errorf("unknown name %q", tok.text)
+	case tokOp:
+		if tok.text == "(" {
+			value, err := p.parseExpr(0)
+			if err != nil {
+				return value, err
+			}
+			if closing := p.next(); closing.text != ")" {
+				return value, fmt.Errorf("missing )")
+			}
+			return value, nil
+		}
+		return calcValue{}, fmt.Errorf("unexpected %q", tok.text)
+	}
+	return calcValue{}, fmt.Errorf("unexpected end of expression")
+}
+
+func (p *calcParser) parseArgs() ([]float64, error) {
+	p.next() // (
+	var args []float64
+	if p.peek().text == ")" {
+		p.next()
+		return args, nil
+	}
+	for {
+		value, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		if value.kind != calcNumber {
+			return nil, fmt.Errorf("functions only take numbers")
+		}
+		args = append(args, value.number())
+		switch p.next().text {
+		case ",":
+			continue
+		case ")":
+			return args, nil
+		default:
+			return nil, fmt.Errorf("missing )")
+		}
+	}
+}
+
+func applyOp(op string, a, b calcValue) (calcValue, error) {
+	switch op {
+	case "+":
+		return addValues(a, b, 1)
+	case "-":
+		return addValues(a, b, -1)
+	case "*":
+		switch {
+		case a.kind == calcSpan && b.kind == calcNumber:
+			return scaleSpan(a, b.number())
+		case a.kind == calcNumber && b.kind == calcSpan:
+			return scaleSpan(b, a.number())
+		}
+	case "/":
+		if b.kind == calcNumber && b.number() == 0 {
+			return calcValue{}, fmt.Errorf("division by zero")
+		}
+		switch {
+		case a.kind == calcSpan && b.kind == calcNumber:
+			return calcValue{kind: calcSpan, seconds: (a.seconds + float64(a.months)*30.436875*86400) / b.number()}, nil
+		case a.kind == calcSpan && b.kind == calcSpan:
+			return numberValue(a.spanDays() / b.spanDays()), nil
+		}
+	case "of":
+		// "15% of 240"
+		if a.kind == calcNumber && b.kind == calcNumber {
+			return numberValue(a.number() * b.number()), nil
+		}
+	}
+
+	if a.kind != calcNumber || b.kind != calcNumber {
+		return calcValue{}, fmt.Errorf("can't use %s on a %s and a %s", op, kindName(a), kindName(b))
+	}
+	x, y := a.number(), b.number()
+	switch op {
+	case "*":
+		return numberValue(x * y), nil
+	case "/":
+		return numberValue(x / y), nil
+	case "%", "mod":
+		if y == 0 {
+			return calcValue{}, fmt.Errorf("division by zero")
+		}
+		return numberValue(math.Mod(x, y)), nil
+	case "^", "**":
+		return numberValue(math.Pow(x, y)), nil
+	}
+
+	if !isWholeNumber(x) || !isWholeNumber(y) {
+		return calcValue{}, fmt.Errorf("%s only works on whole numbers", op)
+	}
+	i, j := int64(x), int64(y)
+	switch op {
+	case "&":
+		return numberValue(float64(i & j)), nil
+	case "|":
+		return numberValue(float64(i | j)), nil
+	case "<<", ">>":
+		if j < 0 || j > 63 {
+			return calcValue{}, fmt.Errorf("shift by %d is out of range", j)
+		}
+		if op == "<<" {
+			return numberValue(float64(i << j)), nil
+		}
+		return numberValue(float64(i >> j)), nil
+	}
+	return calcValue{}, fmt.Errorf("unknown operator %s", op)
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestCalculatorEvaluate(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		kind  string
+	}{
+		{"2+3", "5", "number"},
+		{"what is 2^10", "1024", "number"},
+		{"2 ** 10", "1024", "number"},
+		{"0.1 + 0.2", "0.3", "number"},
+		{"(1 + 2) * 3", "9", "number"},
+		{"240 + 15%", "276", "number"},
+		{"sqrt(16)", "4", "number"},
+		{"max(1, 7, 3)", "7", "number"},
+		{"0xff | 0x0f", "255", "number"},
+		{"0xff & 0x0f", "15", "number"},
+		{"255 to hex", "0xff", "number"},
+		{"10 in binary", "0b1010", "number"},
+		{"2026-01-31 + 1 month", "2026-02-28 (Saturday)", "date"},
+		{"2024-01-31 + 1 month", "2024-02-29 (Thursday)", "date"},
+		{"2026-03-31 - 1 month", "2026-02-28 (Saturday)", "date"},
+		{"2026-01-31 + 13 months", "2027-02-28 (Sunday)", "date"},
+		{"2026-01-15 + 1 month", "2026-02-15 (Sunday)", "date"},
+		{"2026-01-01 + 2 weeks", "2026-01-15 (Thursday)", "date"},
+	}
+	for _, tt := range tests {
+		got, err := NewCalculatorService().Evaluate(tt.query)
+		if err != nil {
+			t.Errorf("Evaluate(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if got.Result != tt.want || got.Kind != tt.kind {
+			t.Errorf("Evaluate(%q) = %q (%s), want %q (%s)", tt.query, got.Result, got.Kind, tt.want, tt.kind)
+		}
+	}
+}
+
+func TestCalculatorEvaluateErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"42",
+		"2 ** 1024",
+		"sqrt(-1)",
+		"ln(-1)",
+		"1 / 0",
+		"0 / 0",
+		"2 +",
+		"1.5 months + 2026-01-01",
+		"pi = 3",
+		"2026-01-01 + 2026-01-02",
+	}
+	for _, query := range tests {
+		if got, err := NewCalculatorService().Evaluate(query); err == nil {
+			t.Errorf("Evaluate(%q) = %q, want an error", query, got.Result)
+		}
+	}
+}
+
+func TestCalculatorVariables(t *testing.T) {
+	cs := NewCalculatorService()
+	for _, query := range []string{"rate = 0.19", "100 * rate"} {
+		if _, err := cs.Evaluate(query); err != nil {
+			t.Fatalf("Evaluate(%q) failed: %v", query, err)
+		}
+	}
+	got, err := cs.Evaluate("ans + 1")
+	if err != nil || got.Result != "20" {
+		t.Errorf("Evaluate(ans + 1) = %q, %v, want 20", got.Result, err)
+	}
+}
+
+func TestConvertUnits(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"5 km to miles", "3.10685596119 mi"},
+		{"convert 1 mile in km", "1.609344 km"},
+		{"72°F in c", "22.2222222222 °C"},
+		{"0 c to f", "32 °F"},
+		{"1,000 g to kg", "1 kg"},
+	}
+	for _, tt := range tests {
+		got, err := NewCalculatorService().Evaluate(tt.query)
+		if err != nil {
+			t.Errorf("Evaluate(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if got.Result != tt.want || got.Kind != "unit" {
+			t.Errorf("Evaluate(%q) = %q (%s), want %q (unit)", tt.query, got.Result, got.Kind, tt.want)
+		}
+	}
+
+	for _, query := range []string{"5 km to kg", "5 km to parsecs"} {
+		if got, err := NewCalculatorService().Evaluate(query); err == nil {
+			t.Errorf("Evaluate(%q) = %q, want an error", query, got.Result)
+		}
+	}
+}
+
+func TestCalculatorRouting(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home+"/.config")
+	t.Setenv("XDG_DATA_HOME", home+"/.local/share")
+	sm := NewServiceManager()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"convert 5 km to miles", "calculator"},
+		{"5 kg in lb", "calculator"},
+		{"calc 0xff | 0x0f", "calculator"},
+		{"2026-01-31 + 1 month", "calculator"},
+		// Unit-shaped but wrong, so the calculator explains why
+		{"convert 5 km to kg", "calculator"},
+		{"convert song.flac to mp3", "converter"},
+		{"convert ~/scan.png to pdf", "converter"},
+		// Not a finite number, so not a calculation
+		{"calc 2 ** 1024", "llm"},
+	}
+	for _, tt := range tests {
+		if got := sm.ClassifyIntent(tt.query).ServiceName; got != tt.want {
+			t.Errorf("ClassifyIntent(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
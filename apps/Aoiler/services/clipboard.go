@@ -0,0 +1,480 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	clipboardSearchLimit = 50
+	// clipboardPreviewLimit bounds how many images are decoded per search
+	clipboardPreviewLimit = 20
+	clipboardPasteDelay   = 150 * time.Millisecond
+)
+
+// clipboardPattern matches "clip docker", "clipboard" and "clipboard history
+// ssh", capturing the search words. It only matches at the start so "ocr and
+// copy to clipboard" stays an OCR query.
+var clipboardPattern = regexp.MustCompile(`(?i)^\s*(?:clipboard|clip|cliphist)(?:\s+history)?(?:\s+(.*))?$`)
+
+// clipBinaryPattern reads cliphist's preview of binary entries, e.g.
+// "[[ binary data 52 KiB png 1920x1080 ]]"
+var clipBinaryPattern = regexp.MustCompile(`^\[\[ binary data (.+?) (\w+)(?: (\d+x\d+))? \]\]$`)
+
+// defaultPasteCommand types Ctrl+V into the focused window
+var defaultPasteCommand = []string{"wtype", "-M", "ctrl", "v", "-m", "ctrl"}
+
+// ClipboardConfig sets how entries are pasted
+type ClipboardConfig struct {
+	// PasteCommand sends the paste shortcut to the focused window after an
+	// entry is copied (default wtype -M ctrl v -m ctrl)
+	PasteCommand []string `json:"pasteCommand,omitempty"`
+}
+
+// ClipboardEntry is one item of the clipboard history
+type ClipboardEntry struct {
+	// ID is cliphist's ID; pinned entries keep theirs after cliphist drops them
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Preview string `json:"preview"`
+	// Text is the full text, filled for pinned entries and the best match
+	Text string `json:"text,omitempty"`
+	// Format, Size and Dimensions describe image entries
+	Format     string `json:"format,omitempty"`
+	Size       string `json:"size,omitempty"`
+	Dimensions string `json:"dimensions,omitempty"`
+	// Image is a thumbnail as a data URL the webview can show directly
+	Image string `json:"image,omitempty"`
+	// Path is where an image is stored, for pinned images and the best match
+	Path     string     `json:"path,omitempty"`
+	Pinned   bool       `json:"pinned,omitempty"`
+	PinnedAt *time.Time `json:"pinnedAt,omitempty"`
+}
+
+// ClipboardResult lists history entries matching a search, pinned first
+type ClipboardResult struct {
+	Terms   string           `json:"terms"`
+	Entries []ClipboardEntry `json:"entries"`
+	Total   int              `json:"total"`
+}
+
+// ClipboardService searches the cliphist history and keeps pinned entries in
+// ~/.local/share/aoiler/clipboard, so they survive cliphist's own pruning
+type ClipboardService struct {
+	cfg    ClipboardConfig
+	dir    string
+	mu     sync.Mutex
+	loaded bool
+	pins   []ClipboardEntry
+	// thumbs caches image previews by cliphist ID, entries never change
+	thumbs map[string]string
+}
+
+func NewClipboardService(cfg ClipboardConfig) *ClipboardService {
+	if len(cfg.PasteCommand) == 0 {
+		cfg.PasteCommand = defaultPasteCommand
+	}
+	return &ClipboardService{
+		cfg:    cfg,
+		dir:    filepath.Join(aoilerDataDir(), "clipboard"),
+		thumbs: make(map[string]string),
+	}
+}
+
+// Available reports whether cliphist is installed
+func (cs *ClipboardService) Available() bool {
+	_, err := exec.LookPath("cliphist")
+	return err == nil
+}
+
+func (cs *ClipboardService) pinsPath() string {
+	return filepath.Join(cs.dir, "pins.json")
+}
+
+// Search returns entries whose text contains every term, pinned first and
+// then newest first. The best match is decoded in full so it can be passed
+// to the next step of a pipeline: text as Text, images as a file in Path.
+func (cs *ClipboardService) Search(ctx context.Context, terms string) (ClipboardResult, error) {
+	result := ClipboardResult{Terms: terms, Entries: []ClipboardEntry{}}
+
+	history, err := cs.list(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if err := cs.loadLocked(); err != nil {
+		return result, err
+	}
+
+	words := strings.Fields(strings.ToLower(terms))
+	var matches []ClipboardEntry
+	pinned := make(map[string]bool)
+	for i := len(cs.pins) - 1; i >= 0; i-- {
+		pin := cs.pins[i]
+		pinned[pin.ID] = true
+		if matchesAll(pin.Preview+" "+pin.Text, words) {
+			matches = append(matches, pin)
+		}
+	}
+	for _, entry := range history {
+		if !pinned[entry.ID] && matchesAll(entry.Preview, words) {
+			matches = append(matches, entry)
+		}
+	}
+
+	result.Total = len(matches)
+	if len(matches) > clipboardSearchLimit {
+		matches = matches[:clipboardSearchLimit]
+	}
+
+	previews := 0
+	for i := range matches {
+		if matches[i].Kind == "image" && previews < clipboardPreviewLimit {
+			matches[i].Image = cs.thumbnailLocked(ctx, matches[i])
+			previews++
+		}
+	}
+	if len(matches) > 0 {
+		if err := cs.resolveLocked(ctx, &matches[0]); err != nil {
+			return result, err
+		}
+	}
+
+	result.Entries = append(result.Entries, matches...)
+	return result, nil
+}
+
+// Copy puts an entry back on the clipboard
+func (cs *ClipboardService) Copy(ctx context.Context, id string) error {
+	entry, data, err := cs.content(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	args := []string{}
+	if entry.Kind == "image" {
+		args = append(args, "--type", "image/"+entry.Format)
+	}
+	cmd := exec.CommandContext(ctx, "wl-copy", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("wl-copy failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Paste copies an entry and types the paste shortcut into the focused
+// window. The caller hides its own window first so focus goes back.
+func (cs *ClipboardService) Paste(ctx context.Context, id string) error {
+	if err := cs.Copy(ctx, id); err != nil {
+		return err
+	}
+	return cs.sendPaste(ctx)
+}
+
+// PasteText copies text, like a saved snippet, and pastes it into the
+// focused window
+func (cs *ClipboardService) PasteText(ctx context.Context, text string) error {
+	if err := CopyToClipboard(text); err != nil {
+		return err
+	}
+	return cs.sendPaste(ctx)
+}
+
+// sendPaste types the paste shortcut once focus is back on the previous
+// window
+func (cs *ClipboardService) sendPaste(ctx context.Context) error {
+	if _, err := exec.LookPath(cs.cfg.PasteCommand[0]); err != nil {
+		return fmt.Errorf("%s is not installed, the entry was copied instead", cs.cfg.PasteCommand[0])
+	}
+
+	// Give the compositor a moment to refocus the previous window
+	select {
+	case <-time.After(clipboardPasteDelay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	cmd := exec.CommandContext(ctx, cs.cfg.PasteCommand[0], cs.cfg.PasteCommand[1:]...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %s", cs.cfg.PasteCommand[0], strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Pin keeps a copy of an entry so it stays after cliphist drops it, or
+// forgets that copy again
+func (cs *ClipboardService) Pin(ctx context.Context, id string, pin bool) (bool, error) {
+	if !pin {
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+		if err := cs.loadLocked(); err != nil {
+			return false, err
+		}
+		return cs.unpinLocked(id)
+	}
+
+	entry, data, err := cs.content(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if entry.Pinned {
+		return true, nil
+	}
+
+	now := time.Now()
+	entry.Pinned = true
+	entry.PinnedAt = &now
+	if entry.Kind == "image" {
+		entry.Path = filepath.Join(cs.dir, "pins", entry.ID+"."+entry.Format)
+		if err := os.MkdirAll(filepath.Dir(entry.Path), 0o700); err != nil {
+			return false, fmt.Errorf("failed to create clipboard directory: %w", err)
+		}
+		if err := os.WriteFile(entry.Path, data, 0o600); err != nil {
+			return false, fmt.Errorf("failed to save pinned image: %w", err)
+		}
+	} else {
+		entry.Text = string(data)
+	}
+	cs.pins = append(cs.pins, entry)
+	return true, cs.saveLocked()
+}
+
+// Delete removes an entry from the cliphist history and unpins it
+func (cs *ClipboardService) Delete(ctx context.Context, id string) (bool, error) {
+	history, err := cs.list(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	deleted := false
+	for _, entry := range history {
+		if entry.ID != id {
+			continue
+		}
+		cmd := exec.CommandContext(ctx, "cliphist", "delete")
+		cmd.Stdin = strings.NewReader(entry.ID + "\t" + entry.Preview + "\n")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return false, fmt.Errorf("cliphist delete failed: %s", strings.TrimSpace(string(output)))
+		}
+		deleted = true
+		break
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if err := cs.loadLocked(); err != nil {
+		return deleted, err
+	}
+	unpinned, err := cs.unpinLocked(id)
+	return deleted || unpinned, err
+}
+
+// list reads the cliphist history, newest first
+func (cs *ClipboardService) list(ctx context.Context) ([]ClipboardEntry, error) {
+	if !cs.Available() {
+		return nil, fmt.Errorf("cliphist is not installed")
+	}
+	output, err := exec.CommandContext(ctx, "cliphist", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("cliphist list failed: %w", err)
+	}
+
+	var entries []ClipboardEntry
+	for _, line := range strings.Split(string(output), "\n") {
+		id, preview, ok := strings.Cut(line, "\t")
+		if !ok || id == "" {
+			continue
+		}
+		entries = append(entries, parseClipEntry(id, preview))
+	}
+	return entries, nil
+}
+
+func parseClipEntry(id, preview string) ClipboardEntry {
+	entry := ClipboardEntry{ID: id, Kind: "text", Preview: preview}
+	if m := clipBinaryPattern.FindStringSubmatch(preview); m != nil {
+		entry.Kind = "image"
+		entry.Size, entry.Format, entry.Dimensions = m[1], m[2], m[3]
+	}
+	return entry
+}
+
+// content returns an entry and its raw bytes, from the pin if it has one
+func (cs *ClipboardService) content(ctx context.Context, id string) (ClipboardEntry, []byte, error) {
+	cs.mu.Lock()
+	if err := cs.loadLocked(); err != nil {
+		cs.mu.Unlock()
+		return ClipboardEntry{}, nil, err
+	}
+	for _, pin := range cs.pins {
+		if pin.ID == id {
+			cs.mu.Unlock()
+			data, err := pinData(pin)
+			return pin, data, err
+		}
+	}
+	cs.mu.Unlock()
+
+	history, err := cs.list(ctx)
+	if err != nil {
+		return ClipboardEntry{}, nil, err
+	}
+	for _, entry := range history {
+		if entry.ID == id {
+			data, err := cs.decode(ctx, entry)
+			return entry, data, err
+		}
+	}
+	return ClipboardEntry{}, nil, fmt.Errorf("clipboard entry %s is no longer in the history", id)
+}
+
+func pinData(pin ClipboardEntry) ([]byte, error) {
+	if pin.Kind != "image" {
+		return []byte(pin.Text), nil
+	}
+	data, err := os.ReadFile(pin.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pinned image: %w", err)
+	}
+	return data, nil
+}
+
+// decode fetches an entry's full content from cliphist
+func (cs *ClipboardService) decode(ctx context.Context, entry ClipboardEntry) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "cliphist", "decode")
+	cmd.Stdin = strings.NewReader(entry.ID + "\t" + entry.Preview + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cliphist decode failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return data, nil
+}
+
+// resolveLocked fills in the full content of an entry. Images are written
+// to a file since other services take paths.
+func (cs *ClipboardService) resolveLocked(ctx context.Context, entry *ClipboardEntry) error {
+	if entry.Pinned {
+		return nil
+	}
+	data, err := cs.decode(ctx, *entry)
+	if err != nil {
+		return err
+	}
+	if entry.Kind != "image" {
+		entry.Text = string(data)
+		return nil
+	}
+
+	// One file is reused so searching doesn't pile up copies
+	entry.Path = filepath.Join(cs.dir, "clip."+entry.Format)
+	if err := os.MkdirAll(cs.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create clipboard directory: %w", err)
+	}
+	if err := os.WriteFile(entry.Path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save clipboard image: %w", err)
+	}
+	return nil
+}
+
+// thumbnailLocked returns a cached JPEG preview of an image entry, or ""
+// when it can't be decoded (webp and other formats Go doesn't read)
+func (cs *ClipboardService) thumbnailLocked(ctx context.Context, entry ClipboardEntry) string {
+	if thumb, ok := cs.thumbs[entry.ID]; ok {
+		return thumb
+	}
+
+	var data []byte
+	var err error
+	if entry.Pinned {
+		data, err = pinData(entry)
+	} else {
+		data, err = cs.decode(ctx, entry)
+	}
+	if err != nil {
+		return ""
+	}
+
+	thumb := ""
+	if src, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scaleDown(src, ocrThumbnailSize), &jpeg.Options{Quality: 75}); err == nil {
+			thumb = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+	}
+	cs.thumbs[entry.ID] = thumb
+	return thumb
+}
+
+func (cs *ClipboardService) unpinLocked(id string) (bool, error) {
+	for i, pin := range cs.pins {
+		if pin.ID == id {
+			if pin.Kind == "image" {
+				os.Remove(pin.Path)
+			}
+			cs.pins = append(cs.pins[:i], cs.pins[i+1:]...)
+			return true, cs.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+func (cs *ClipboardService) loadLocked() error {
+	if cs.loaded {
+		return nil
+	}
+	data, err := os.ReadFile(cs.pinsPath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read clipboard pins: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cs.pins); err != nil {
+			return fmt.Errorf("invalid clipboard pins %s: %w", cs.pinsPath(), err)
+		}
+	}
+	cs.loaded = true
+	return nil
+}
+
+func (cs *ClipboardService) saveLocked() error {
+	if err := os.MkdirAll(cs.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create clipboard directory: %w", err)
+	}
+	data, err := json.MarshalIndent(cs.pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(cs.pinsPath(), data); err != nil {
+		return fmt.Errorf("failed to save clipboard pins: %w", err)
+	}
+	return nil
+}
+
+// matchesAll reports whether text contains every lowercase word
+func matchesAll(text string, words []string) bool {
+	text = strings.ToLower(text)
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package services
+
+import "strings"
+
+// commonWords are everyday English words. DetectTypos never "corrects" one of
+// them into a command, since "task list" or "fine tune" mean what they say
+// even though "ask" and "find" are one edit away.
+var commonWords = wordSet(`
+about above accept according account across act action add address after again against age ago
+agree ahead air all allow almost alone along already also always among and animal another answer
+any anyone anything appear apply area arm army around arrive art article artist asked asking
+asks assume attack author available avoid away baby back bad bag ball bank bar bark base bask
+bath bays bean beat beautiful became because become bed been before began begin behind being
+believe best better between big bill bind bit black blood blue board boat body book born both
+box boy brain bread break bring brother brown build building built bun business but buy calf
+call called calm came camera can cancel cannot car card care career carry case cask cat catch
+cause cell center central certain chain chair chance charge cheap cheek chick child chip choice
+choose chuck church city claim clap class clear cleat clips close coach coat codes cold collect
+college color come command common company compare concert convent cook cool copy corner cost
+could count country couple course court cover covert cream create crime cup current cut dad
+daily damage dance dare dart data date daughter day dead deal dear death debate decade decide
+decode deep define degree deny describe design detail determine develop did die diet differ
+dinner direct dirt discover dish doctor does dog doing done door down draw dream dreams dress
+drink drive drop drug during each early earth east easy eat edge effect eight either elm else
+employee encore end energy enjoy enough enter entire even evening event ever every exactly
+example except expect expert explain extra eye face fact fail fall family far farm fast father
+fax fear feel feet few field fig fight figure file fill film final finally financial finds fine
+fined finger finish fins fir fire firm first fish fist fit five flask flat flip floor fly focus
+fog follow food foot force foreign forget fork form formal fort forth forward four fox free
+friend from front full fun fund fur future game garden gas gave general get gift girl give given
+glass goal god going gold gone good got government great green ground group grow growth guess
+gun guy hair half hall hand hang happen happy hard has hat hate haunch have head health heap
+hear heard heart heat heavy held hell hello hem hemp hen her here herself hey high hill him
+himself hind hint his hit hold hole home hook hope horse hospital hot hotel hour house how
+however huge human hundred hurt husband idea image impact inch include increase indeed inside
+instead into issue item its itself job join joke just keep kelp key kid kill kind king kitchen
+knee knew know land language large lark last late later laugh launches law lawyer lay lead lean
+learn least leave led left leg less let letter level lie life lift lights like likely line lines
+link lip list listen little live load local locale lock long loop lose loss lost lot loud love
+low lunch machine made mail main maintain major make male man manage many map mark market marry
+mask mass match material matter may maybe meal mean measure medic meet member memory men message
+meta method middle might mile milk mince mind mine mink mint minute miss mist mix model modem
+modes mole moment money month mood more morning most mother mouth move movie much music must
+myself name nation native nature near nearly need never new news next nice night nine node nodes
+none nook nor north not note nothing notice now number nun nurse oar off offer office often oil
+old omen once one only onto opera orc order other our out oven over own owner page pain paint
+pair paper parent park part party pass past path paunch pay pays peace people per perform
+perhaps period person pick picture piece pink place plan plant play player please point police
+policy pool poor popular port position post pound power pretty price print probes prone proud
+prove pull push put question quick quite race radio raise ran range rapture rate rather rays
+reach ready real reason receive recent record records red reed region remain remember remove
+report resin rest result return rich ride right rind ring rise risk river road robe rock rode
+role roll room root rose rub rule rum rut safe said sale same save saw say says scene school
+score scream sea season seat second section see seek seem seen sell send sense serve set seven
+several sex shake share she shock shoe shop short shot should show shrank shrine side sight sign
+simple sing singe single sink sister sit site six size skill skin slip small smart smile snort
+snow social soft some son song soon sore sound source south space speak special spend sport
+spring staff stage stand star starch stare stark stars state stay step still stock stone stop
+store story stream street strong student study stuff style subject such suffer suggest summer
+sun sure system table take talk tar task tasks tax taxi teach team tell ten tent term test than
+thank that the their them theme then theory there these they thing think third this those though
+three through throw thus thyme tight time tint tire today together told tone too took top total
+touch toward town toy track trade train travel tree trial trip trouble true trust truth try turn
+two type under unit unto upon urn use used user usual value very vex view visit voice vote wait
+walk wall want war warm was wash watch water way ways weapon wear week weight well went were
+west what when whether which while white who whole whose why wide wife will win wince wind
+window wine wink wise wish with within without woman wonder word work worker world worry would
+write wrong yard yeah year yelp yes yet you young your
+`)
+
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config holds user overrides read from ~/.config/hecate/aoiler.json.
+// Every section is optional and falls back to built-in defaults.
+type Config struct {
+	Linters []LanguageSpec `json:"linters,omitempty"`
+	// LintWorkers bounds parallel tool runs for directory lint/format runs
+	LintWorkers int `json:"lintWorkers,omitempty"`
+	// OCR sets the default tesseract language and page segmentation mode
+	OCR OCROptions `json:"ocr,omitempty"`
+	// OCRHistory sets retention limits for stored captures
+	OCRHistory OCRHistoryOptions `json:"ocrHistory,omitempty"`
+	// Converter adds conversion presets such as "for discord"
+	Converter ConverterConfig `json:"converter,omitempty"`
+	// Document picks the pandoc PDF engine and PDF rendering DPI
+	Document DocumentConfig `json:"document,omitempty"`
+	// QueryHistory sets retention limits for past queries
+	QueryHistory QueryHistoryOptions `json:"queryHistory,omitempty"`
+	// Clipboard sets the command that pastes an entry into the focused window
+	Clipboard ClipboardConfig `json:"clipboard,omitempty"`
+	// Launcher sets the terminal for terminal apps and the quick apps file
+	Launcher LauncherConfig `json:"launcher,omitempty"`
+	// Screenshot sets where screenshots and recordings go and how they're named
+	Screenshot ScreenshotConfig `json:"screenshot,omitempty"`
+	// Notes sets the directory notes and snippets are kept in
+	Notes NotesConfig `json:"notes,omitempty"`
+	// Jobs sets per-service timeouts for background queries
+	Jobs JobsConfig `json:"jobs,omitempty"`
+}
+
+// ConfigPath returns the location of the Aoiler config file
+func ConfigPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "hecate", "aoiler.json")
+}
+
+// LoadConfig reads the config file, returning an empty config if it doesn't exist
+func LoadConfig() (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(ConfigPath())
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %w", ConfigPath(), err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errOutputExists is returned for single conversions with "skip existing"
+var errOutputExists = errors.New("output already exists")
+
+// sourceFormatPattern narrows a folder to one input format, e.g. "all flac"
+var sourceFormatPattern = regexp.MustCompile(`\b(?:all|every)\s+(?:the\s+)?\.?([a-z0-9]{2,4}?)s?\b`)
+
+// ConversionBatchFile is the outcome for one file in a batch
+type ConversionBatchFile struct {
+	Input    string `json:"input"`
+	Output   string `json:"output,omitempty"`
+	FileSize int64  `json:"fileSize,omitempty"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ConversionBatchSummary aggregates a batch run
+type ConversionBatchSummary struct {
+	RunID          string `json:"runId"`
+	Root           string `json:"root"`
+	Format         string `json:"format"`
+	OutputDir      string `json:"outputDir,omitempty"`
+	FilesProcessed int    `json:"filesProcessed"`
+	FilesConverted int    `json:"filesConverted"`
+	FilesFailed    int    `json:"filesFailed"`
+	FilesSkipped   int    `json:"filesSkipped"`
+	BytesIn        int64  `json:"bytesIn"`
+	BytesOut       int64  `json:"bytesOut"`
+	Cancelled      bool   `json:"cancelled,omitempty"`
+	DurationMs     int64  `json:"durationMs"`
+}
+
+// ConversionBatchResult is returned once a batch finishes. Per-file results
+// are also streamed as "converter:file" events while it runs.
+type ConversionBatchResult struct {
+	Summary ConversionBatchSummary `json:"summary"`
+	Preset  string                 `json:"preset,omitempty"`
+	Options ConversionOptions      `json:"options"`
+	Files   []ConversionBatchFile  `json:"files"`
+}
+
+// ConversionBatchEvent is emitted for every file as soon as it is done
+type ConversionBatchEvent struct {
+	RunID     string              `json:"runId"`
+	Processed int                 `json:"processed"`
+	Total     int                 `json:"total"`
+	File      ConversionBatchFile `json:"file"`
+}
+
+// isBatchQuery reports whether a converter query names a glob or a folder
+// rather than a single file
+func isBatchQuery(query string) bool {
+	_, rest := parseOutputDir(query)
+	input := expandHome(extractMediaPath(rest))
+	return strings.ContainsAny(input, "*?[") || isDirectory(input)
+}
+
+// ConvertBatch converts every media file matched by a glob or found in a
+// folder, e.g. "convert ~/Music/*.flac to mp3 into ~/mp3". Files run in
+// parallel, each as its own cancellable job, and cancelling the run ID stops
+// the whole batch.
+func (cs *ConverterService) ConvertBatch(parent context.Context, query string) (ConversionBatchResult, error) {
+	start := time.Now()
+
+	opts, preset := parseConversionOptions(query, cs.presets)
+	_, rest := parseOutputDir(query)
+	pattern := expandHome(extractMediaPath(rest))
+	if opts.Format == "" {
+		return ConversionBatchResult{}, fmt.Errorf("no target format specified")
+	}
+
+	sourceFormat := ""
+	if m := sourceFormatPattern.FindStringSubmatch(strings.ToLower(stripPaths(rest))); m != nil && isMediaFormat(m[1]) {
+		sourceFormat = m[1]
+	}
+	root, files, err := expandMediaInputs(pattern, sourceFormat, opts)
+	if err != nil {
+		return ConversionBatchResult{}, err
+	}
+
+	summary := ConversionBatchSummary{RunID: newID(), Root: root, Format: opts.Format, OutputDir: opts.OutputDir}
+	ctx, cancel := context.WithCancel(parent)
+	cs.running.mu.Lock()
+	if cs.running.batches == nil {
+		cs.running.batches = make(map[string]context.CancelFunc)
+	}
+	cs.running.batches[summary.RunID] = cancel
+	cs.running.mu.Unlock()
+	defer func() {
+		cancel()
+		cs.running.mu.Lock()
+		delete(cs.running.batches, summary.RunID)
+		cs.running.mu.Unlock()
+	}()
+
+	cs.emitter.emit("converter:batchstart", map[string]interface{}{
+		"runId": summary.RunID,
+		"root":  root,
+		"total": len(files),
+	})
+
+	// Outputs are claimed before any file starts so two inputs with the same
+	// name, like a.flac and a.wav, never write to the same a.mp3
+	outputs := make(map[string]string, len(files))
+	existing := make(map[string]bool)
+	taken := make(map[string]bool)
+	for _, path := range files {
+		rel, _ := filepath.Rel(root, path)
+		output, exists := outputPathFor(path, rel, opts, taken)
+		outputs[path] = output
+		existing[path] = exists
+		taken[output] = true
+	}
+
+	workers := cs.workers
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	// The batch reports progress per file, not per ffmpeg run
+	fileCtx := withProgress(ctx, func(float64, string) {})
+
+	jobs := make(chan string)
+	results := make(chan ConversionBatchFile)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				if existing[path] {
+					results <- ConversionBatchFile{Input: path, Output: outputs[path], Skipped: true}
+					continue
+				}
+				results <- cs.convertBatchFile(fileCtx, path, outputs[path], opts, preset)
+			}
+		}()
+	}
+
+	go func() {
+		for _, path := range files {
+			jobs <- path
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	collected := make([]ConversionBatchFile, 0, len(files))
+	for file := range results {
+		collected = append(collected, file)
+		summary.FilesProcessed++
+
+		switch {
+		case file.Skipped:
+			summary.FilesSkipped++
+		case file.Error != "":
+			summary.FilesFailed++
+		default:
+			summary.FilesConverted++
+			summary.BytesOut += file.FileSize
+			if info, err := os.Stat(file.Input); err == nil {
+				summary.BytesIn += info.Size()
+			}
+		}
+
+		cs.emitter.emit("converter:file", ConversionBatchEvent{
+			RunID:     summary.RunID,
+			Processed: summary.FilesProcessed,
+			Total:     len(files),
+			File:      file,
+		})
+		reportProgress(ctx, float64(summary.FilesProcessed)/float64(len(files)),
+			fmt.Sprintf("%d of %d files", summary.FilesProcessed, len(files)))
+	}
+
+	sort.Slice(collected, func(i, j int) bool { return collected[i].Input < collected[j].Input })
+
+	summary.Cancelled = ctx.Err() != nil && summary.FilesConverted+summary.FilesSkipped < len(files)
+	summary.DurationMs = time.Since(start).Milliseconds()
+	cs.emitter.emit("converter:batchdone", summary)
+
+	return ConversionBatchResult{Summary: summary, Preset: preset, Options: opts, Files: collected}, nil
+}
+
+func (cs *ConverterService) convertBatchFile(ctx context.Context, input, output string, opts ConversionOptions, preset string) ConversionBatchFile {
+	file := ConversionBatchFile{Input: input, Output: output}
+	// Files still queued when the batch is cancelled are not started
+	if ctx.Err() != nil {
+		file.Output = ""
+		file.Error = errConversionCancelled.Error()
+		return file
+	}
+
+	result, err := cs.convertFile(ctx, input, output, opts, preset)
+	if err != nil {
+		file.Output = ""
+		file.Error = err.Error()
+		return file
+	}
+	file.FileSize = result.FileSize
+	return file
+}
+
+// expandMediaInputs turns a glob or a folder into the media files to convert,
+// along with the root their relative output paths are kept under. Files
+// already in the target format are left alone unless the options re-encode.
+func expandMediaInputs(pattern, sourceFormat string, opts ConversionOptions) (string, []string, error) {
+	var root string
+	var candidates []string
+
+	if isDirectory(pattern) {
+		root = pattern
+		all, err := listProjectFiles(root)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to list files in %s: %w", root, err)
+		}
+		candidates = all
+	} else {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		root = globRoot(pattern)
+		candidates = matches
+	}
+
+	plain := ConversionOptions{Format: opts.Format, AudioCodec: opts.AudioCodec, OutputDir: opts.OutputDir, OnConflict: opts.OnConflict}
+	reencodes := opts != plain
+
+	var files []string
+	for _, path := range candidates {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+		if !isMediaFormat(ext) || (sourceFormat != "" && ext != sourceFormat) {
+			continue
+		}
+		if ext == opts.Format && !reencodes {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	if len(files) == 0 {
+		return root, nil, fmt.Errorf("no media files to convert in %s", pattern)
+	}
+	return root, files, nil
+}
+
+// globRoot is the directory part of a pattern before the first wildcard
+func globRoot(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// outputPathFor picks where a conversion writes. rel is the input's path
+// relative to the batch root, which is recreated under OutputDir. taken holds
+// outputs already claimed in the same batch. With OnConflict "skip" an
+// existing output is returned with exists set; otherwise existing files are
+// overwritten or a free "_converted" name is found. The input itself is never
+// overwritten.
+func outputPathFor(inputPath, rel string, opts ConversionOptions, taken map[string]bool) (string, bool) {
+	dir := filepath.Dir(inputPath)
+	if opts.OutputDir != "" {
+		dir = filepath.Join(opts.OutputDir, filepath.Dir(rel))
+	}
+	base := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	candidate := filepath.Join(dir, base+"."+opts.Format)
+
+	used := func(path string) bool {
+		if taken[path] {
+			return true
+		}
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	if candidate != inputPath && !taken[candidate] {
+		switch {
+		case opts.OnConflict == "overwrite":
+			return candidate, false
+		case opts.OnConflict == "skip" && used(candidate):
+			return candidate, true
+		case !used(candidate):
+			return candidate, false
+		}
+	}
+
+	output := filepath.Join(dir, base+"_converted."+opts.Format)
+	for n := 2; used(output); n++ {
+		output = filepath.Join(dir, base+"_converted_"+strconv.Itoa(n)+"."+opts.Format)
+	}
+	return output, false
+}
+
+// cancelBatch stops a batch by run ID, which also cancels its running jobs
+func (cs *ConverterService) cancelBatch(runID string) bool {
+	cs.running.mu.Lock()
+	cancel, ok := cs.running.batches[runID]
+	cs.running.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
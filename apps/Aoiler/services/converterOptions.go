@@ -0,0 +1,597 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ConversionOptions describe what ffmpeg should do beyond changing the
+// container. Zero values leave the choice to ffmpeg.
+type ConversionOptions struct {
+	Format       string `json:"format,omitempty"`
+	VideoCodec   string `json:"videoCodec,omitempty"`
+	AudioCodec   string `json:"audioCodec,omitempty"`
+	VideoBitrate string `json:"videoBitrate,omitempty"`
+	AudioBitrate string `json:"audioBitrate,omitempty"`
+	CRF          int    `json:"crf,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	FPS          int    `json:"fps,omitempty"`
+	// Percent scales both sides, Quality is the JPEG/WebP quality (1-100)
+	Percent int `json:"percent,omitempty"`
+	Quality int `json:"quality,omitempty"`
+	// Start and Duration trim the input in seconds, FromEnd keeps only the
+	// last FromEnd seconds
+	Start    float64 `json:"start,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	FromEnd  float64 `json:"fromEnd,omitempty"`
+	// TargetSizeMB switches video to a two-pass encode sized to fit
+	TargetSizeMB float64 `json:"targetSizeMB,omitempty"`
+	AudioOnly    bool    `json:"audioOnly,omitempty"`
+	NoAudio      bool    `json:"noAudio,omitempty"`
+	// OutputDir puts results somewhere other than next to the input.
+	// OnConflict is "rename" (default), "overwrite" or "skip" when the
+	// output file already exists.
+	OutputDir  string `json:"outputDir,omitempty"`
+	OnConflict string `json:"onConflict,omitempty"`
+}
+
+// ConversionPreset is a named set of options, e.g. "compress for discord"
+type ConversionPreset struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Options     ConversionOptions `json:"options"`
+}
+
+// ConverterConfig is the "converter" section of the config file
+type ConverterConfig struct {
+	// Presets add to or replace the built-in presets by name
+	Presets []ConversionPreset `json:"presets,omitempty"`
+	// Workers is how many files a batch converts at once (default 2)
+	Workers int `json:"workers,omitempty"`
+}
+
+func defaultPresets() []ConversionPreset {
+	return []ConversionPreset{
+		{
+			Name:        "discord",
+			Description: "MP4 that fits Discord's 10 MB upload limit",
+			Options:     ConversionOptions{Format: "mp4", VideoCodec: "libx264", AudioCodec: "aac", TargetSizeMB: 10},
+		},
+		{
+			Name:        "web",
+			Description: "H.264 MP4 that streams in any browser",
+			Options:     ConversionOptions{Format: "mp4", VideoCodec: "libx264", AudioCodec: "aac", CRF: 23},
+		},
+		{
+			Name:        "gif",
+			Description: "480px wide GIF at 15 fps",
+			Options:     ConversionOptions{Format: "gif", Width: 480, FPS: 15},
+		},
+		{
+			Name:        "podcast",
+			Description: "Speech friendly 96k MP3",
+			Options:     ConversionOptions{Format: "mp3", AudioCodec: "libmp3lame", AudioBitrate: "96k", AudioOnly: true},
+		},
+	}
+}
+
+// mergePresets lets user presets replace built-ins with the same name
+func mergePresets(overrides []ConversionPreset) []ConversionPreset {
+	presets := defaultPresets()
+	for _, override := range overrides {
+		name := strings.ToLower(override.Name)
+		replaced := false
+		for i := range presets {
+			if presets[i].Name == name {
+				override.Name = name
+				presets[i] = override
+				replaced = true
+				break
+			}
+		}
+		if !replaced && name != "" {
+			override.Name = name
+			presets = append(presets, override)
+		}
+	}
+	return presets
+}
+
+// mediaFormats are the output formats the converter understands
+var mediaFormats = []string{
+	"mp4", "webm", "avi", "mkv", "mov",
+	"mp3", "wav", "flac", "ogg", "opus", "m4a", "aac",
+	"png", "jpg", "jpeg", "gif", "webp", "bmp", "tif", "tiff",
+}
+
+// audioFormats drop the video stream
+var audioFormats = map[string]bool{
+	"mp3": true, "wav": true, "flac": true, "ogg": true, "opus": true, "m4a": true, "aac": true,
+}
+
+var videoCodecs = map[string]string{
+	"h264": "libx264", "x264": "libx264", "avc": "libx264",
+	"h265": "libx265", "x265": "libx265", "hevc": "libx265",
+	"vp9": "libvpx-vp9", "av1": "libsvtav1",
+}
+
+var audioCodecs = map[string]string{
+	"opus": "libopus", "mp3": "libmp3lame", "aac": "aac",
+	"vorbis": "libvorbis", "flac": "flac",
+}
+
+var (
+	resolutionPattern = regexp.MustCompile(`\b(\d{3,4})p\b`)
+	uhdPattern        = regexp.MustCompile(`\b4k\b`)
+	dimensionPattern  = regexp.MustCompile(`\b(\d{2,5})x(\d{2,5})\b`)
+	widthPattern      = regexp.MustCompile(`\b(\d{2,5})\s*(?:px\s+)?wide\b|\bwidth\s+(?:of\s+)?(\d{2,5})\b`)
+	heightPattern     = regexp.MustCompile(`\b(\d{2,5})\s*(?:px\s+)?(?:tall|high)\b|\bheight\s+(?:of\s+)?(\d{2,5})\b`)
+	percentPattern    = regexp.MustCompile(`\b(\d{1,3})\s*%`)
+	qualityPattern    = regexp.MustCompile(`\bq(?:uality)?\s*(\d{1,3})\b`)
+	fpsPattern        = regexp.MustCompile(`\b(\d{1,3})\s*fps\b`)
+	crfPattern        = regexp.MustCompile(`\bcrf\s*(\d{1,2})\b`)
+	sizePattern       = regexp.MustCompile(`\b(?:under|below|max|at most|less than|fit(?:s)? in(?:to)?)\s+(\d+(?:\.\d+)?)\s*(kb|mb|gb)\b`)
+	bitratePattern    = regexp.MustCompile(`\b(?:(audio|video)\s+(?:at\s+)?)?(\d+(?:\.\d+)?)\s*(k|m)(?:bps|b/s)?\b`)
+
+	timeValue       = `(\d+(?::\d{1,2}){0,2}(?:\.\d+)?)`
+	timeUnit        = `\s*(seconds?|secs?|s|minutes?|mins?|m)?\b`
+	firstPattern    = regexp.MustCompile(`\bfirst\s+` + timeValue + timeUnit)
+	lastPattern     = regexp.MustCompile(`\blast\s+` + timeValue + timeUnit)
+	rangePattern    = regexp.MustCompile(`\bfrom\s+` + timeValue + `\s*(?:s|sec|seconds)?\s+to\s+` + timeValue + `\b`)
+	startPattern    = regexp.MustCompile(`\b(?:start(?:ing)? at|skip(?: the first)?)\s+` + timeValue + timeUnit)
+	durationPattern = regexp.MustCompile(`\bfor\s+` + timeValue + timeUnit)
+
+	// fileNamePattern is a bare file name like "commute.mp4", whose words
+	// aren't options
+	fileNamePattern = regexp.MustCompile(`^[^.\s]\S*\.[a-z][a-z0-9]{1,4}$`)
+	noAudioPattern  = regexp.MustCompile(`\b(?:muted?|no audio|remove audio|without audio)\b`)
+
+	outputDirPattern = regexp.MustCompile(`(?i)\b(?:into|output(?:\s+to)?|out(?:put)?\s*dir(?:ectory)?|save\s+(?:it\s+|them\s+)?(?:to|in))\s+(["']?(?:~|\.\.?)?/\S*|~)`)
+)
+
+// parseConversionOptions reads options like "480p", "first 10 seconds",
+// "opus 96k" or "under 25MB" from a query and layers them over any preset
+// named in it
+func parseConversionOptions(query string, presets []ConversionPreset) (ConversionOptions, string) {
+	var opts ConversionOptions
+	opts.OutputDir, query = parseOutputDir(query)
+	lowerQuery := strings.ToLower(stripFileNames(stripPaths(query)))
+
+	// Size and time phrases are blanked out as they're read so "2m" in
+	// "first 2m" isn't taken for a bitrate
+	blank := func(re *regexp.Regexp, fn func(m []string)) {
+		if m := re.FindStringSubmatch(lowerQuery); m != nil {
+			fn(m)
+			lowerQuery = strings.Replace(lowerQuery, m[0], strings.Repeat(" ", len(m[0])), 1)
+		}
+	}
+
+	blank(sizePattern, func(m []string) {
+		size, _ := strconv.ParseFloat(m[1], 64)
+		switch m[2] {
+		case "kb":
+			size /= 1024
+		case "gb":
+			size *= 1024
+		}
+		opts.TargetSizeMB = size
+	})
+	blank(rangePattern, func(m []string) {
+		start, end := parseTimestamp(m[1], ""), parseTimestamp(m[2], "")
+		if end > start {
+			opts.Start, opts.Duration = start, end-start
+		}
+	})
+	blank(firstPattern, func(m []string) { opts.Duration = parseTimestamp(m[1], m[2]) })
+	blank(lastPattern, func(m []string) { opts.FromEnd = parseTimestamp(m[1], m[2]) })
+	blank(startPattern, func(m []string) { opts.Start = parseTimestamp(m[1], m[2]) })
+	blank(durationPattern, func(m []string) {
+		if opts.Duration == 0 {
+			opts.Duration = parseTimestamp(m[1], m[2])
+		}
+	})
+	blank(resolutionPattern, func(m []string) { opts.Height, _ = strconv.Atoi(m[1]) })
+	blank(dimensionPattern, func(m []string) {
+		opts.Width, _ = strconv.Atoi(m[1])
+		opts.Height, _ = strconv.Atoi(m[2])
+	})
+	blank(widthPattern, func(m []string) { opts.Width, _ = strconv.Atoi(m[1] + m[2]) })
+	blank(heightPattern, func(m []string) { opts.Height, _ = strconv.Atoi(m[1] + m[2]) })
+	blank(percentPattern, func(m []string) { opts.Percent, _ = strconv.Atoi(m[1]) })
+	blank(qualityPattern, func(m []string) { opts.Quality, _ = strconv.Atoi(m[1]) })
+	if strings.Contains(lowerQuery, "half size") || strings.Contains(lowerQuery, "half the size") {
+		opts.Percent = 50
+	}
+	blank(fpsPattern, func(m []string) { opts.FPS, _ = strconv.Atoi(m[1]) })
+	blank(crfPattern, func(m []string) { opts.CRF, _ = strconv.Atoi(m[1]) })
+	blank(uhdPattern, func(m []string) { opts.Height = 2160 })
+
+	opts.Format = parseTargetFormat(lowerQuery, isMediaFormat)
+
+	if strings.Contains(lowerQuery, "extract audio") || strings.Contains(lowerQuery, "audio only") {
+		opts.AudioOnly = true
+	}
+	if noAudioPattern.MatchString(lowerQuery) {
+		opts.NoAudio = true
+	}
+
+	opts.OnConflict = parseConflict(lowerQuery)
+
+	for _, word := range strings.Fields(lowerQuery) {
+		if codec, ok := videoCodecs[word]; ok {
+			opts.VideoCodec = codec
+		}
+		if codec, ok := audioCodecs[word]; ok {
+			opts.AudioCodec = codec
+		}
+	}
+
+	for _, m := range bitratePattern.FindAllStringSubmatch(lowerQuery, -1) {
+		value, _ := strconv.ParseFloat(m[2], 64)
+		if m[3] == "m" {
+			value *= 1000
+		}
+		bitrate := strconv.Itoa(int(value)) + "k"
+
+		isAudio := m[1] == "audio" ||
+			(m[1] == "" && value <= 320 && (opts.AudioOnly || audioFormats[opts.Format] || opts.AudioCodec != ""))
+		if isAudio {
+			opts.AudioBitrate = bitrate
+		} else {
+			opts.VideoBitrate = bitrate
+		}
+	}
+
+	presetName := ""
+	for _, preset := range presets {
+		if regexp.MustCompile(`\b` + regexp.QuoteMeta(preset.Name) + `\b`).MatchString(lowerQuery) {
+			// A bare "to gif" is just a format, not the gif preset
+			if preset.Name == opts.Format && !strings.Contains(lowerQuery, "for "+preset.Name) {
+				continue
+			}
+			presetName = preset.Name
+			opts = mergeOptions(preset.Options, opts)
+			break
+		}
+	}
+
+	// Formats that are also codec names ("as opus") pick the codec too
+	if opts.AudioCodec == "" && audioFormats[opts.Format] {
+		if codec, ok := audioCodecs[opts.Format]; ok && opts.Format != "aac" {
+			opts.AudioCodec = codec
+		}
+	}
+	return opts, presetName
+}
+
+// stripFileNames drops bare file names, as stripPaths does paths, so an
+// option word inside one ("commute.mp4", "clip.480p.mkv") isn't read
+func stripFileNames(query string) string {
+	var kept []string
+	for _, word := range strings.Fields(query) {
+		if fileNamePattern.MatchString(strings.ToLower(strings.Trim(word, "\"',"))) {
+			continue
+		}
+		kept = append(kept, word)
+	}
+	return strings.Join(kept, " ")
+}
+
+// parseTargetFormat prefers "to webm"/"as opus" over any other format word, so
+// the input's own extension is never mistaken for the target. known decides
+// which words are formats.
+func parseTargetFormat(lowerQuery string, known func(string) bool) string {
+	// Walked word by word rather than with one regex so "flac in ~/Music to
+	// mp3", where the path has been stripped, still finds "to mp3"
+	words := strings.Fields(lowerQuery)
+	for i, word := range words {
+		if word != "to" && word != "as" && word != "into" && word != "in" {
+			continue
+		}
+		next := i + 1
+		if next < len(words) && (words[next] == "a" || words[next] == "an") {
+			next++
+		}
+		if next < len(words) {
+			if format := strings.Trim(words[next], ".,"); known(format) {
+				return format
+			}
+		}
+	}
+	for _, word := range strings.Fields(lowerQuery) {
+		word = strings.Trim(word, ".,")
+		if known(word) {
+			return word
+		}
+	}
+	return ""
+}
+
+func isMediaFormat(word string) bool {
+	for _, format := range mediaFormats {
+		if word == format {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeOptions layers the explicitly requested options over a preset
+func mergeOptions(base, override ConversionOptions) ConversionOptions {
+	merged := base
+	if override.Format != "" {
+		merged.Format = override.Format
+	}
+	if override.VideoCodec != "" {
+		merged.VideoCodec = override.VideoCodec
+	}
+	if override.AudioCodec != "" {
+		merged.AudioCodec = override.AudioCodec
+	}
+	if override.VideoBitrate != "" {
+		merged.VideoBitrate = override.VideoBitrate
+	}
+	if override.AudioBitrate != "" {
+		merged.AudioBitrate = override.AudioBitrate
+	}
+	if override.CRF != 0 {
+		merged.CRF = override.CRF
+	}
+	if override.Width != 0 || override.Height != 0 {
+		merged.Width, merged.Height = override.Width, override.Height
+	}
+	if override.FPS != 0 {
+		merged.FPS = override.FPS
+	}
+	if override.Percent != 0 {
+		merged.Percent = override.Percent
+	}
+	if override.Quality != 0 {
+		merged.Quality = override.Quality
+	}
+	if override.Start != 0 {
+		merged.Start = override.Start
+	}
+	if override.Duration != 0 {
+		merged.Duration = override.Duration
+	}
+	if override.FromEnd != 0 {
+		merged.FromEnd = override.FromEnd
+	}
+	if override.TargetSizeMB != 0 {
+		merged.TargetSizeMB = override.TargetSizeMB
+	}
+	if override.OutputDir != "" {
+		merged.OutputDir = override.OutputDir
+	}
+	if override.OnConflict != "" {
+		merged.OnConflict = override.OnConflict
+	}
+	merged.AudioOnly = merged.AudioOnly || override.AudioOnly
+	merged.NoAudio = merged.NoAudio || override.NoAudio
+	return merged
+}
+
+// parseTimestamp reads "90", "1:30" or "1:02:03", with an optional unit for
+// plain numbers
+func parseTimestamp(value, unit string) float64 {
+	if strings.Contains(value, ":") {
+		var seconds float64
+		for _, part := range strings.Split(value, ":") {
+			n, _ := strconv.ParseFloat(part, 64)
+			seconds = seconds*60 + n
+		}
+		return seconds
+	}
+
+	n, _ := strconv.ParseFloat(value, 64)
+	if strings.HasPrefix(unit, "m") {
+		n *= 60
+	}
+	return n
+}
+
+// effectiveDuration is how much of the input ends up in the output
+func (opts ConversionOptions) effectiveDuration(input float64) float64 {
+	d := input
+	if opts.FromEnd > 0 && (d == 0 || opts.FromEnd < d) {
+		d = opts.FromEnd
+	} else if opts.Start > 0 && d > opts.Start {
+		d -= opts.Start
+	}
+	if opts.Duration > 0 && (d == 0 || opts.Duration < d) {
+		d = opts.Duration
+	}
+	return d
+}
+
+// videoBitrateForSize picks the video bitrate that lands the output just under
+// the target size once audio and container overhead are accounted for
+func (opts ConversionOptions) videoBitrateForSize(duration float64) (string, error) {
+	if duration <= 0 {
+		return "", fmt.Errorf("can't target a file size without knowing the duration (is ffprobe installed?)")
+	}
+
+	// MB to kilobits, less a few percent for container overhead
+	totalKbps := opts.TargetSizeMB * 8 * 1024 * 1024 / 1000 / duration * 0.96
+	audioKbps := 0.0
+	if !opts.NoAudio {
+		audioKbps = 128
+		if opts.AudioBitrate != "" {
+			audioKbps, _ = strconv.ParseFloat(strings.TrimSuffix(opts.AudioBitrate, "k"), 64)
+		}
+	}
+
+	videoKbps := math.Floor(totalKbps - audioKbps)
+	if videoKbps < 100 {
+		return "", fmt.Errorf("%.0f MB is too small for %.0f seconds of video", opts.TargetSizeMB, duration)
+	}
+	return strconv.Itoa(int(videoKbps)) + "k", nil
+}
+
+// ffmpegArgs builds the command line for one pass. pass is 0 for a normal
+// encode, or 1 and 2 for a two-pass encode sharing passlog.
+func (opts ConversionOptions) ffmpegArgs(input, output string, pass int, passlog string) []string {
+	var args []string
+	if opts.FromEnd > 0 {
+		args = append(args, "-sseof", formatSeconds(-opts.FromEnd))
+	} else if opts.Start > 0 {
+		args = append(args, "-ss", formatSeconds(opts.Start))
+	}
+	args = append(args, "-i", input)
+	if opts.Duration > 0 {
+		args = append(args, "-t", formatSeconds(opts.Duration))
+	}
+
+	audioOnly := opts.AudioOnly || audioFormats[opts.Format]
+	if audioOnly {
+		args = append(args, "-vn")
+	} else {
+		args = append(args, opts.videoArgs()...)
+	}
+
+	if pass == 1 || opts.NoAudio || opts.Format == "gif" {
+		args = append(args, "-an")
+	} else {
+		if opts.AudioCodec != "" {
+			args = append(args, "-c:a", opts.AudioCodec)
+		}
+		if opts.AudioBitrate != "" {
+			args = append(args, "-b:a", opts.AudioBitrate)
+		}
+	}
+
+	// A still image from a video is a single frame at the start point
+	if isImageFormat(opts.Format) && opts.Format != "gif" && opts.Format != "webp" {
+		args = append(args, "-frames:v", "1", "-update", "1")
+	}
+
+	if opts.Format == "mp4" || opts.Format == "mov" || opts.Format == "m4a" {
+		args = append(args, "-movflags", "+faststart")
+	}
+
+	if pass > 0 {
+		args = append(args, "-pass", strconv.Itoa(pass), "-passlogfile", passlog)
+	}
+	if pass == 1 {
+		return append(args, "-f", "null", "-y", "/dev/null")
+	}
+	return append(args, "-y", output)
+}
+
+func (opts ConversionOptions) videoArgs() []string {
+	var args []string
+	var filters []string
+	fps := opts.FPS
+	if fps == 0 && opts.Format == "gif" {
+		fps = 15
+	}
+	if fps > 0 {
+		filters = append(filters, "fps="+strconv.Itoa(fps))
+	}
+	if scale := opts.scaleFilter(); scale != "" {
+		filters = append(filters, scale)
+	}
+
+	if opts.Format == "gif" {
+		// A generated palette keeps GIF colors from banding
+		chain := "split[a][b];[a]palettegen[p];[b][p]paletteuse"
+		if len(filters) > 0 {
+			chain = strings.Join(filters, ",") + "," + chain
+		}
+		return []string{"-filter_complex", "[0:v]" + chain}
+	}
+
+	if len(filters) > 0 {
+		args = append(args, "-vf", strings.Join(filters, ","))
+	}
+	if opts.VideoCodec != "" {
+		args = append(args, "-c:v", opts.VideoCodec)
+	}
+	if opts.VideoBitrate != "" {
+		args = append(args, "-b:v", opts.VideoBitrate)
+	} else if opts.CRF > 0 {
+		args = append(args, "-crf", strconv.Itoa(opts.CRF))
+	}
+	return args
+}
+
+// scaleFilter keeps the aspect ratio; -2 keeps the other side even, which
+// most encoders require
+func (opts ConversionOptions) scaleFilter() string {
+	flags := ""
+	if opts.Format == "gif" {
+		flags = ":flags=lanczos"
+	}
+	switch {
+	case opts.Percent > 0:
+		return fmt.Sprintf("scale=trunc(iw*%d/200)*2:-2%s", opts.Percent, flags)
+	case opts.Width > 0 && opts.Height > 0:
+		return fmt.Sprintf("scale=%d:%d%s", opts.Width, opts.Height, flags)
+	case opts.Height > 0:
+		return fmt.Sprintf("scale=-2:%d%s", opts.Height, flags)
+	case opts.Width > 0:
+		return fmt.Sprintf("scale=%d:-2%s", opts.Width, flags)
+	}
+	return ""
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
+
+// extractMediaPath finds the input file, also accepting bare names like
+// "clip.mkv" that have a media extension
+func extractMediaPath(query string) string {
+	return extractFilePath(query, isMediaFormat)
+}
+
+// extractFilePath returns the first path-like word, or else the first bare
+// file name whose extension known accepts
+func extractFilePath(query string, known func(string) bool) string {
+	if path := extractPath(query); path != "" {
+		return path
+	}
+	for _, word := range strings.Fields(query) {
+		word = strings.Trim(word, "\"',")
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(word)), ".")
+		if ext != "" && ext != word && known(ext) {
+			return word
+		}
+	}
+	return ""
+}
+
+// parseConflict reads what to do when the output already exists
+func parseConflict(lowerQuery string) string {
+	switch {
+	case strings.Contains(lowerQuery, "overwrite") || strings.Contains(lowerQuery, "replace existing"):
+		return "overwrite"
+	case strings.Contains(lowerQuery, "skip existing"):
+		return "skip"
+	}
+	return ""
+}
+
+// parseOutputDir finds "into ~/out" or "output to ./mp3" and returns the
+// directory along with the query without that phrase, so the directory isn't
+// taken for the input
+func parseOutputDir(query string) (string, string) {
+	loc := outputDirPattern.FindStringSubmatchIndex(query)
+	if loc == nil {
+		return "", query
+	}
+	dir := expandHome(strings.Trim(query[loc[2]:loc[3]], "\"'"))
+	return dir, query[:loc[0]] + query[loc[1]:]
+}
+
+// resizes reports whether the options change the picture size or quality, in
+// which case a missing target format means "keep the format"
+func (opts ConversionOptions) resizes() bool {
+	return opts.Percent > 0 || opts.Width > 0 || opts.Height > 0 || opts.Quality > 0
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConversionOptions(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   ConversionOptions
+		preset string
+	}{
+		{
+			query: "convert clip.mkv to gif 480p first 10 seconds",
+			want:  ConversionOptions{Format: "gif", Height: 480, Duration: 10},
+		},
+		{
+			query: "extract audio from talk.mp4 as opus 96k",
+			want:  ConversionOptions{Format: "opus", AudioCodec: "libopus", AudioBitrate: "96k", AudioOnly: true},
+		},
+		{
+			query:  "compress video for discord under 25MB",
+			want:   ConversionOptions{Format: "mp4", VideoCodec: "libx264", AudioCodec: "aac", TargetSizeMB: 25},
+			preset: "discord",
+		},
+		{
+			query: "convert ~/Videos/clip.mkv to webm 720p 30fps",
+			want:  ConversionOptions{Format: "webm", Height: 720, FPS: 30},
+		},
+		{
+			query: "mute clip.mp4",
+			want:  ConversionOptions{NoAudio: true},
+		},
+		{
+			query: "convert clip.mp4 to webm without audio",
+			want:  ConversionOptions{Format: "webm", NoAudio: true},
+		},
+		// Option words inside file names are not options
+		{
+			query: "convert commute.mp4 to mp3",
+			want:  ConversionOptions{Format: "mp3", AudioCodec: "libmp3lame"},
+		},
+		{
+			query: "convert mutemath.mp4 to webm",
+			want:  ConversionOptions{Format: "webm"},
+		},
+		{
+			query: "convert clip.480p.mkv to mp4",
+			want:  ConversionOptions{Format: "mp4"},
+		},
+		{
+			query: "convert discord.mov to mp4",
+			want:  ConversionOptions{Format: "mp4"},
+		},
+	}
+	for _, tt := range tests {
+		got, preset := parseConversionOptions(tt.query, defaultPresets())
+		if !reflect.DeepEqual(got, tt.want) || preset != tt.preset {
+			t.Errorf("parseConversionOptions(%q) = %+v, %q\nwant %+v, %q", tt.query, got, preset, tt.want, tt.preset)
+		}
+	}
+}
+
+func TestValidateConversion(t *testing.T) {
+	video := MediaInfo{Path: "/tmp/clip.mp4", Duration: 60, Streams: []MediaStream{{Type: "video"}, {Type: "audio"}}}
+	tests := []struct {
+		opts    ConversionOptions
+		wantErr bool
+	}{
+		{ConversionOptions{Format: "webm"}, false},
+		{ConversionOptions{Format: "webm", NoAudio: true}, false},
+		{ConversionOptions{Format: "mp3", AudioOnly: true}, false},
+		{ConversionOptions{Format: "mp3", NoAudio: true}, true},
+		{ConversionOptions{Format: "mp4", AudioOnly: true, NoAudio: true}, true},
+		{ConversionOptions{Format: "mp4", Start: 90}, true},
+	}
+	for _, tt := range tests {
+		if err := validateConversion(video, tt.opts); (err != nil) != tt.wantErr {
+			t.Errorf("validateConversion(%+v) = %v, want error %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
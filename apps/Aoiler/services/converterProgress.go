@@ -0,0 +1,258 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errConversionCancelled is returned when the user stops a running job
+var errConversionCancelled = errors.New("conversion cancelled")
+
+// ConverterProgress is emitted as "converter:progress" while ffmpeg runs.
+// Percent and ETA stay at zero when the input duration is unknown.
+type ConverterProgress struct {
+	JobID      string  `json:"jobId"`
+	Percent    float64 `json:"percent"`
+	Speed      float64 `json:"speed"`
+	ETASeconds float64 `json:"etaSeconds"`
+	OutTime    float64 `json:"outTime"`
+	Duration   float64 `json:"duration"`
+	Size       int64   `json:"size"`
+	Pass       int     `json:"pass"`
+	Passes     int     `json:"passes"`
+}
+
+// conversionJob is one running ffmpeg invocation that can be cancelled
+type conversionJob struct {
+	id       string
+	input    string
+	output   string
+	duration float64
+	// pass and passes split progress across two-pass encodes
+	pass   int
+	passes int
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// conversionJobs tracks running jobs and batches so the UI can cancel them
+// by ID
+type conversionJobs struct {
+	mu      sync.Mutex
+	jobs    map[string]*conversionJob
+	batches map[string]context.CancelFunc
+}
+
+// startJob registers a job that stops when parent is cancelled. duration is
+// how much media ffmpeg will write, zero when unknown.
+func (cs *ConverterService) startJob(parent context.Context, input, output string, duration float64) *conversionJob {
+	ctx, cancel := context.WithCancel(parent)
+	job := &conversionJob{
+		id:       newID(),
+		input:    input,
+		output:   output,
+		duration: duration,
+		pass:     1,
+		passes:   1,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	cs.running.mu.Lock()
+	if cs.running.jobs == nil {
+		cs.running.jobs = make(map[string]*conversionJob)
+	}
+	cs.running.jobs[job.id] = job
+	cs.running.mu.Unlock()
+
+	cs.emitter.emit("converter:start", map[string]interface{}{
+		"jobId":    job.id,
+		"input":    input,
+		"output":   output,
+		"duration": job.duration,
+	})
+	return job
+}
+
+// finishJob unregisters a job and removes partial output if it didn't succeed
+func (cs *ConverterService) finishJob(job *conversionJob, err error) {
+	job.cancel()
+
+	cs.running.mu.Lock()
+	delete(cs.running.jobs, job.id)
+	cs.running.mu.Unlock()
+
+	if err != nil {
+		os.Remove(job.output)
+	}
+	cs.emitter.emit("converter:done", map[string]interface{}{
+		"jobId":     job.id,
+		"success":   err == nil,
+		"cancelled": errors.Is(err, errConversionCancelled),
+	})
+}
+
+// Cancel stops a running conversion or a whole batch by its run ID. It
+// returns false if the ID is unknown or already finished.
+func (cs *ConverterService) Cancel(id string) bool {
+	cs.running.mu.Lock()
+	job, ok := cs.running.jobs[id]
+	cs.running.mu.Unlock()
+	if ok {
+		job.cancel()
+		return true
+	}
+	return cs.cancelBatch(id)
+}
+
+// encode runs one ffmpeg pass, or two when a target size has to be hit. It
+// returns the options with any computed bitrates filled in.
+func (cs *ConverterService) encode(job *conversionJob, opts ConversionOptions) (ConversionOptions, error) {
+	if opts.TargetSizeMB <= 0 {
+		return opts, cs.runFFmpeg(job, opts.ffmpegArgs(job.input, job.output, 0, ""))
+	}
+
+	if opts.AudioOnly || audioFormats[opts.Format] {
+		if job.duration <= 0 {
+			return opts, fmt.Errorf("can't target a file size without knowing the duration (is ffprobe installed?)")
+		}
+		kbps := int(opts.TargetSizeMB * 8 * 1024 * 1024 / 1000 / job.duration * 0.96)
+		opts.AudioBitrate = strconv.Itoa(kbps) + "k"
+		return opts, cs.runFFmpeg(job, opts.ffmpegArgs(job.input, job.output, 0, ""))
+	}
+
+	bitrate, err := opts.videoBitrateForSize(job.duration)
+	if err != nil {
+		return opts, err
+	}
+	opts.VideoBitrate = bitrate
+	if opts.VideoCodec == "" {
+		opts.VideoCodec = "libx264"
+		if opts.Format == "webm" {
+			opts.VideoCodec = "libvpx-vp9"
+		}
+	}
+
+	passDir, err := os.MkdirTemp("", "aoiler-2pass-")
+	if err != nil {
+		return opts, err
+	}
+	defer os.RemoveAll(passDir)
+	passlog := filepath.Join(passDir, "ffmpeg2pass")
+
+	job.passes = 2
+	for pass := 1; pass <= 2; pass++ {
+		job.pass = pass
+		if err := cs.runFFmpeg(job, opts.ffmpegArgs(job.input, job.output, pass, passlog)); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// runFFmpeg runs ffmpeg with args, streaming progress events for the job.
+// args must not include the progress flags, they are added here.
+func (cs *ConverterService) runFFmpeg(job *conversionJob, args []string) error {
+	full := append([]string{"-hide_banner", "-nostdin", "-nostats", "-progress", "pipe:1"}, args...)
+	cmd := exec.CommandContext(job.ctx, "ffmpeg", full...)
+	// Interrupt lets ffmpeg close files cleanly, kill it if it doesn't exit
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 5 * time.Second
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr tailBuffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("ffmpeg is not installed")
+		}
+		return err
+	}
+
+	cs.readProgress(job, bufio.NewScanner(stdout))
+	err = cmd.Wait()
+
+	if job.ctx.Err() != nil {
+		return errConversionCancelled
+	}
+	if err != nil {
+		return fmt.Errorf("conversion failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// readProgress parses ffmpeg's key=value progress blocks, each of which ends
+// with a "progress=continue" or "progress=end" line
+func (cs *ConverterService) readProgress(job *conversionJob, scanner *bufio.Scanner) {
+	progress := ConverterProgress{JobID: job.id, Duration: job.duration, Pass: job.pass, Passes: job.passes}
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "out_time_us":
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				progress.OutTime = float64(us) / 1e6
+			}
+		case "speed":
+			if speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+				progress.Speed = speed
+			}
+		case "total_size":
+			progress.Size, _ = strconv.ParseInt(value, 10, 64)
+		case "progress":
+			if job.duration > 0 {
+				done := progress.OutTime / job.duration
+				if value == "end" || done > 1 {
+					done = 1
+				}
+				progress.Percent = (float64(job.pass-1) + done) / float64(job.passes) * 100
+				if progress.Speed > 0 {
+					remaining := float64(job.passes-job.pass)*job.duration + job.duration*(1-done)
+					progress.ETASeconds = remaining / progress.Speed
+				}
+			}
+			cs.emitter.emit("converter:progress", progress)
+			if job.duration > 0 {
+				reportProgress(job.ctx, progress.Percent/100, fmt.Sprintf("%.1fx, %s left", progress.Speed, formatClock(progress.ETASeconds)))
+			} else {
+				reportProgress(job.ctx, -1, fmt.Sprintf("%s written", formatClock(progress.OutTime)))
+			}
+		}
+	}
+}
+
+// tailBuffer keeps only the end of a long stream, which is where ffmpeg
+// prints the reason it failed
+type tailBuffer struct {
+	data []byte
+}
+
+const tailBufferSize = 4096
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.data = append(t.data, p...)
+	if over := len(t.data) - tailBufferSize; over > 0 {
+		t.data = t.data[over:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	return string(t.data)
+}
@@ -0,0 +1,551 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"shared/theme"
+)
+
+const (
+	desktopVolumeStep = 5
+	// pendingDesktopTTL is how long a disruptive action waits for confirmation
+	pendingDesktopTTL = 10 * time.Minute
+	// hyprctlTimeout bounds the window lookup done while classifying a query
+	hyprctlTimeout = 2 * time.Second
+	defaultSink    = "@DEFAULT_AUDIO_SINK@"
+)
+
+// Desktop commands, each anchored so a word like "theme" or "wallpaper" in a
+// file name or a question doesn't turn it into a desktop change
+var (
+	volumePattern     = regexp.MustCompile(`(?i)^(?:set\s+|turn\s+)?(?:the\s+)?volume(?:\s+(?:to\s+)?(up|down|mute|unmute|[+-]?\d{1,3})\s*%?)?$`)
+	turnVolumePattern = regexp.MustCompile(`(?i)^turn\s+(up|down)\s+(?:the\s+)?volume$`)
+	mutePattern       = regexp.MustCompile(`(?i)^(mute|unmute)(?:\s+(?:the\s+)?(?:audio|sound|volume))?$`)
+	themeListPattern  = regexp.MustCompile(`(?i)^(?:list\s+|show\s+)?themes$`)
+	// "switch to Nord theme", "set theme to nord", "apply gruvbox theme", "theme nord"
+	themePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^(?:switch|change|set)\s+(?:the\s+)?(?:theme\s+)?to\s+(.+?)(?:\s+theme)?$`),
+		regexp.MustCompile(`(?i)^(?:apply|use)\s+(?:the\s+)?(.+?)\s+theme$`),
+		regexp.MustCompile(`(?i)^theme\s+(.+)$`),
+	}
+	wallpaperPattern       = regexp.MustCompile(`(?i)^(?:(?:set|change)\s+(?:the\s+)?)?wallpaper(?:\s+to)?\s+(.+)$`)
+	randomWallpaperPattern = regexp.MustCompile(`(?i)^(?:random|next|shuffle)\s+wallpaper$`)
+	// "enable dark mode", "light mode", "dark mode off"
+	colorSchemePattern = regexp.MustCompile(`(?i)^(?:(enable|disable|turn\s+on|turn\s+off|switch\s+to|use)\s+)?(dark|light)\s+(?:mode|theme)(?:\s+(on|off))?$`)
+	moveWindowPattern  = regexp.MustCompile(`(?i)^move\s+(.+?)\s+to\s+workspace\s+(\d+|special)$`)
+	workspacePattern   = regexp.MustCompile(`(?i)^(?:(?:go|switch)\s+to\s+)?workspace\s+(\d+|special)$`)
+	focusWindowPattern = regexp.MustCompile(`(?i)^(?:focus|switch\s+to)\s+(.+)$`)
+	closeWindowPattern = regexp.MustCompile(`(?i)^close\s+(?:the\s+)?(.+?)(?:\s+window)?$`)
+	waybarPattern      = regexp.MustCompile(`(?i)^(?:reload|restart|refresh)\s+waybar$`)
+)
+
+// DesktopResult reports a desktop change, or one waiting for confirmation
+type DesktopResult struct {
+	// Action is volume, theme, themes, wallpaper, colorscheme, window,
+	// workspace or waybar
+	Action      string `json:"action"`
+	Description string `json:"description"`
+	// PendingID is set while a disruptive change waits for ConfirmAction
+	PendingID string `json:"pendingId,omitempty"`
+	Done      bool   `json:"done"`
+	// Volume is the sink volume in percent after a volume change
+	Volume int  `json:"volume,omitempty"`
+	Muted  bool `json:"muted,omitempty"`
+	// Themes and CurrentTheme answer "themes"
+	Themes       []theme.Preset `json:"themes,omitempty"`
+	CurrentTheme string         `json:"currentTheme,omitempty"`
+}
+
+// desktopAction is one parsed command. Disruptive ones change the whole
+// desktop or close something and only run once confirmed.
+type desktopAction struct {
+	kind        string
+	description string
+	disruptive  bool
+	// check, when set, fails early so a change that can't work isn't
+	// offered for confirmation
+	check func() error
+	run   func(ctx context.Context, result *DesktopResult) error
+}
+
+type pendingDesktop struct {
+	action    desktopAction
+	createdAt time.Time
+}
+
+// hyprClient is the part of "hyprctl clients -j" used to find windows
+type hyprClient struct {
+	Address      string `json:"address"`
+	Class        string `json:"class"`
+	InitialClass string `json:"initialClass"`
+	Title        string `json:"title"`
+}
+
+// DesktopService controls volume, themes, the wallpaper, dark mode and
+// Hyprland windows
+type DesktopService struct {
+	mu      sync.Mutex
+	pending map[string]pendingDesktop
+}
+
+func NewDesktopService() *DesktopService {
+	return &DesktopService{pending: make(map[string]pendingDesktop)}
+}
+
+// Handles reports whether a query is a desktop command
+func (ds *DesktopService) Handles(query string) bool {
+	_, ok := ds.parse(query)
+	return ok
+}
+
+// Run carries out a desktop command. Disruptive ones return a PendingID
+// instead and wait for ConfirmAction.
+func (ds *DesktopService) Run(ctx context.Context, query string) (DesktopResult, error) {
+	action, ok := ds.parse(query)
+	if !ok {
+		return DesktopResult{}, fmt.Errorf("not a desktop command: %s", query)
+	}
+	result := DesktopResult{Action: action.kind, Description: action.description}
+	if action.check != nil {
+		if err := action.check(); err != nil {
+			return result, err
+		}
+	}
+
+	if action.disruptive {
+		id := newID()
+		ds.mu.Lock()
+		ds.prunePendingLocked()
+		ds.pending[id] = pendingDesktop{action: action, createdAt: time.Now()}
+		ds.mu.Unlock()
+		result.PendingID = id
+		return result, nil
+	}
+
+	err := action.run(ctx, &result)
+	result.Done = err == nil
+	return result, err
+}
+
+// ConfirmAction runs a disruptive change the user agreed to
+func (ds *DesktopService) ConfirmAction(ctx context.Context, id string) (DesktopResult, error) {
+	ds.mu.Lock()
+	pending, ok := ds.pending[id]
+	delete(ds.pending, id)
+	ds.mu.Unlock()
+
+	if !ok {
+		return DesktopResult{}, fmt.Errorf("no pending desktop change with id %s, run the command again", id)
+	}
+	result := DesktopResult{Action: pending.action.kind, Description: pending.action.description}
+	err := pending.action.run(ctx, &result)
+	result.Done = err == nil
+	return result, err
+}
+
+// DiscardAction drops a disruptive change without running it
+func (ds *DesktopService) DiscardAction(id string) bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	_, ok := ds.pending[id]
+	delete(ds.pending, id)
+	return ok
+}
+
+func (ds *DesktopService) prunePendingLocked() {
+	for id, p := range ds.pending {
+		if time.Since(p.createdAt) > pendingDesktopTTL {
+			delete(ds.pending, id)
+		}
+	}
+}
+
+// parse turns a query into a desktop action. Window commands only match
+// when a window does, so "focus on the intro" still goes to the LLM.
+func (ds *DesktopService) parse(query string) (desktopAction, bool) {
+	query = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(query), ".!"))
+
+	if m := volumePattern.FindStringSubmatch(query); m != nil {
+		return volumeAction(strings.ToLower(m[1])), true
+	}
+	if m := turnVolumePattern.FindStringSubmatch(query); m != nil {
+		return volumeAction(strings.ToLower(m[1])), true
+	}
+	if m := mutePattern.FindStringSubmatch(query); m != nil {
+		return volumeAction(strings.ToLower(m[1])), true
+	}
+
+	if waybarPattern.MatchString(query) {
+		return desktopAction{
+			kind:        "waybar",
+			description: "Reload waybar",
+			run: func(ctx context.Context, result *DesktopResult) error {
+				if err := theme.ReloadWaybar(); err != nil {
+					return fmt.Errorf("waybar is not running")
+				}
+				return nil
+			},
+		}, true
+	}
+
+	if themeListPattern.MatchString(query) {
+		return themeListAction(), true
+	}
+	for _, pattern := range themePatterns {
+		if m := pattern.FindStringSubmatch(query); m != nil {
+			if action, ok := themeAction(m[1]); ok {
+				return action, true
+			}
+		}
+	}
+
+	if randomWallpaperPattern.MatchString(query) {
+		return wallpaperAction(""), true
+	}
+	if m := wallpaperPattern.FindStringSubmatch(query); m != nil {
+		return wallpaperAction(strings.Trim(m[1], `"'`)), true
+	}
+
+	if m := colorSchemePattern.FindStringSubmatch(query); m != nil {
+		dark := strings.EqualFold(m[2], "dark")
+		verb := strings.ToLower(strings.Join(strings.Fields(m[1]), " "))
+		if verb == "disable" || verb == "turn off" || strings.EqualFold(m[3], "off") {
+			dark = !dark
+		}
+		return colorSchemeAction(dark), true
+	}
+
+	if m := moveWindowPattern.FindStringSubmatch(query); m != nil {
+		return moveWindowAction(m[1], strings.ToLower(m[2])), true
+	}
+	if m := workspacePattern.FindStringSubmatch(query); m != nil {
+		workspace := strings.ToLower(m[1])
+		return desktopAction{
+			kind:        "workspace",
+			description: "Switch to workspace " + workspace,
+			run: func(ctx context.Context, result *DesktopResult) error {
+				return hyprDispatch(ctx, "workspace", workspace)
+			},
+		}, true
+	}
+	if m := focusWindowPattern.FindStringSubmatch(query); m != nil {
+		if client, ok := findWindow(m[1]); ok {
+			return desktopAction{
+				kind:        "window",
+				description: "Focus " + windowLabel(client),
+				run: func(ctx context.Context, result *DesktopResult) error {
+					return hyprDispatch(ctx, "focuswindow", "address:"+client.Address)
+				},
+			}, true
+		}
+	}
+	if m := closeWindowPattern.FindStringSubmatch(query); m != nil {
+		if client, ok := findWindow(m[1]); ok {
+			return desktopAction{
+				kind:        "window",
+				description: "Close " + windowLabel(client),
+				disruptive:  true,
+				run: func(ctx context.Context, result *DesktopResult) error {
+					return hyprDispatch(ctx, "closewindow", "address:"+client.Address)
+				},
+			}, true
+		}
+	}
+	return desktopAction{}, false
+}
+
+// volumeAction sets, raises, lowers or mutes the default sink with wpctl.
+// Raising stops at 100%.
+func volumeAction(change string) desktopAction {
+	action := desktopAction{kind: "volume"}
+	var args []string
+	switch {
+	case change == "":
+		action.description = "Show the volume"
+	case change == "mute" || change == "unmute":
+		action.description = strings.ToUpper(change[:1]) + change[1:]
+		args = []string{"set-mute", defaultSink, map[string]string{"mute": "1", "unmute": "0"}[change]}
+	case change == "up" || strings.HasPrefix(change, "+"):
+		step := strings.TrimPrefix(change, "+")
+		if change == "up" {
+			step = strconv.Itoa(desktopVolumeStep)
+		}
+		action.description = "Raise the volume by " + step + "%"
+		args = []string{"set-volume", "-l", "1.0", defaultSink, step + "%+"}
+	case change == "down" || strings.HasPrefix(change, "-"):
+		step := strings.TrimPrefix(change, "-")
+		if change == "down" {
+			step = strconv.Itoa(desktopVolumeStep)
+		}
+		action.description = "Lower the volume by " + step + "%"
+		args = []string{"set-volume", defaultSink, step + "%-"}
+	default:
+		percent, _ := strconv.Atoi(change)
+		percent = min(percent, 100)
+		action.description = fmt.Sprintf("Set the volume to %d%%", percent)
+		args = []string{"set-volume", defaultSink, strconv.Itoa(percent) + "%"}
+	}
+
+	action.run = func(ctx context.Context, result *DesktopResult) error {
+		if _, err := exec.LookPath("wpctl"); err != nil {
+			return fmt.Errorf("wpctl is not installed, volume is controlled through WirePlumber")
+		}
+		if args != nil {
+			if output, err := exec.CommandContext(ctx, "wpctl", args...).CombinedOutput(); err != nil {
+				return fmt.Errorf("wpctl failed: %s", strings.TrimSpace(string(output)))
+			}
+		}
+		// "Volume: 0.40" or "Volume: 0.40 [MUTED]"
+		output, err := exec.CommandContext(ctx, "wpctl", "get-volume", defaultSink).Output()
+		if err != nil {
+			return fmt.Errorf("wpctl get-volume failed: %w", err)
+		}
+		fields := strings.Fields(string(output))
+		if len(fields) >= 2 {
+			if volume, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				result.Volume = int(volume*100 + 0.5)
+			}
+		}
+		result.Muted = strings.Contains(string(output), "[MUTED]")
+		return nil
+	}
+	return action
+}
+
+func themeListAction() desktopAction {
+	return desktopAction{
+		kind:        "themes",
+		description: "List the themes",
+		run: func(ctx context.Context, result *DesktopResult) error {
+			result.Themes = theme.Presets()
+			if mode, _ := theme.Mode(); mode == "dynamic" {
+				result.CurrentTheme = "dynamic"
+			} else if colors, err := theme.Colors(); err == nil {
+				result.CurrentTheme = theme.Detect(colors, result.Themes)
+			}
+			return nil
+		},
+	}
+}
+
+// themeAction applies a preset, or "dynamic" to take colors from the
+// wallpaper again. Names match loosely: "nord", "tokyonight", "catppuccin".
+func themeAction(name string) (desktopAction, bool) {
+	key := themeKey(name)
+	if key == "" {
+		return desktopAction{}, false
+	}
+
+	if key == "dynamic" || key == "wallpaper" || key == "pywal" {
+		return desktopAction{
+			kind:        "theme",
+			description: "Switch to dynamic colors from the wallpaper",
+			disruptive:  true,
+			run: func(ctx context.Context, result *DesktopResult) error {
+				if err := theme.SetMode("dynamic"); err != nil {
+					return fmt.Errorf("failed to switch theme mode to dynamic: %w", err)
+				}
+				homeDir, _ := os.UserHomeDir()
+				script := filepath.Join(homeDir, ".config", "hecate", "scripts", "hecate-system-colors.sh")
+				if output, err := exec.CommandContext(ctx, "bash", script).CombinedOutput(); err != nil {
+					return fmt.Errorf("hecate-system-colors.sh failed: %s", strings.TrimSpace(string(output)))
+				}
+				return nil
+			},
+		}, true
+	}
+
+	for _, preset := range theme.Presets() {
+		if strings.HasPrefix(themeKey(preset.Name), key) {
+			preset := preset
+			return desktopAction{
+				kind:        "theme",
+				description: "Apply the " + preset.Name + " theme",
+				disruptive:  true,
+				run: func(ctx context.Context, result *DesktopResult) error {
+					return applyTheme(preset)
+				},
+			}, true
+		}
+	}
+	return desktopAction{}, false
+}
+
+func themeKey(name string) string {
+	return strings.Join(searchWords(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), " theme")), "")
+}
+
+// wallpaperAction sets the wallpaper through waypaper, which also runs its
+// post_command so dynamic colors follow. An empty path picks a random one.
+func wallpaperAction(path string) desktopAction {
+	action := desktopAction{kind: "wallpaper", disruptive: true}
+	args := []string{"--random"}
+	action.description = "Set a random wallpaper"
+	if path != "" {
+		path = expandHome(path)
+		args = []string{"--wallpaper", path}
+		action.description = "Set the wallpaper to " + path
+	}
+	if mode, err := theme.Mode(); err == nil && mode == "dynamic" {
+		action.description += ", the theme colors will follow it"
+	}
+
+	action.check = func() error {
+		if path != "" {
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				return fmt.Errorf("wallpaper not found: %s", path)
+			}
+		}
+		if _, err := exec.LookPath("waypaper"); err != nil {
+			return fmt.Errorf("waypaper is not installed")
+		}
+		return nil
+	}
+	action.run = func(ctx context.Context, result *DesktopResult) error {
+		if output, err := exec.CommandContext(ctx, "waypaper", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("waypaper failed: %s", strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+	return action
+}
+
+// colorSchemeAction sets the GNOME color scheme that GTK 4 and libadwaita
+// apps follow and the prefer-dark setting of GTK 3 and 4
+func colorSchemeAction(dark bool) desktopAction {
+	scheme, preferDark, label := "prefer-light", "0", "light"
+	if dark {
+		scheme, preferDark, label = "prefer-dark", "1", "dark"
+	}
+
+	return desktopAction{
+		kind:        "colorscheme",
+		description: "Switch to " + label + " mode",
+		run: func(ctx context.Context, result *DesktopResult) error {
+			changed := false
+			if _, err := exec.LookPath("gsettings"); err == nil {
+				output, err := exec.CommandContext(ctx, "gsettings", "set", "org.gnome.desktop.interface", "color-scheme", scheme).CombinedOutput()
+				if err != nil {
+					return fmt.Errorf("gsettings failed: %s", strings.TrimSpace(string(output)))
+				}
+				changed = true
+			}
+
+			homeDir, _ := os.UserHomeDir()
+			for _, dir := range []string{"gtk-3.0", "gtk-4.0"} {
+				path := filepath.Join(homeDir, ".config", dir, "settings.ini")
+				ok, err := setIniValue(path, "gtk-application-prefer-dark-theme", preferDark)
+				if err != nil {
+					return fmt.Errorf("failed to update %s: %w", path, err)
+				}
+				changed = changed || ok
+			}
+			if !changed {
+				return fmt.Errorf("neither gsettings nor a GTK settings.ini was found")
+			}
+			return nil
+		},
+	}
+}
+
+// setIniValue replaces key's value in an existing settings.ini, adding the
+// key when it's missing. It reports false when the file doesn't exist.
+func setIniValue(path, key, value string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	found := false
+	for i, line := range lines {
+		if name, _, ok := strings.Cut(line, "="); ok && strings.TrimSpace(name) == key {
+			lines[i] = key + "=" + value
+			found = true
+		}
+	}
+	if !found {
+		lines = append(lines, key+"="+value)
+	}
+	return true, writeFileAtomic(path, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+func moveWindowAction(name, workspace string) desktopAction {
+	return desktopAction{
+		kind:        "window",
+		description: fmt.Sprintf("Move %s to workspace %s", name, workspace),
+		run: func(ctx context.Context, result *DesktopResult) error {
+			client, ok := findWindow(name)
+			if !ok {
+				return fmt.Errorf("no window matches %q", name)
+			}
+			result.Description = fmt.Sprintf("Move %s to workspace %s", windowLabel(client), workspace)
+			return hyprDispatch(ctx, "movetoworkspacesilent", workspace+",address:"+client.Address)
+		},
+	}
+}
+
+// findWindow returns the window whose class is name, or else the first
+// whose class or title contains it
+func findWindow(name string) (hyprClient, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return hyprClient{}, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hyprctlTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "hyprctl", "clients", "-j").Output()
+	if err != nil {
+		return hyprClient{}, false
+	}
+	var clients []hyprClient
+	if err := json.Unmarshal(output, &clients); err != nil {
+		return hyprClient{}, false
+	}
+
+	for _, client := range clients {
+		if strings.EqualFold(client.Class, name) || strings.EqualFold(client.InitialClass, name) {
+			return client, true
+		}
+	}
+	for _, client := range clients {
+		if strings.Contains(strings.ToLower(client.Class), name) || strings.Contains(strings.ToLower(client.Title), name) {
+			return client, true
+		}
+	}
+	return hyprClient{}, false
+}
+
+func windowLabel(client hyprClient) string {
+	if client.Title == "" {
+		return client.Class
+	}
+	return fmt.Sprintf("%s (%s)", client.Title, client.Class)
+}
+
+// hyprDispatch runs a Hyprland dispatcher. hyprctl exits 0 even when the
+// dispatch fails, only "ok" means it worked.
+func hyprDispatch(ctx context.Context, dispatcher, arg string) error {
+	if _, err := exec.LookPath("hyprctl"); err != nil {
+		return fmt.Errorf("hyprctl is not installed, Aoiler controls the desktop through Hyprland")
+	}
+	output, err := exec.CommandContext(ctx, "hyprctl", "dispatch", dispatcher, arg).CombinedOutput()
+	if reply := strings.TrimSpace(string(output)); err != nil || reply != "ok" {
+		return fmt.Errorf("hyprctl dispatch %s failed: %s", dispatcher, reply)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"fmt"
+
+	"shared/theme"
+)
+
+// applyTheme applies a preset through the theme package Hecate-Help uses, so
+// either app can change the theme and the other still recognizes it. Theme
+// mode is switched to static first, or the next wallpaper change would undo it.
+func applyTheme(preset theme.Preset) error {
+	if mode, err := theme.Mode(); err == nil && mode != "static" {
+		if err := theme.SetMode("static"); err != nil {
+			return fmt.Errorf("failed to switch theme mode to static: %w", err)
+		}
+	}
+	return theme.Apply(preset)
+}
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// documentTimeout bounds a single tool run; LibreOffice in particular can
+// hang on a broken file
+const documentTimeout = 3 * time.Minute
+
+// Format groups the document routes are built from
+var (
+	markupFormats = []string{"md", "markdown", "html", "htm", "rst", "org", "tex", "epub", "txt"}
+	officeFormats = []string{"doc", "docx", "odt", "rtf", "xls", "xlsx", "ods", "ppt", "pptx", "odp"}
+	pandocReads   = append([]string{"docx", "odt"}, markupFormats...)
+	pandocWrites  = []string{"md", "html", "docx", "odt", "rst", "org", "tex", "epub", "txt", "pptx"}
+	officeWrites  = []string{"doc", "docx", "odt", "rtf", "xlsx", "ods", "csv", "pptx", "odp", "html", "txt"}
+)
+
+// pandocNames maps extensions to pandoc reader/writer names
+var pandocNames = map[string]string{
+	"md": "markdown", "markdown": "markdown", "htm": "html", "tex": "latex", "txt": "plain",
+}
+
+// documentAliases are other names people use for a target format
+var documentAliases = map[string]string{
+	"markdown": "md", "htm": "html", "latex": "tex", "text": "txt",
+	"jpeg": "jpg", "word": "docx", "excel": "xlsx", "powerpoint": "pptx",
+}
+
+func documentFormat(name string) string {
+	if format, ok := documentAliases[name]; ok {
+		return format
+	}
+	return name
+}
+
+// pdfEngines are tried in order when pandoc writes PDF directly
+var pdfEngines = []string{"typst", "tectonic", "xelatex", "pdflatex", "wkhtmltopdf", "weasyprint"}
+
+// DocumentConversion is one route of the document service, reported by
+// GetAvailableServices so the UI knows what will actually work
+type DocumentConversion struct {
+	Tool      string   `json:"tool"`
+	From      []string `json:"from"`
+	To        []string `json:"to"`
+	Available bool     `json:"available"`
+	Missing   []string `json:"missing,omitempty"`
+	// needs lists the detected tools the route runs
+	needs []string
+}
+
+// documentRoutes are checked in order, the first available match wins. PDF
+// from markup goes through pandoc's PDF engine when there is one, otherwise
+// through HTML and LibreOffice.
+var documentRoutes = []DocumentConversion{
+	{Tool: "pdftoppm", From: []string{"pdf"}, To: []string{"png", "jpg", "jpeg", "tif", "tiff"}, needs: []string{"pdftoppm"}},
+	{Tool: "pdftotext", From: []string{"pdf"}, To: []string{"txt"}, needs: []string{"pdftotext"}},
+	{Tool: "libreoffice", From: officeFormats, To: []string{"pdf"}, needs: []string{"libreoffice"}},
+	{Tool: "pandoc", From: markupFormats, To: []string{"pdf"}, needs: []string{"pandoc", "pdf-engine"}},
+	{Tool: "pandoc+libreoffice", From: markupFormats, To: []string{"pdf"}, needs: []string{"pandoc", "libreoffice"}},
+	{Tool: "pandoc", From: pandocReads, To: pandocWrites, needs: []string{"pandoc"}},
+	{Tool: "libreoffice", From: officeFormats, To: officeWrites, needs: []string{"libreoffice"}},
+}
+
+// DocumentConfig is the "document" section of the config file
+type DocumentConfig struct {
+	// PDFEngine is passed to pandoc --pdf-engine, picked from pdfEngines
+	// when empty
+	PDFEngine string `json:"pdfEngine,omitempty"`
+	// DPI for PDF pages rendered to images (default 150)
+	DPI int `json:"dpi,omitempty"`
+}
+
+// DocumentOptions are read from a query such as "convert slides.pdf to png
+// pages 2-4 300 dpi into ~/out"
+type DocumentOptions struct {
+	Format     string `json:"format"`
+	DPI        int    `json:"dpi,omitempty"`
+	FirstPage  int    `json:"firstPage,omitempty"`
+	LastPage   int    `json:"lastPage,omitempty"`
+	OutputDir  string `json:"outputDir,omitempty"`
+	OnConflict string `json:"onConflict,omitempty"`
+}
+
+// DocumentResult describes a finished document conversion. PDFs rendered to
+// images produce one file per page.
+type DocumentResult struct {
+	Success      bool            `json:"success"`
+	InputPath    string          `json:"inputPath"`
+	InputFormat  string          `json:"inputFormat"`
+	OutputFormat string          `json:"outputFormat"`
+	OutputPath   string          `json:"outputPath"`
+	OutputPaths  []string        `json:"outputPaths,omitempty"`
+	FileSize     int64           `json:"fileSize,omitempty"`
+	Tool         string          `json:"tool"`
+	Options      DocumentOptions `json:"options"`
+}
+
+// DocumentService converts documents with pandoc, LibreOffice and poppler
+type DocumentService struct {
+	// tools maps the names used in route needs to detected binaries
+	tools map[string]string
+	dpi   int
+}
+
+// NewDocumentService looks up the external tools once so routing and
+// GetAvailableServices don't have to search PATH on every query
+func NewDocumentService(cfg DocumentConfig) *DocumentService {
+	ds := &DocumentService{tools: make(map[string]string), dpi: cfg.DPI}
+	if ds.dpi <= 0 {
+		ds.dpi = 150
+	}
+
+	lookup := func(name string, candidates ...string) {
+		for _, candidate := range candidates {
+			if path, err := exec.LookPath(candidate); err == nil {
+				ds.tools[name] = path
+				return
+			}
+		}
+	}
+	lookup("pandoc", "pandoc")
+	lookup("libreoffice", "soffice", "libreoffice")
+	lookup("pdftoppm", "pdftoppm")
+	lookup("pdftotext", "pdftotext")
+
+	engines := pdfEngines
+	if cfg.PDFEngine != "" {
+		engines = []string{cfg.PDFEngine}
+	}
+	for _, engine := range engines {
+		if _, err := exec.LookPath(engine); err == nil {
+			ds.tools["pdf-engine"] = engine
+			break
+		}
+	}
+	return ds
+}
+
+// Conversions lists every route along with whether its tools are installed
+func (ds *DocumentService) Conversions() []DocumentConversion {
+	routes := make([]DocumentConversion, len(documentRoutes))
+	for i, route := range documentRoutes {
+		route.Missing = ds.missing(route)
+		route.Available = len(route.Missing) == 0
+		routes[i] = route
+	}
+	return routes
+}
+
+// Available reports whether any document conversion can run
+func (ds *DocumentService) Available() bool {
+	for _, route := range documentRoutes {
+		if len(ds.missing(route)) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (ds *DocumentService) missing(route DocumentConversion) []string {
+	var missing []string
+	for _, need := range route.needs {
+		if ds.tools[need] == "" {
+			missing = append(missing, need)
+		}
+	}
+	return missing
+}
+
+func isDocumentFormat(format string) bool {
+	format = strings.ToLower(format)
+	if format == "pdf" {
+		return true
+	}
+	for _, group := range [][]string{markupFormats, officeFormats} {
+		for _, f := range group {
+			if f == format {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDocumentTarget accepts anything a document route can write
+func isDocumentTarget(format string) bool {
+	for _, route := range documentRoutes {
+		if contains(route.To, format) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isDocumentQuery reports whether a conversion query is about a document,
+// judged by the input's extension
+func isDocumentQuery(query string) bool {
+	_, rest := parseOutputDir(query)
+	path := extractFilePath(rest, isDocumentFormat)
+	return isDocumentFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+var (
+	dpiPattern   = regexp.MustCompile(`\b(\d{2,4})\s*dpi\b`)
+	pagesPattern = regexp.MustCompile(`\bpages?\s+(\d+)(?:\s*(?:-|to)\s*(\d+))?\b`)
+)
+
+func parseDocumentOptions(query string) DocumentOptions {
+	var opts DocumentOptions
+	opts.OutputDir, query = parseOutputDir(query)
+	lowerQuery := strings.ToLower(stripPaths(query))
+
+	if m := dpiPattern.FindStringSubmatch(lowerQuery); m != nil {
+		opts.DPI, _ = strconv.Atoi(m[1])
+	}
+	if m := pagesPattern.FindStringSubmatch(lowerQuery); m != nil {
+		opts.FirstPage, _ = strconv.Atoi(m[1])
+		opts.LastPage = opts.FirstPage
+		if m[2] != "" {
+			opts.LastPage, _ = strconv.Atoi(m[2])
+		}
+	}
+	opts.OnConflict = parseConflict(lowerQuery)
+
+	// The input's own extension would otherwise count as the target
+	for _, word := range strings.Fields(lowerQuery) {
+		if strings.Contains(word, ".") && !strings.HasPrefix(word, ".") {
+			lowerQuery = strings.Replace(lowerQuery, word, "", 1)
+		}
+	}
+	opts.Format = documentFormat(parseTargetFormat(lowerQuery, func(word string) bool {
+		return isDocumentTarget(documentFormat(word))
+	}))
+	return opts
+}
+
+// Convert handles queries like "convert notes.md to pdf" or "convert
+// report.pdf to png pages 1-3"
+func (ds *DocumentService) Convert(ctx context.Context, query string) (DocumentResult, error) {
+	_, rest := parseOutputDir(query)
+	inputPath := expandHome(extractFilePath(rest, isDocumentFormat))
+	if inputPath == "" {
+		return DocumentResult{}, fmt.Errorf("no input document found")
+	}
+	opts := parseDocumentOptions(query)
+	if opts.Format == "" {
+		return DocumentResult{}, fmt.Errorf("no target format specified")
+	}
+	return ds.ConvertWithOptions(ctx, inputPath, opts)
+}
+
+// ConvertWithOptions picks the first route with installed tools for the
+// input and target formats and runs it
+func (ds *DocumentService) ConvertWithOptions(parent context.Context, inputPath string, opts DocumentOptions) (DocumentResult, error) {
+	inputFormat := strings.ToLower(strings.TrimPrefix(filepath.Ext(inputPath), "."))
+	result := DocumentResult{
+		InputPath:    inputPath,
+		InputFormat:  inputFormat,
+		OutputFormat: opts.Format,
+		Options:      opts,
+	}
+
+	if _, err := os.Stat(inputPath); err != nil {
+		return result, fmt.Errorf("input file does not exist: %s", inputPath)
+	}
+	if opts.Format == inputFormat {
+		return result, fmt.Errorf("%s is already %s", filepath.Base(inputPath), opts.Format)
+	}
+
+	route, err := ds.route(inputFormat, opts.Format)
+	if err != nil {
+		return result, err
+	}
+	result.Tool = route.Tool
+	if opts.DPI <= 0 {
+		opts.DPI = ds.dpi
+	}
+
+	outputPath, exists := outputPathFor(inputPath, filepath.Base(inputPath),
+		ConversionOptions{Format: opts.Format, OutputDir: opts.OutputDir, OnConflict: opts.OnConflict}, nil)
+	if exists {
+		return result, fmt.Errorf("%w: %s", errOutputExists, outputPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return result, fmt.Errorf("failed to create %s: %w", filepath.Dir(outputPath), err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "aoiler-doc-")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ctx, cancel := context.WithTimeout(parent, documentTimeout)
+	defer cancel()
+
+	switch route.Tool {
+	case "pdftoppm":
+		result.OutputPaths, err = ds.pdfToImages(ctx, inputPath, outputPath, tmpDir, opts)
+		if len(result.OutputPaths) == 1 {
+			outputPath = result.OutputPaths[0]
+			result.OutputPaths = nil
+		} else if err == nil {
+			outputPath = filepath.Dir(result.OutputPaths[0])
+		}
+	case "pdftotext":
+		err = ds.pdfToText(ctx, inputPath, outputPath, opts)
+	case "pandoc":
+		err = ds.pandoc(ctx, inputPath, inputFormat, outputPath, opts.Format)
+	case "pandoc+libreoffice":
+		html := filepath.Join(tmpDir, strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))+".html")
+		if err = ds.pandoc(ctx, inputPath, inputFormat, html, "html"); err == nil {
+			err = ds.libreoffice(ctx, html, outputPath, "pdf", tmpDir)
+		}
+	case "libreoffice":
+		err = ds.libreoffice(ctx, inputPath, outputPath, opts.Format, tmpDir)
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%s timed out after %s", route.Tool, documentTimeout)
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return result, err
+	}
+
+	result.Success = true
+	result.OutputPath = outputPath
+	if info, err := os.Stat(outputPath); err == nil && !info.IsDir() {
+		result.FileSize = info.Size()
+	}
+	return result, nil
+}
+
+// route finds the first usable route, or explains which tool to install
+func (ds *DocumentService) route(from, to string) (DocumentConversion, error) {
+	// Each alternative is the set of tools one route is missing
+	var alternatives []string
+	for _, route := range documentRoutes {
+		if !contains(route.From, from) || !contains(route.To, to) {
+			continue
+		}
+		if m := ds.missing(route); len(m) > 0 {
+			for i, tool := range m {
+				if tool == "pdf-engine" {
+					m[i] = "a PDF engine (" + strings.Join(pdfEngines, ", ") + ")"
+				}
+			}
+			alternatives = append(alternatives, strings.Join(m, " and "))
+			continue
+		}
+		return route, nil
+	}
+	if len(alternatives) > 0 {
+		return DocumentConversion{}, fmt.Errorf("converting %s to %s needs %s", from, to, strings.Join(uniqueStrings(alternatives), ", or "))
+	}
+	return DocumentConversion{}, fmt.Errorf("can't convert %s to %s", from, to)
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+func (ds *DocumentService) pandoc(ctx context.Context, input, inputFormat, output, format string) error {
+	// Pandoc writes plain text but can't read it, read it as Markdown
+	reader := pandocName(inputFormat)
+	if inputFormat == "txt" {
+		reader = "markdown"
+	}
+	args := []string{"-f", reader, "-t", pandocName(format), "-s", "-o", output, input}
+	if format == "pdf" {
+		args = []string{"-f", reader, "--pdf-engine=" + ds.tools["pdf-engine"], "-o", output, input}
+	}
+	// Images embedded in Word or EPUB files are kept next to the markup
+	if (format == "md" || format == "html") && (inputFormat == "docx" || inputFormat == "odt" || inputFormat == "epub") {
+		args = append(args, "--extract-media="+strings.TrimSuffix(output, filepath.Ext(output))+"_media")
+	}
+	return runDocumentTool(ctx, ds.tools["pandoc"], args...)
+}
+
+func pandocName(format string) string {
+	if name, ok := pandocNames[format]; ok {
+		return name
+	}
+	return format
+}
+
+// libreoffice converts into tmpDir first because it always names the output
+// after the input and would overwrite an existing file. A private profile
+// keeps it from handing the job to an already running LibreOffice window,
+// which silently ignores --headless conversions.
+func (ds *DocumentService) libreoffice(ctx context.Context, input, output, format, tmpDir string) error {
+	outDir := filepath.Join(tmpDir, "out")
+	profile := "file://" + filepath.Join(tmpDir, "profile")
+	err := runDocumentTool(ctx, ds.tools["libreoffice"], "-env:UserInstallation="+profile,
+		"--headless", "--convert-to", format, "--outdir", outDir, input)
+	if err != nil {
+		return err
+	}
+
+	produced := filepath.Join(outDir, strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))+"."+format)
+	if _, err := os.Stat(produced); err != nil {
+		return fmt.Errorf("libreoffice did not produce a %s file", format)
+	}
+	return moveFile(produced, output)
+}
+
+func (ds *DocumentService) pdfToText(ctx context.Context, input, output string, opts DocumentOptions) error {
+	args := append([]string{"-layout"}, pageArgs(opts)...)
+	return runDocumentTool(ctx, ds.tools["pdftotext"], append(args, input, output)...)
+}
+
+// pdfToImages renders pages with pdftoppm. A single page becomes outputPath,
+// several go into a "<name>_pages" folder next to it.
+func (ds *DocumentService) pdfToImages(ctx context.Context, input, outputPath, tmpDir string, opts DocumentOptions) ([]string, error) {
+	flag := map[string]string{"png": "-png", "jpg": "-jpeg", "tif": "-tiff", "tiff": "-tiff"}[opts.Format]
+	args := append([]string{"-r", strconv.Itoa(opts.DPI), flag}, pageArgs(opts)...)
+	if err := runDocumentTool(ctx, ds.tools["pdftoppm"], append(args, input, filepath.Join(tmpDir, "page"))...); err != nil {
+		return nil, err
+	}
+
+	pages, _ := filepath.Glob(filepath.Join(tmpDir, "page*"))
+	sort.Strings(pages)
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("pdftoppm produced no pages for %s", filepath.Base(input))
+	}
+	if len(pages) == 1 {
+		return []string{outputPath}, moveFile(pages[0], outputPath)
+	}
+
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	dir := base + "_pages"
+	for n := 2; ; n++ {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+		dir = base + "_pages_" + strconv.Itoa(n)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	outputs := make([]string, 0, len(pages))
+	for _, page := range pages {
+		// pdftoppm names pages "page-01.png", keep its zero padded number
+		target := filepath.Join(dir, name+strings.TrimPrefix(filepath.Base(page), "page"))
+		if err := moveFile(page, target); err != nil {
+			return outputs, err
+		}
+		outputs = append(outputs, target)
+	}
+	return outputs, nil
+}
+
+func pageArgs(opts DocumentOptions) []string {
+	if opts.FirstPage <= 0 {
+		return nil
+	}
+	return []string{"-f", strconv.Itoa(opts.FirstPage), "-l", strconv.Itoa(opts.LastPage)}
+}
+
+func runDocumentTool(ctx context.Context, tool string, args ...string) error {
+	output, err := exec.CommandContext(ctx, tool, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%s failed: %s", filepath.Base(tool), lastLine(msg))
+		}
+		return fmt.Errorf("%s failed: %w", filepath.Base(tool), err)
+	}
+	return nil
+}
+
+// moveFile renames src to dst, copying when they are on different
+// filesystems such as /tmp and $HOME
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
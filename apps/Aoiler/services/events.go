@@ -0,0 +1,25 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// EventEmitter forwards progress events to the frontend. The app wires it to
+// the Wails runtime; services must treat a nil emitter as "nobody listening".
+type EventEmitter func(name string, data interface{})
+
+func (e EventEmitter) emit(name string, data interface{}) {
+	if e != nil {
+		e(name, data)
+	}
+}
+
+// warnings receives non-fatal problems. It is stderr so that warnings never
+// end up in front of the JSON that `aoiler query --json` prints on stdout.
+var warnings io.Writer = os.Stderr
+
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(warnings, "Warning: "+format+"\n", args...)
+}
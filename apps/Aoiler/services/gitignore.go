@@ -0,0 +1,183 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// listProjectFiles returns every file under root that isn't ignored. Inside a
+// git work tree git itself answers, elsewhere .gitignore files are read while
+// walking the tree.
+func listProjectFiles(root string) ([]string, error) {
+	if files, err := gitListFiles(root); err == nil {
+		return files, nil
+	}
+	return walkWithGitignore(root)
+}
+
+func gitListFiles(root string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, rel := range bytes.Split(output, []byte{0}) {
+		if len(rel) == 0 {
+			continue
+		}
+		path := filepath.Join(root, string(rel))
+		// Deleted but still tracked files show up in --cached
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// ignoreRule is one .gitignore pattern scoped to the directory it came from
+type ignoreRule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+func walkWithGitignore(root string) ([]string, error) {
+	var files []string
+	rulesByDir := make(map[string][]ignoreRule)
+
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if path != root && isIgnored(path, d.IsDir(), rulesFor(rulesByDir, root, filepath.Dir(path))) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			rulesByDir[path] = append(rulesFor(rulesByDir, root, filepath.Dir(path)), readGitignore(path)...)
+			return nil
+		}
+
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// rulesFor returns the rules inherited by entries of dir
+func rulesFor(rulesByDir map[string][]ignoreRule, root, dir string) []ignoreRule {
+	if rules, ok := rulesByDir[dir]; ok {
+		return rules
+	}
+	if dir == root || !strings.HasPrefix(dir, root) {
+		return nil
+	}
+	return rulesFor(rulesByDir, root, filepath.Dir(dir))
+}
+
+func readGitignore(dir string) []ignoreRule {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// A slash anywhere but the end anchors the pattern to this directory
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isIgnored applies rules in order so later negations can re-include a path
+func isIgnored(path string, isDir bool, rules []ignoreRule) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if matchIgnorePattern(rule, filepath.ToSlash(rel)) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func matchIgnorePattern(rule ignoreRule, rel string) bool {
+	if !rule.anchored {
+		ok, _ := filepath.Match(rule.pattern, filepath.Base(rel))
+		return ok
+	}
+
+	if strings.Contains(rule.pattern, "**") {
+		return matchDoubleStar(strings.Split(rule.pattern, "/"), strings.Split(rel, "/"))
+	}
+	ok, _ := filepath.Match(rule.pattern, rel)
+	return ok
+}
+
+// matchDoubleStar matches path segments where "**" spans any number of them
+func matchDoubleStar(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchDoubleStar(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchDoubleStar(pattern[1:], segments[1:])
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// imageFormats can be converted without ffmpeg. WebP can only be read in
+// pure Go, writing it falls back to ImageMagick or ffmpeg.
+var imageFormats = map[string]bool{
+	"png": true, "jpg": true, "jpeg": true, "gif": true,
+	"bmp": true, "tif": true, "tiff": true, "webp": true,
+}
+
+func isImageFormat(format string) bool {
+	return imageFormats[strings.ToLower(format)]
+}
+
+// convertImageFile handles still image conversions, which are quick enough
+// to skip the job and progress machinery
+func (cs *ConverterService) convertImageFile(ctx context.Context, inputPath, outputPath string, opts ConversionOptions, preset string) (ConverterResult, error) {
+	result := ConverterResult{
+		InputFormat:  strings.TrimPrefix(filepath.Ext(inputPath), "."),
+		OutputFormat: opts.Format,
+		Preset:       preset,
+		Options:      opts,
+	}
+
+	outcome, err := convertImage(ctx, inputPath, outputPath, opts)
+	if err != nil {
+		os.Remove(outputPath)
+		return result, err
+	}
+
+	result.OutputPath = outputPath
+	result.Success = true
+	result.Width, result.Height = outcome.width, outcome.height
+	result.Tool = outcome.tool
+	if info, err := os.Stat(outputPath); err == nil {
+		result.FileSize = info.Size()
+	}
+	return result, nil
+}
+
+// imageOutcome describes how an image conversion was done
+type imageOutcome struct {
+	width, height int
+	tool          string
+}
+
+// convertImage decodes, orients, resizes and re-encodes an image in Go.
+// Re-encoding never carries EXIF or other metadata over. Formats Go can't
+// write, and animated GIFs, go to ImageMagick or ffmpeg instead.
+func convertImage(ctx context.Context, inputPath, outputPath string, opts ConversionOptions) (imageOutcome, error) {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return imageOutcome{}, err
+	}
+
+	format := strings.ToLower(opts.Format)
+	if format == "webp" || (format == "gif" && isAnimatedGIF(data)) {
+		return convertImageExternal(ctx, inputPath, outputPath, opts)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		// Let the external tools try formats Go doesn't read
+		if outcome, extErr := convertImageExternal(ctx, inputPath, outputPath, opts); extErr == nil {
+			return outcome, nil
+		}
+		return imageOutcome{}, fmt.Errorf("failed to decode %s: %w", filepath.Base(inputPath), err)
+	}
+
+	img := applyOrientation(src, jpegOrientation(data))
+	img = resizeImage(img, opts)
+
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, img, format, opts.Quality); err != nil {
+		return imageOutcome{}, err
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0o644); err != nil {
+		return imageOutcome{}, err
+	}
+
+	bounds := img.Bounds()
+	return imageOutcome{width: bounds.Dx(), height: bounds.Dy(), tool: "go"}, nil
+}
+
+func encodeImage(buf *bytes.Buffer, img image.Image, format string, quality int) error {
+	switch format {
+	case "png":
+		return (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(buf, img)
+	case "jpg", "jpeg":
+		if quality <= 0 || quality > 100 {
+			quality = 90
+		}
+		return jpeg.Encode(buf, flatten(img), &jpeg.Options{Quality: quality})
+	case "gif":
+		return gif.Encode(buf, img, &gif.Options{NumColors: 256})
+	case "bmp":
+		return bmp.Encode(buf, flatten(img))
+	case "tif", "tiff":
+		return tiff.Encode(buf, img, &tiff.Options{Compression: tiff.Deflate})
+	default:
+		return fmt.Errorf("unsupported image format: %s", format)
+	}
+}
+
+// flatten puts transparent pixels on white for formats without alpha, which
+// would otherwise turn them black
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// targetSize works out the output size from width, height or percent,
+// keeping the aspect ratio when only one side is given
+func targetSize(w, h int, opts ConversionOptions) (int, int) {
+	switch {
+	case opts.Percent > 0:
+		return w * opts.Percent / 100, h * opts.Percent / 100
+	case opts.Width > 0 && opts.Height > 0:
+		return opts.Width, opts.Height
+	case opts.Width > 0:
+		return opts.Width, h * opts.Width / w
+	case opts.Height > 0:
+		return w * opts.Height / h, opts.Height
+	}
+	return w, h
+}
+
+func resizeImage(img image.Image, opts ConversionOptions) image.Image {
+	bounds := img.Bounds()
+	w, h := targetSize(bounds.Dx(), bounds.Dy(), opts)
+	if w < 1 || h < 1 || (w == bounds.Dx() && h == bounds.Dy()) {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// convertImageExternal uses ImageMagick when available and ffmpeg otherwise
+func convertImageExternal(ctx context.Context, inputPath, outputPath string, opts ConversionOptions) (imageOutcome, error) {
+	for _, magick := range []string{"magick", "convert"} {
+		if _, err := exec.LookPath(magick); err != nil {
+			continue
+		}
+		args := []string{inputPath, "-auto-orient", "-strip"}
+		if geometry := magickGeometry(opts); geometry != "" {
+			args = append(args, "-resize", geometry)
+		}
+		if opts.Quality > 0 {
+			args = append(args, "-quality", strconv.Itoa(opts.Quality))
+		}
+		args = append(args, outputPath)
+
+		if output, err := exec.CommandContext(ctx, magick, args...).CombinedOutput(); err != nil {
+			return imageOutcome{}, fmt.Errorf("%s failed: %s", magick, strings.TrimSpace(string(output)))
+		}
+		return imageOutcome{tool: "imagemagick"}, nil
+	}
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return imageOutcome{}, fmt.Errorf("writing %s needs ImageMagick or ffmpeg", opts.Format)
+	}
+	args := []string{"-hide_banner", "-nostdin", "-i", inputPath, "-map_metadata", "-1"}
+	if scale := ffmpegImageScale(opts); scale != "" {
+		args = append(args, "-vf", scale)
+	}
+	if opts.Quality > 0 {
+		args = append(args, "-quality", strconv.Itoa(opts.Quality))
+	}
+	args = append(args, "-y", outputPath)
+
+	if output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput(); err != nil {
+		return imageOutcome{}, fmt.Errorf("ffmpeg failed: %s", lastLine(string(output)))
+	}
+	return imageOutcome{tool: "ffmpeg"}, nil
+}
+
+func magickGeometry(opts ConversionOptions) string {
+	switch {
+	case opts.Percent > 0:
+		return strconv.Itoa(opts.Percent) + "%"
+	case opts.Width > 0 && opts.Height > 0:
+		return fmt.Sprintf("%dx%d!", opts.Width, opts.Height)
+	case opts.Width > 0:
+		return strconv.Itoa(opts.Width)
+	case opts.Height > 0:
+		return "x" + strconv.Itoa(opts.Height)
+	}
+	return ""
+}
+
+func ffmpegImageScale(opts ConversionOptions) string {
+	switch {
+	case opts.Percent > 0:
+		return fmt.Sprintf("scale=iw*%d/100:-1", opts.Percent)
+	case opts.Width > 0 || opts.Height > 0:
+		w, h := opts.Width, opts.Height
+		if w == 0 {
+			w = -1
+		}
+		if h == 0 {
+			h = -1
+		}
+		return fmt.Sprintf("scale=%d:%d", w, h)
+	}
+	return ""
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
+
+// isAnimatedGIF reports whether a GIF has more than one frame
+func isAnimatedGIF(data []byte) bool {
+	return gifFrameCount(data) > 1
+}
+
+// gifFrameCount returns the number of frames in a GIF, or 0 for anything else
+func gifFrameCount(data []byte) int {
+	if !bytes.HasPrefix(data, []byte("GIF8")) {
+		return 0
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return 0
+	}
+	return len(anim.Image)
+}
+
+// jpegOrientation reads the EXIF orientation tag (1-8) from a JPEG, or 1 when
+// there is none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		// Start of scan, the metadata segments are all before it
+		if marker == 0xDA || size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation finds tag 0x0112 in the first IFD of a TIFF structure
+func exifOrientation(tiffData []byte) int {
+	if len(tiffData) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiffData[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiffData[4:]))
+	if offset+2 > len(tiffData) {
+		return 1
+	}
+	count := int(order.Uint16(tiffData[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiffData) {
+			return 1
+		}
+		if order.Uint16(tiffData[entry:]) == 0x0112 {
+			if v := int(order.Uint16(tiffData[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates and flips pixels so the image displays upright
+// without the EXIF tag
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5-8 swap width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flip horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotate 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip vertically
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
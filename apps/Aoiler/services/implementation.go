@@ -1,11 +1,16 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -29,19 +34,63 @@ type OrganizerResult struct {
 }
 
 type LinterResult struct {
-	Output      string `json:"output"`
-	Fixed       bool   `json:"fixed"`
-	FilePath    string `json:"filePath"`
-	LinterUsed  string `json:"linterUsed"`
-	ErrorCount  int    `json:"errorCount,omitempty"`
+	Output       string       `json:"output"`
+	Fixed        bool         `json:"fixed"`
+	FilePath     string       `json:"filePath"`
+	LinterUsed   string       `json:"linterUsed"`
+	ConfigUsed   string       `json:"configUsed,omitempty"`
+	Mode         string       `json:"mode"`
+	Changed      bool         `json:"changed,omitempty"`
+	Diff         string       `json:"diff,omitempty"`
+	PendingID    string       `json:"pendingId,omitempty"`
+	Error        string       `json:"error,omitempty"`
+	ErrorCount   int          `json:"errorCount,omitempty"`
+	WarningCount int          `json:"warningCount,omitempty"`
+	Diagnostics  []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Diagnostic is a single linter finding normalized across tools
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule,omitempty"`
+	Message  string `json:"message"`
 }
 
 type OCRResult struct {
-	Text       string `json:"text"`
-	Success    bool   `json:"success"`
-	Mode       string `json:"mode"`
-	WordCount  int    `json:"wordCount,omitempty"`
-	Confidence string `json:"confidence,omitempty"`
+	Text           string    `json:"text"`
+	Success        bool      `json:"success"`
+	Mode           string    `json:"mode"`
+	Source         string    `json:"source,omitempty"`
+	Language       string    `json:"language,omitempty"`
+	PSM            int       `json:"psm,omitempty"`
+	Pages          int       `json:"pages,omitempty"`
+	WordCount      int       `json:"wordCount,omitempty"`
+	Confidence     string    `json:"confidence,omitempty"`
+	MeanConfidence float64     `json:"meanConfidence,omitempty"`
+	Words          []OCRWord   `json:"words,omitempty"`
+	Entities       []OCREntity `json:"entities,omitempty"`
+	Table          string      `json:"table,omitempty"`
+	TableFormat    string      `json:"tableFormat,omitempty"`
+	Copied         bool        `json:"copied,omitempty"`
+	OpenedURL      string      `json:"openedUrl,omitempty"`
+	ActionError    string      `json:"actionError,omitempty"`
+	HistoryID      string      `json:"historyId,omitempty"`
+}
+
+// OCRWord is a single word recognized by tesseract with its bounding box
+type OCRWord struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+	Left       int     `json:"left"`
+	Top        int     `json:"top"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Block      int     `json:"block"`
+	Paragraph  int     `json:"paragraph"`
+	Line       int     `json:"line"`
 }
 
 type ConverterResult struct {
@@ -50,6 +99,15 @@ type ConverterResult struct {
 	InputFormat string `json:"inputFormat"`
 	OutputFormat string `json:"outputFormat"`
 	FileSize    int64  `json:"fileSize,omitempty"`
+	JobID       string  `json:"jobId,omitempty"`
+	Duration    float64 `json:"duration,omitempty"`
+	Preset      string            `json:"preset,omitempty"`
+	Options     ConversionOptions `json:"options"`
+	Width       int               `json:"width,omitempty"`
+	Height      int               `json:"height,omitempty"`
+	Tool        string            `json:"tool,omitempty"`
+	Source      *MediaInfo        `json:"source,omitempty"`
+	Output      *MediaInfo        `json:"output,omitempty"`
 }
 
 type AutoCompleteResult struct {
@@ -72,7 +130,7 @@ func NewFileSearchService() *FileSearchService {
 }
 
 // Search with scoring and multiple results
-func (fs *FileSearchService) Search(query string) (FileSearchResult, error) {
+func (fs *FileSearchService) Search(ctx context.Context, query string) (FileSearchResult, error) {
 	searchTerms := extractSearchTerms(query)
 
 	homeDir, _ := os.UserHomeDir()
@@ -81,7 +139,10 @@ func (fs *FileSearchService) Search(query string) (FileSearchResult, error) {
 	var foundPath string
 	var bestScore int
 
-	filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
 			return nil
 		}
@@ -118,6 +179,9 @@ func (fs *FileSearchService) Search(query string) (FileSearchResult, error) {
 
 		return nil
 	})
+	if ctx.Err() != nil {
+		return FileSearchResult{Found: false}, err
+	}
 
 	if foundPath != "" {
 		fileType := "file"
@@ -132,6 +196,11 @@ func (fs *FileSearchService) Search(query string) (FileSearchResult, error) {
 		}, nil
 	}
 
+	// Scans read with "ocr <folder>" are found by the text in their sidecars
+	if result, ok := searchOCRArchives(ctx, searchTerms); ok {
+		return result, nil
+	}
+
 	return FileSearchResult{Found: false}, fmt.Errorf("file not found")
 }
 
@@ -224,7 +293,7 @@ func NewOrganizerService() *OrganizerService {
 	return &OrganizerService{}
 }
 
-func (o *OrganizerService) Organize(query, mode string) (OrganizerResult, error) {
+func (o *OrganizerService) Organize(ctx context.Context, query, mode string) (OrganizerResult, error) {
 	path := extractPath(query)
 	homeDir, _ := os.UserHomeDir()
 	if path == "" {
@@ -237,10 +306,10 @@ func (o *OrganizerService) Organize(query, mode string) (OrganizerResult, error)
 
 	var cmd *exec.Cmd
 	if mode == "filename" {
-		cmd = exec.Command("tyr", "-f", "-nui", path)
+		cmd = exec.CommandContext(ctx, "tyr", "-f", "-nui", path)
 		cmd.Env = append(os.Environ(), "PATH="+os.Getenv("PATH")+":"+filepath.Join(homeDir, ".local/bin"))
 	} else {
-		cmd = exec.Command("tyr", "-c", "-nui", path)
+		cmd = exec.CommandContext(ctx, "tyr", "-c", "-nui", path)
 		cmd.Env = append(os.Environ(), "PATH="+os.Getenv("PATH")+":"+filepath.Join(homeDir, ".local/bin"))
 	}
 
@@ -264,14 +333,32 @@ func (o *OrganizerService) GetPathSuggestions(input string) (AutoCompleteResult,
 }
 
 // LinterService with better error reporting
-type LinterService struct{}
+type LinterService struct {
+	registry *LinterRegistry
+	workers  int
+	emitter  EventEmitter
+	mu       sync.Mutex
+	pending  map[string]pendingFormat
+}
+
+func NewLinterService(languages []LanguageSpec, workers int) *LinterService {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &LinterService{
+		registry: NewLinterRegistry(languages),
+		workers:  workers,
+		pending:  make(map[string]pendingFormat),
+	}
+}
 
-func NewLinterService() *LinterService {
-	return &LinterService{}
+func (ls *LinterService) LintFormat(ctx context.Context, query string) (LinterResult, error) {
+	return ls.Run(ctx, query, "format")
 }
 
-func (ls *LinterService) LintFormat(query string) (LinterResult, error) {
-	filePath := extractPath(query)
+// Run lints or formats the file referenced by query depending on mode
+func (ls *LinterService) Run(ctx context.Context, query, mode string) (LinterResult, error) {
+	filePath := expandHome(extractPath(query))
 	if filePath == "" {
 		return LinterResult{}, fmt.Errorf("no file path found in query")
 	}
@@ -281,40 +368,11 @@ func (ls *LinterService) LintFormat(query string) (LinterResult, error) {
 		return LinterResult{}, fmt.Errorf("file does not exist: %s", filePath)
 	}
 
-	ext := strings.ToLower(filepath.Ext(filePath))
-
-	var cmd *exec.Cmd
-	var linterName string
-
-	switch ext {
-	case ".py":
-		cmd = exec.Command("black", filePath)
-		linterName = "black"
-	case ".go":
-		cmd = exec.Command("gofmt", "-w", filePath)
-		linterName = "gofmt"
-	case ".sh":
-		cmd = exec.Command("shfmt", "-w", filePath)
-		linterName = "shfmt"
-	case ".js", ".ts", ".jsx", ".tsx":
-		cmd = exec.Command("prettier", "--write", filePath)
-		linterName = "prettier"
-	default:
-		return LinterResult{}, fmt.Errorf("unsupported file type: %s", ext)
+	if mode == "check" {
+		return ls.Check(ctx, filePath)
 	}
 
-	output, err := cmd.CombinedOutput()
-
-	// Count errors (rough heuristic)
-	errorCount := strings.Count(strings.ToLower(string(output)), "error")
-
-	return LinterResult{
-		Output:     string(output),
-		Fixed:      err == nil,
-		FilePath:   filePath,
-		LinterUsed: linterName,
-		ErrorCount: errorCount,
-	}, err
+	return ls.Preview(ctx, filePath)
 }
 
 func (ls *LinterService) GetPathSuggestions(input string) (AutoCompleteResult, error) {
@@ -325,15 +383,9 @@ func (ls *LinterService) GetPathSuggestions(input string) (AutoCompleteResult, e
 		return result, err
 	}
 
-	supportedExts := map[string]bool{
-		".py": true, ".go": true, ".sh": true,
-		".js": true, ".ts": true, ".jsx": true, ".tsx": true,
-	}
-
 	var filtered []string
 	for _, path := range result.Suggestions {
-		ext := strings.ToLower(filepath.Ext(path))
-		if strings.HasSuffix(path, "/") || supportedExts[ext] {
+		if strings.HasSuffix(path, "/") || ls.registry.Supports(path) {
 			filtered = append(filtered, path)
 		}
 	}
@@ -343,115 +395,129 @@ func (ls *LinterService) GetPathSuggestions(input string) (AutoCompleteResult, e
 	return result, nil
 }
 
-// OCRService with confidence estimation
+// OCRService runs tesseract on screen captures or image files
 type OCRService struct {
-	scriptPath string
+	defaults OCROptions
+	history  *OCRHistory
+	emitter  EventEmitter
+
+	// langs caches `tesseract --list-langs`, see checkLanguages
+	langsMu sync.Mutex
+	langs   map[string]bool
 }
 
-func NewOCRService() *OCRService {
-	homeDir, _ := os.UserHomeDir()
-	scriptPath := filepath.Join(homeDir, ".config/hecate/scripts/ocr-capture.sh")
-	if _, err := os.Stat(scriptPath); err == nil {
-		return &OCRService{scriptPath: scriptPath}
+func NewOCRService(defaults OCROptions, history OCRHistoryOptions) *OCRService {
+	if defaults.Language == "" {
+		defaults.Language = "eng"
+	}
+	if defaults.PSM == 0 {
+		defaults.PSM = 3
 	}
-	return &OCRService{}
+	// tesseract already uses several threads per image
+	if defaults.Workers <= 0 {
+		defaults.Workers = runtime.NumCPU() / 2
+		if defaults.Workers < 1 {
+			defaults.Workers = 1
+		}
+	}
+	return &OCRService{defaults: defaults, history: NewOCRHistory(history)}
 }
 
-func (ocr *OCRService) ExtractText() (OCRResult, error) {
-	text, err := ocr.runOCR("", true)
-
+// ExtractText lets the user select a screen area with slurp and runs OCR on it
+func (ocr *OCRService) ExtractText(ctx context.Context, opts OCROptions) (OCRResult, error) {
+	imagePath, err := captureScreenArea(ctx)
 	if err != nil {
-		return OCRResult{
-			Text:    text,
-			Success: false,
-			Mode:    "screen",
-		}, err
+		return OCRResult{Success: false, Mode: "screen"}, err
 	}
+	defer os.Remove(imagePath)
 
-	return OCRResult{
-		Text:       strings.TrimSpace(text),
-		Success:    true,
-		Mode:       "screen",
-		WordCount:  len(strings.Fields(text)),
-		Confidence: estimateConfidence(text),
-	}, nil
-}
-
-func (ocr *OCRService) ExtractTextFromFile(imagePath string) (OCRResult, error) {
-	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-		return OCRResult{Success: false}, fmt.Errorf("image file not found: %s", imagePath)
+	result, err := ocr.recognize(ctx, imagePath, "screen", opts)
+	if err == nil {
+		ocr.record(&result, imagePath)
 	}
+	return result, err
+}
 
-	text, err := ocr.runOCR(imagePath, false)
-
+// record saves a capture to the history; a failure there shouldn't lose the text
+func (ocr *OCRService) record(result *OCRResult, imagePath string) {
+	id, err := ocr.history.Add(*result, imagePath)
 	if err != nil {
-		return OCRResult{
-			Text:    text,
-			Success: false,
-			Mode:    "file",
-		}, err
+		warnf("could not save OCR history: %v", err)
+		return
 	}
-
-	return OCRResult{
-		Text:       strings.TrimSpace(text),
-		Success:    true,
-		Mode:       "file",
-		WordCount:  len(strings.Fields(text)),
-		Confidence: estimateConfidence(text),
-	}, nil
+	result.HistoryID = id
 }
 
-func (ocr *OCRService) runOCR(imagePath string, screenCapture bool) (string, error) {
-	var cmd *exec.Cmd
-
-	if screenCapture {
-		if ocr.scriptPath != "" {
-			cmd = exec.Command(ocr.scriptPath, "-au")
-		} else {
-			// Use inline script
-			return ocr.runInlineOCRScript()
-		}
-	} else {
-		cmd = exec.Command("tesseract", imagePath, "stdout")
-	}
-
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+// SearchHistory finds past captures containing all of the given words
+func (ocr *OCRService) SearchHistory(terms string) (OCRHistoryResult, error) {
+	return ocr.history.Search(terms)
 }
 
-func (ocr *OCRService) runInlineOCRScript() (string, error) {
-	scriptPath := "/tmp/ocr_capture.sh"
-	script := `#!/bin/bash
-set -e
-TMPFILE="/tmp/ocr_screenshot_$(date +%s).png"
-LANG="eng"
+// DeleteHistory removes a stored capture
+func (ocr *OCRService) DeleteHistory(id string) (bool, error) {
+	return ocr.history.Delete(id)
+}
 
-if ! grim -g "$(slurp)" "$TMPFILE" 2>/dev/null; then
-    echo "Screenshot cancelled or failed" >&2
-    exit 1
-fi
+func (ocr *OCRService) ExtractTextFromFile(ctx context.Context, imagePath string, opts OCROptions) (OCRResult, error) {
+	return ocr.readFile(ctx, imagePath, opts, true)
+}
 
-OCR_OUTPUT=$(tesseract "$TMPFILE" stdout -l "$LANG" 2>/dev/null)
-rm -f "$TMPFILE"
+// readFile reads an image or PDF, saving it to the history when record is
+// set. Batches don't, their text is kept in sidecars next to the scans.
+func (ocr *OCRService) readFile(ctx context.Context, imagePath string, opts OCROptions, record bool) (OCRResult, error) {
+	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
+		return OCRResult{Success: false}, fmt.Errorf("image file not found: %s", imagePath)
+	}
 
-if [ -z "$OCR_OUTPUT" ]; then
-    echo "No text detected" >&2
-    exit 1
-fi
+	if isPDF(imagePath) {
+		return ocr.recognizePDF(ctx, imagePath, opts, record)
+	}
 
-echo "$OCR_OUTPUT"
-exit 0
-`
+	result, err := ocr.recognize(ctx, imagePath, "file", opts)
+	result.Source = imagePath
+	result.Pages = 1
+	if err == nil && record {
+		ocr.record(&result, imagePath)
+	}
+	return result, err
+}
 
-	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
-		return "", fmt.Errorf("failed to create OCR script: %w", err)
+func (ocr *OCRService) recognize(ctx context.Context, imagePath, mode string, opts OCROptions) (OCRResult, error) {
+	if opts.Language == "" {
+		opts.Language = ocr.defaults.Language
+	}
+	if opts.PSM == 0 {
+		opts.PSM = ocr.defaults.PSM
 	}
-	defer os.Remove(scriptPath)
 
-	cmd := exec.Command("bash", scriptPath, "-au")
-	output, err := cmd.CombinedOutput()
+	err := ocr.checkLanguages(opts.Language)
+	var text string
+	var words []OCRWord
+	if err == nil {
+		text, words, err = runTesseract(ctx, imagePath, opts)
+	}
+	if err != nil {
+		return OCRResult{
+			Success:  false,
+			Mode:     mode,
+			Language: opts.Language,
+			PSM:      opts.PSM,
+		}, err
+	}
 
-	return string(output), err
+	mean := meanConfidence(words)
+	return OCRResult{
+		Text:           strings.TrimSpace(text),
+		Success:        true,
+		Mode:           mode,
+		Language:       opts.Language,
+		PSM:            opts.PSM,
+		WordCount:      len(strings.Fields(text)),
+		Confidence:     confidenceLabel(mean, len(words)),
+		MeanConfidence: mean,
+		Words:          words,
+		Entities:       extractEntities(text),
+	}, nil
 }
 
 func (ocr *OCRService) GetPathSuggestions(input string) (AutoCompleteResult, error) {
@@ -482,62 +548,136 @@ func (ocr *OCRService) GetPathSuggestions(input string) (AutoCompleteResult, err
 }
 
 // ConverterService with format detection
-type ConverterService struct{}
+type ConverterService struct {
+	emitter EventEmitter
+	running conversionJobs
+	presets []ConversionPreset
+	workers int
+}
 
-func NewConverterService() *ConverterService {
-	return &ConverterService{}
+func NewConverterService(cfg ConverterConfig) *ConverterService {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 2
+	}
+	return &ConverterService{presets: mergePresets(cfg.Presets), workers: workers}
 }
 
-func (cs *ConverterService) Convert(query string) (ConverterResult, error) {
-	inputPath := extractPath(query)
+// Convert understands queries like "convert clip.mkv to gif 480p first 10
+// seconds" or "compress ~/video.mp4 for discord under 25MB"
+func (cs *ConverterService) Convert(ctx context.Context, query string) (ConverterResult, error) {
+	_, rest := parseOutputDir(query)
+	inputPath := expandHome(extractMediaPath(rest))
 	if inputPath == "" {
 		return ConverterResult{}, fmt.Errorf("no input file found")
 	}
 
-	targetFormat := extractFormat(query)
-	if targetFormat == "" {
-		return ConverterResult{}, fmt.Errorf("no target format specified")
+	opts, preset := parseConversionOptions(query, cs.presets)
+	if opts.Format == "" {
+		// Shrinking or resizing a file keeps its format
+		if opts.TargetSizeMB == 0 && !opts.resizes() && !strings.Contains(strings.ToLower(query), "compress") {
+			return ConverterResult{}, fmt.Errorf("no target format specified")
+		}
+		opts.Format = strings.ToLower(strings.TrimPrefix(filepath.Ext(inputPath), "."))
 	}
 
-	return cs.ConvertWithFormat(inputPath, targetFormat)
+	return cs.ConvertWithOptions(ctx, inputPath, opts, preset)
+}
+
+func (cs *ConverterService) ConvertWithFormat(ctx context.Context, inputPath, targetFormat string) (ConverterResult, error) {
+	return cs.ConvertWithOptions(ctx, inputPath, ConversionOptions{Format: targetFormat}, "")
 }
 
-func (cs *ConverterService) ConvertWithFormat(inputPath, targetFormat string) (ConverterResult, error) {
+// ConvertWithOptions runs the conversion as a cancellable job, streaming
+// progress events while ffmpeg works
+func (cs *ConverterService) ConvertWithOptions(ctx context.Context, inputPath string, opts ConversionOptions, preset string) (ConverterResult, error) {
 	// Verify input file exists
 	_, err := os.Stat(inputPath)
 	if os.IsNotExist(err) {
 		return ConverterResult{}, fmt.Errorf("input file does not exist: %s", inputPath)
 	}
 
+	outputPath, exists := outputPathFor(inputPath, filepath.Base(inputPath), opts, nil)
+	if exists {
+		return ConverterResult{OutputPath: outputPath, Options: opts, Preset: preset}, fmt.Errorf("%w: %s", errOutputExists, outputPath)
+	}
+	return cs.convertFile(ctx, inputPath, outputPath, opts, preset)
+}
+
+// convertFile converts one file to outputPath. Cancelling ctx stops ffmpeg.
+func (cs *ConverterService) convertFile(ctx context.Context, inputPath, outputPath string, opts ConversionOptions, preset string) (ConverterResult, error) {
+	targetFormat := opts.Format
 	inputFormat := strings.TrimPrefix(filepath.Ext(inputPath), ".")
-	outputPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + "." + targetFormat
 
-	// Check if output already exists
-	if _, err := os.Stat(outputPath); err == nil {
-		outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) +
-		            "_converted." + targetFormat
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return ConverterResult{InputFormat: inputFormat, OutputFormat: targetFormat, Preset: preset, Options: opts},
+			fmt.Errorf("failed to create %s: %w", filepath.Dir(outputPath), err)
+	}
+
+	// Catch impossible targets before starting anything. Without ffprobe we
+	// skip the checks and let ffmpeg report what it can't do.
+	var source *MediaInfo
+	if info, err := probeMedia(ctx, inputPath); err == nil {
+		source = &info
+		if err := validateConversion(info, opts); err != nil {
+			return ConverterResult{
+				Success:      false,
+				InputFormat:  inputFormat,
+				OutputFormat: targetFormat,
+				Preset:       preset,
+				Options:      opts,
+				Source:       source,
+			}, err
+		}
 	}
 
-	cmd := exec.Command("ffmpeg", "-i", inputPath, "-y", outputPath)
-	output, err := cmd.CombinedOutput()
+	if isImageFormat(inputFormat) && isImageFormat(targetFormat) {
+		result, err := cs.convertImageFile(ctx, inputPath, outputPath, opts, preset)
+		result.Source = source
+		return result, err
+	}
+
+	// Without a duration we can still show speed, just not percent or ETA.
+	// A single frame grab has nothing to measure.
+	duration := 0.0
+	if source != nil && !isImageFormat(targetFormat) {
+		duration = opts.effectiveDuration(source.Duration)
+	}
+
+	job := cs.startJob(ctx, inputPath, outputPath, duration)
+	opts, err := cs.encode(job, opts)
+	cs.finishJob(job, err)
 
 	if err != nil {
 		return ConverterResult{
-			Success: false,
-			InputFormat: inputFormat,
+			Success:      false,
+			InputFormat:  inputFormat,
 			OutputFormat: targetFormat,
-		}, fmt.Errorf("conversion failed: %s", string(output))
+			JobID:        job.id,
+			Preset:       preset,
+			Options:      opts,
+			Source:       source,
+		}, err
 	}
 
 	outputInfo, _ := os.Stat(outputPath)
 
-	return ConverterResult{
+	result := ConverterResult{
 		OutputPath:   outputPath,
 		Success:      true,
 		InputFormat:  inputFormat,
 		OutputFormat: targetFormat,
 		FileSize:     outputInfo.Size(),
-	}, nil
+		JobID:        job.id,
+		Duration:     job.duration,
+		Preset:       preset,
+		Options:      opts,
+		Source:       source,
+	}
+	if info, err := probeMedia(ctx, outputPath); err == nil {
+		result.Output = &info
+	}
+	return result, nil
 }
 
 func (cs *ConverterService) GetPathSuggestions(input string) (AutoCompleteResult, error) {
@@ -552,6 +692,7 @@ func (cs *ConverterService) GetPathSuggestions(input string) (AutoCompleteResult
 		".mp4": true, ".webm": true, ".avi": true, ".mkv": true, ".mov": true,
 		".mp3": true, ".wav": true, ".flac": true, ".ogg": true, ".m4a": true,
 		".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+		".bmp": true, ".tif": true, ".tiff": true,
 	}
 
 	var filtered []string
@@ -568,6 +709,14 @@ func (cs *ConverterService) GetPathSuggestions(input string) (AutoCompleteResult
 }
 
 // Helper functions
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
 func extractPath(query string) string {
 	words := strings.Fields(query)
 	for _, word := range words {
@@ -582,6 +731,48 @@ func extractPath(query string) string {
 	return ""
 }
 
+// stripPaths removes path-like words so keyword matching ignores file names
+func stripPaths(query string) string {
+	var kept []string
+	for _, word := range strings.Fields(query) {
+		clean := strings.Trim(word, "\"'")
+		if strings.Contains(clean, "/") || strings.HasPrefix(clean, "~") {
+			continue
+		}
+		kept = append(kept, word)
+	}
+	return strings.Join(kept, " ")
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[1:])
+	}
+	return path
+}
+
+func isDirectory(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// uniquePath adds _2, _3 and so on before the extension until path is free
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s_%d%s", base, n, ext)
+	}
+}
+
 func extractPathFromInput(input string) string {
 	if strings.HasPrefix(input, "/") || strings.HasPrefix(input, "~") ||
 	   strings.HasPrefix(input, "./") || strings.HasPrefix(input, "../") {
@@ -600,24 +791,6 @@ func extractPathFromInput(input string) string {
 	return ""
 }
 
-func extractFormat(query string) string {
-	formats := []string{
-		"mp4", "webm", "avi", "mkv", "mov",
-		"mp3", "wav", "flac", "ogg", "m4a",
-		"png", "jpg", "jpeg", "gif", "webp",
-	}
-	lowerQuery := strings.ToLower(query)
-
-	for _, format := range formats {
-		if strings.Contains(lowerQuery, " "+format) ||
-		   strings.Contains(lowerQuery, "."+format) ||
-		   strings.HasSuffix(lowerQuery, format) {
-			return format
-		}
-	}
-	return ""
-}
-
 func extractSearchTerms(query string) []string {
 	commonWords := map[string]bool{
 		"where": true, "is": true, "my": true, "the": true, "a": true,
@@ -639,28 +812,6 @@ func extractSearchTerms(query string) []string {
 	return terms
 }
 
-// estimateConfidence provides a rough quality estimate of OCR output
-func estimateConfidence(text string) string {
-	if len(text) == 0 {
-		return "none"
-	}
-
-	// Simple heuristics
-	words := strings.Fields(text)
-	specialChars := strings.Count(text, "�") + strings.Count(text, "□")
-	wordCount := len(words)
-
-	if specialChars > wordCount/4 {
-		return "low"
-	}
-
-	if wordCount < 3 {
-		return "medium"
-	}
-
-	return "high"
-}
-
 func min(a, b int) int {
 	if a < b {
 		return a
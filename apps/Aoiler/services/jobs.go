@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job states as reported to the UI
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+	JobTimedOut  = "timedout"
+)
+
+// errJobNotFound is returned for unknown or already pruned job IDs
+var errJobNotFound = errors.New("job not found")
+
+// JobsConfig sets per-service timeouts and how many finished jobs are kept
+type JobsConfig struct {
+	// TimeoutSeconds overrides the default timeout by service name, e.g.
+	// {"converter": 7200}. Zero or negative disables the timeout.
+	TimeoutSeconds map[string]int `json:"timeoutSeconds,omitempty"`
+	// MaxFinished caps finished jobs kept for the jobs panel (default 100)
+	MaxFinished int `json:"maxFinished,omitempty"`
+}
+
+// defaultJobTimeouts bound every service so a hung tool can't run forever.
+// Conversions and directory runs get the most room. A pipeline's stages each
+// get their own service's timeout, and the pipeline entry bounds them all.
+var defaultJobTimeouts = map[string]time.Duration{
+	"filesearch": time.Minute,
+	"organizer":  10 * time.Minute,
+	"linter":     10 * time.Minute,
+	"ocr":        15 * time.Minute,
+	"converter":  2 * time.Hour,
+	"document":   10 * time.Minute,
+	"clipboard":  30 * time.Second,
+	"calculator": 10 * time.Second,
+	"launcher":   30 * time.Second,
+	"desktop":    time.Minute,
+	"screenshot": time.Hour,
+	"notes":      30 * time.Second,
+	"llm":        2 * time.Minute,
+	"pipeline":   3 * time.Hour,
+}
+
+// JobInfo is the state of one service execution, emitted as "job:update"
+// whenever it changes
+type JobInfo struct {
+	ID      string `json:"id"`
+	Query   string `json:"query"`
+	Service string `json:"service"`
+	// Params are what the query was classified into, e.g. the linter mode
+	Params map[string]string `json:"params,omitempty"`
+	Status string            `json:"status"`
+	// Progress runs from 0 to 1, or stays at -1 when the service can't tell
+	Progress   float64     `json:"progress"`
+	Message    string      `json:"message,omitempty"`
+	StartedAt  time.Time   `json:"startedAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+	TimeoutMs  int64       `json:"timeoutMs,omitempty"`
+	DurationMs int64       `json:"durationMs"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Done reports whether the job has stopped for any reason
+func (j JobInfo) Done() bool {
+	return j.Status != JobRunning
+}
+
+type job struct {
+	info   JobInfo
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// JobManager runs service calls in the background so several queries can
+// run at once, each with its own timeout and cancel
+type JobManager struct {
+	mu          sync.Mutex
+	jobs        map[string]*job
+	order       []string
+	timeouts    map[string]time.Duration
+	maxFinished int
+	emitter     EventEmitter
+	// onFinish sees every job once it stops, result included
+	onFinish func(JobInfo)
+}
+
+// NewJobManager creates a job manager with the default timeouts overridden
+// by cfg
+func NewJobManager(cfg JobsConfig) *JobManager {
+	timeouts := make(map[string]time.Duration, len(defaultJobTimeouts))
+	for name, timeout := range defaultJobTimeouts {
+		timeouts[name] = timeout
+	}
+	for name, seconds := range cfg.TimeoutSeconds {
+		timeouts[name] = time.Duration(seconds) * time.Second
+	}
+	if cfg.MaxFinished <= 0 {
+		cfg.MaxFinished = 100
+	}
+	return &JobManager{
+		jobs:        make(map[string]*job),
+		timeouts:    timeouts,
+		maxFinished: cfg.MaxFinished,
+	}
+}
+
+// Start runs fn in the background and returns the new job right away. fn
+// must stop when its context is done.
+func (jm *JobManager) Start(service, query string, params map[string]string, fn func(ctx context.Context) (interface{}, error)) JobInfo {
+	ctx, cancel := context.WithCancel(context.Background())
+	timeout := jm.timeouts[service]
+	if timeout > 0 {
+		ctx, cancel = withTimeout(ctx, cancel, timeout)
+	}
+
+	j := &job{
+		info: JobInfo{
+			ID:        newID(),
+			Query:     query,
+			Service:   service,
+			Params:    params,
+			Status:    JobRunning,
+			Progress:  -1,
+			StartedAt: time.Now(),
+			TimeoutMs: timeout.Milliseconds(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	jm.mu.Lock()
+	jm.jobs[j.info.ID] = j
+	jm.order = append(jm.order, j.info.ID)
+	info := j.info
+	jm.mu.Unlock()
+	jm.emitter.emit("job:update", info)
+
+	ctx = withProgress(ctx, func(progress float64, message string) {
+		jm.update(j, progress, message)
+	})
+
+	go func() {
+		result, err := run(ctx, fn)
+		jm.finish(j, ctx, result, err)
+	}()
+	return info
+}
+
+// withTimeout layers a deadline on ctx while keeping a single cancel func
+func withTimeout(ctx context.Context, cancel context.CancelFunc, timeout time.Duration) (context.Context, context.CancelFunc) {
+	timed, cancelTimer := context.WithTimeout(ctx, timeout)
+	return timed, func() {
+		cancelTimer()
+		cancel()
+	}
+}
+
+// run calls fn and turns a panic into an error, so one broken service call
+// can't take down the app
+func run(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func (jm *JobManager) update(j *job, progress float64, message string) {
+	jm.mu.Lock()
+	if j.info.Done() {
+		jm.mu.Unlock()
+		return
+	}
+	if progress >= 0 {
+		j.info.Progress = progress
+	}
+	if message != "" {
+		j.info.Message = message
+	}
+	j.info.DurationMs = time.Since(j.info.StartedAt).Milliseconds()
+	info := j.info
+	jm.mu.Unlock()
+	jm.emitter.emit("job:update", info)
+}
+
+func (jm *JobManager) finish(j *job, ctx context.Context, result interface{}, err error) {
+	now := time.Now()
+
+	jm.mu.Lock()
+	j.info.FinishedAt = &now
+	j.info.DurationMs = now.Sub(j.info.StartedAt).Milliseconds()
+	j.info.Result = result
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		j.info.Status = JobTimedOut
+		j.info.Error = fmt.Sprintf("timed out after %s", time.Duration(j.info.TimeoutMs)*time.Millisecond)
+	case ctx.Err() != nil:
+		j.info.Status = JobCancelled
+		j.info.Error = "cancelled"
+	case err != nil:
+		j.info.Status = JobFailed
+		j.info.Error = err.Error()
+	default:
+		j.info.Status = JobSucceeded
+		j.info.Progress = 1
+	}
+	info := j.info
+	jm.pruneLocked()
+	jm.mu.Unlock()
+
+	// Recorded before anyone waiting is released, so a CLI that exits right
+	// after still leaves its query in the history
+	if jm.onFinish != nil {
+		jm.onFinish(info)
+	}
+	j.cancel()
+	close(j.done)
+	jm.emitter.emit("job:update", info)
+}
+
+// pruneLocked forgets the oldest finished jobs beyond maxFinished
+func (jm *JobManager) pruneLocked() {
+	finished := 0
+	for i := len(jm.order) - 1; i >= 0; i-- {
+		if jm.jobs[jm.order[i]].info.Done() {
+			finished++
+		}
+	}
+
+	kept := jm.order[:0]
+	for _, id := range jm.order {
+		if finished > jm.maxFinished && jm.jobs[id].info.Done() {
+			delete(jm.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	jm.order = kept
+}
+
+// Get returns a job, including its result once it has finished
+func (jm *JobManager) Get(id string) (JobInfo, error) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	j, ok := jm.jobs[id]
+	if !ok {
+		return JobInfo{}, errJobNotFound
+	}
+	info := j.info
+	if !info.Done() {
+		info.DurationMs = time.Since(info.StartedAt).Milliseconds()
+	}
+	return info, nil
+}
+
+// List returns all known jobs, newest first, without their results
+func (jm *JobManager) List() []JobInfo {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jobs := make([]JobInfo, 0, len(jm.order))
+	for _, id := range jm.order {
+		info := jm.jobs[id].info
+		info.Result = nil
+		if !info.Done() {
+			info.DurationMs = time.Since(info.StartedAt).Milliseconds()
+		}
+		jobs = append(jobs, info)
+	}
+	sort.SliceStable(jobs, func(i, k int) bool { return jobs[i].StartedAt.After(jobs[k].StartedAt) })
+	return jobs
+}
+
+// Cancel stops a running job. It returns false for unknown or finished jobs.
+func (jm *JobManager) Cancel(id string) bool {
+	jm.mu.Lock()
+	j, ok := jm.jobs[id]
+	running := ok && !j.info.Done()
+	jm.mu.Unlock()
+	if running {
+		j.cancel()
+	}
+	return running
+}
+
+// Wait blocks until a job finishes or ctx is done
+func (jm *JobManager) Wait(ctx context.Context, id string) (JobInfo, error) {
+	jm.mu.Lock()
+	j, ok := jm.jobs[id]
+	jm.mu.Unlock()
+	if !ok {
+		return JobInfo{}, errJobNotFound
+	}
+
+	select {
+	case <-j.done:
+		// Read the job itself, it may already have been pruned from the list
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+		return j.info, nil
+	case <-ctx.Done():
+		return JobInfo{}, ctx.Err()
+	}
+}
+
+// progressKey carries a job's progress callback through a service call
+type progressKey struct{}
+
+type progressFunc func(progress float64, message string)
+
+func withProgress(ctx context.Context, report progressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// reportProgress updates the job running ctx, if any. Pass a negative
+// progress to change only the message.
+func reportProgress(ctx context.Context, progress float64, message string) {
+	if report, ok := ctx.Value(progressKey{}).(progressFunc); ok {
+		report(progress, message)
+	}
+}
@@ -0,0 +1,686 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	launcherResultLimit = 8
+	// launcherIconLimit skips icons too large to inline in a result
+	launcherIconLimit = 512 * 1024
+)
+
+// launcherPattern matches "open gimp" and "launch firefox private window",
+// capturing what to launch
+var launcherPattern = regexp.MustCompile(`(?i)^\s*(?:open|launch|start|run)\s+(.+)$`)
+
+// launcherFillerWords are dropped from a search so "open the terminal" works
+var launcherFillerWords = map[string]bool{"the": true, "a": true, "an": true, "my": true, "app": true, "application": true}
+
+// launcherFieldCodes are the Exec placeholders for files, URLs and the
+// desktop file itself, which a launch from a query never has
+var launcherFieldCodes = regexp.MustCompile(`%[fFuUdDnNickvm]`)
+
+// iconSizes are tried in order when looking up a themed icon
+var iconSizes = []string{"scalable", "256x256", "128x128", "96x96", "64x64", "48x48", "32x32"}
+
+// LauncherConfig sets how apps are found and started
+type LauncherConfig struct {
+	// Terminal runs apps marked Terminal=true (default "kitty -e")
+	Terminal string `json:"terminal,omitempty"`
+	// QuickApps is a file of Name=command lines merged into the app list
+	// (default ~/.config/hecate/quickapps.conf)
+	QuickApps string `json:"quickApps,omitempty"`
+}
+
+// AppEntry is an application or one of its desktop actions
+type AppEntry struct {
+	// ID is the desktop file ID, "firefox.desktop#new-private-window" for an
+	// action and "quickapps:Name" for quick apps
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Action      string   `json:"action,omitempty"`
+	GenericName string   `json:"genericName,omitempty"`
+	Comment     string   `json:"comment,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	// Aliases are quick app names that start the same program
+	Aliases []string `json:"aliases,omitempty"`
+	// Icon is a data URL the webview can show directly
+	Icon     string `json:"icon,omitempty"`
+	Exec     string `json:"exec"`
+	Source   string `json:"source"`
+	Launches int    `json:"launches"`
+
+	iconName string
+	terminal bool
+	// primary holds the words of the name, aliases and action, secondary
+	// those of the generic name, keywords, program and desktop file ID
+	primary   []string
+	secondary []string
+	action    []string
+}
+
+// LauncherResult lists the apps matching a query, the best one launched
+type LauncherResult struct {
+	Query string     `json:"query"`
+	Apps  []AppEntry `json:"apps"`
+	// Launched is the ID of the app that was started
+	Launched string `json:"launched,omitempty"`
+}
+
+// LauncherService starts applications from XDG .desktop files and the quick
+// apps list, ranking matches by how often each one was launched
+type LauncherService struct {
+	cfg       LauncherConfig
+	statsPath string
+	mu        sync.Mutex
+	apps      []AppEntry
+	// stamp records the modification times the app list was read at
+	stamp       string
+	statsLoaded bool
+	launches    map[string]int
+	icons       map[string]string
+}
+
+func NewLauncherService(cfg LauncherConfig) *LauncherService {
+	if cfg.Terminal == "" {
+		cfg.Terminal = "kitty -e"
+	}
+	if cfg.QuickApps == "" {
+		homeDir, _ := os.UserHomeDir()
+		cfg.QuickApps = filepath.Join(homeDir, ".config", "hecate", "quickapps.conf")
+	}
+	return &LauncherService{
+		cfg:       cfg,
+		statsPath: filepath.Join(aoilerDataDir(), "launcher.json"),
+		launches:  make(map[string]int),
+		icons:     make(map[string]string),
+	}
+}
+
+// Available reports whether apps can be started through Hyprland
+func (ls *LauncherService) Available() bool {
+	_, err := exec.LookPath("hyprctl")
+	return err == nil
+}
+
+// Handles reports whether a query names an installed app, so "open
+// ~/report.pdf" or "run the tests" are left to other services
+func (ls *LauncherService) Handles(query string) bool {
+	m := launcherPattern.FindStringSubmatch(query)
+	if m == nil || strings.ContainsAny(m[1], "/~") {
+		return false
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if err := ls.refreshLocked(); err != nil {
+		return false
+	}
+	return len(ls.matchLocked(m[1])) > 0
+}
+
+// Launch starts the best match for a query and lists the others so a
+// different one can be picked
+func (ls *LauncherService) Launch(ctx context.Context, query string) (LauncherResult, error) {
+	terms := query
+	if m := launcherPattern.FindStringSubmatch(query); m != nil {
+		terms = m[1]
+	}
+	result := LauncherResult{Query: strings.TrimSpace(terms), Apps: []AppEntry{}}
+
+	ls.mu.Lock()
+	if err := ls.refreshLocked(); err != nil {
+		ls.mu.Unlock()
+		return result, err
+	}
+	matches := ls.matchLocked(terms)
+	if len(matches) > launcherResultLimit {
+		matches = matches[:launcherResultLimit]
+	}
+	for i := range matches {
+		matches[i].Icon = ls.iconLocked(matches[i].iconName)
+	}
+	ls.mu.Unlock()
+
+	if len(matches) == 0 {
+		return result, fmt.Errorf("no app matches %q", result.Query)
+	}
+	result.Apps = append(result.Apps, matches...)
+
+	if err := ls.LaunchApp(ctx, matches[0].ID); err != nil {
+		return result, err
+	}
+	result.Launched = matches[0].ID
+	result.Apps[0].Launches++
+	return result, nil
+}
+
+// LaunchApp starts an app or action by ID and counts the launch
+func (ls *LauncherService) LaunchApp(ctx context.Context, id string) error {
+	ls.mu.Lock()
+	if err := ls.refreshLocked(); err != nil {
+		ls.mu.Unlock()
+		return err
+	}
+	var app *AppEntry
+	for i := range ls.apps {
+		if ls.apps[i].ID == id {
+			app = &ls.apps[i]
+			break
+		}
+	}
+	if app == nil {
+		ls.mu.Unlock()
+		return fmt.Errorf("app %s is no longer installed", id)
+	}
+	command := app.Exec
+	if app.terminal {
+		command = ls.cfg.Terminal + " " + command
+	}
+	ls.mu.Unlock()
+
+	if err := hyprDispatch(ctx, "exec", command); err != nil {
+		return err
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.launches[id]++
+	if err := ls.saveStatsLocked(); err != nil {
+		warnf("could not save launch counts: %v", err)
+	}
+	return nil
+}
+
+// matchLocked returns the apps matching every word of terms, best first.
+// Words found in the name count more than those only in keywords, and among
+// equally good matches the most launched comes first.
+func (ls *LauncherService) matchLocked(terms string) []AppEntry {
+	var words []string
+	for _, word := range searchWords(terms) {
+		if !launcherFillerWords[word] {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	type ranked struct {
+		app   AppEntry
+		score float64
+	}
+	var matches []ranked
+	for _, app := range ls.apps {
+		score, ok := app.score(words)
+		if !ok {
+			continue
+		}
+		app.Launches = ls.launches[app.ID]
+		matches = append(matches, ranked{app, score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].app.Launches > matches[j].app.Launches
+	})
+
+	apps := make([]AppEntry, len(matches))
+	for i, m := range matches {
+		apps[i] = m.app
+	}
+	return apps
+}
+
+// score rates how well words describe the app. An action only matches when
+// some word names it, so "open firefox" starts Firefox itself, and words of
+// the action left unsaid count against it, so "firefox window" prefers New
+// Window over New Private Window.
+func (app AppEntry) score(words []string) (float64, bool) {
+	total := 0.0
+	namesAction := false
+	said := make(map[string]bool)
+	for _, word := range words {
+		s := wordScore(word, app.primary)
+		for _, token := range app.action {
+			if a := wordScore(word, []string{token}); a > 0 {
+				s = math.Max(s, a)
+				namesAction = true
+				said[token] = true
+			}
+		}
+		if s == 0 {
+			s = wordScore(word, app.secondary) / 2
+		}
+		if s == 0 {
+			return 0, false
+		}
+		total += s
+	}
+	if app.Action != "" && !namesAction {
+		return 0, false
+	}
+	for _, token := range app.action {
+		if !said[token] {
+			total -= 0.5
+		}
+	}
+	return total, true
+}
+
+// wordScore rates the best match of word among tokens: whole word, prefix,
+// substring, then a typo
+func wordScore(word string, tokens []string) float64 {
+	best := 0.0
+	for _, token := range tokens {
+		switch {
+		case token == word:
+			return 3
+		case strings.HasPrefix(token, word):
+			best = math.Max(best, 2)
+		case len(word) >= 3 && strings.Contains(token, word):
+			best = math.Max(best, 1)
+		case len(word) >= 4 && editDistance(word, token) <= maxTypoDistance(word):
+			best = math.Max(best, 1)
+		}
+	}
+	return best
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// refreshLocked rereads the app list when a desktop file or the quick apps
+// file changed since the last read
+func (ls *LauncherService) refreshLocked() error {
+	if !ls.statsLoaded {
+		data, err := os.ReadFile(ls.statsPath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read launch counts: %w", err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &ls.launches); err != nil {
+				return fmt.Errorf("invalid launch counts %s: %w", ls.statsPath, err)
+			}
+		}
+		ls.statsLoaded = true
+	}
+
+	dirs := applicationDirs()
+	var stamp strings.Builder
+	for _, path := range append(dirs, ls.cfg.QuickApps) {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&stamp, "%s:%d;", path, info.ModTime().UnixNano())
+		}
+	}
+	if ls.apps != nil && stamp.String() == ls.stamp {
+		return nil
+	}
+
+	apps := loadDesktopApps(dirs)
+	quickApps, err := loadQuickApps(ls.cfg.QuickApps)
+	if err != nil {
+		warnf("could not read quick apps: %v", err)
+	}
+	ls.apps = mergeQuickApps(apps, quickApps)
+	ls.stamp = stamp.String()
+	return nil
+}
+
+func (ls *LauncherService) saveStatsLocked() error {
+	if err := os.MkdirAll(filepath.Dir(ls.statsPath), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ls.launches, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(ls.statsPath, data)
+}
+
+// applicationDirs returns the XDG applications directories, most important
+// first so a user's own desktop file hides the system one
+func applicationDirs() []string {
+	var dirs []string
+	for _, dataDir := range xdgDataDirs() {
+		dirs = append(dirs, filepath.Join(dataDir, "applications"))
+	}
+	return dirs
+}
+
+func xdgDataDirs() []string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		homeDir, _ := os.UserHomeDir()
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	return append([]string{dataHome}, filepath.SplitList(dataDirs)...)
+}
+
+// loadDesktopApps reads every application and its actions. A desktop file
+// ID is its path below the applications directory with "/" as "-".
+func loadDesktopApps(dirs []string) []AppEntry {
+	var apps []AppEntry
+	seen := make(map[string]bool)
+	locales := localeNames()
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".desktop") {
+				return nil
+			}
+			rel, _ := filepath.Rel(dir, path)
+			id := strings.ReplaceAll(rel, string(filepath.Separator), "-")
+			if seen[id] {
+				return nil
+			}
+			seen[id] = true
+			entries, err := parseDesktopFile(path, id, locales)
+			if err != nil {
+				warnf("could not read %s: %v", path, err)
+				return nil
+			}
+			apps = append(apps, entries...)
+			return nil
+		})
+	}
+	return apps
+}
+
+// parseDesktopFile returns the app described by a desktop file followed by
+// its actions, or nothing when it is hidden or not meant for this desktop
+func parseDesktopFile(path, id string, locales []string) ([]AppEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	groups := make(map[string]map[string]string)
+	var group map[string]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = make(map[string]string)
+			groups[line[1:len(line)-1]] = group
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && group != nil {
+			group[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	entry := groups["Desktop Entry"]
+	if entry == nil || entry["Type"] != "Application" || entry["Exec"] == "" ||
+		entry["NoDisplay"] == "true" || entry["Hidden"] == "true" || !shownOnDesktop(entry) {
+		return nil, nil
+	}
+	if tryExec := entry["TryExec"]; tryExec != "" {
+		if _, err := exec.LookPath(unescapeDesktop(tryExec)); err != nil {
+			return nil, nil
+		}
+	}
+
+	get := func(group map[string]string, key string) string {
+		for _, locale := range locales {
+			if value, ok := group[key+"["+locale+"]"]; ok {
+				return unescapeDesktop(value)
+			}
+		}
+		return unescapeDesktop(group[key])
+	}
+
+	app := AppEntry{
+		ID:          id,
+		Name:        get(entry, "Name"),
+		GenericName: get(entry, "GenericName"),
+		Comment:     get(entry, "Comment"),
+		Keywords:    splitDesktopList(get(entry, "Keywords")),
+		Exec:        desktopCommand(get(entry, "Exec")),
+		Source:      "desktop",
+		iconName:    get(entry, "Icon"),
+		terminal:    entry["Terminal"] == "true",
+	}
+	if app.Name == "" {
+		app.Name = strings.TrimSuffix(id, ".desktop")
+	}
+	app.primary = searchWords(app.Name)
+	app.secondary = searchWords(strings.Join(append([]string{
+		app.GenericName, programName(app.Exec), strings.TrimSuffix(id, ".desktop"),
+	}, app.Keywords...), " "))
+
+	entries := []AppEntry{app}
+	for _, actionID := range splitDesktopList(entry["Actions"]) {
+		group := groups["Desktop Action "+actionID]
+		if group == nil || group["Exec"] == "" {
+			continue
+		}
+		action := app
+		action.ID = id + "#" + actionID
+		action.Action = get(group, "Name")
+		action.Exec = desktopCommand(get(group, "Exec"))
+		action.action = searchWords(action.Action)
+		if icon := get(group, "Icon"); icon != "" {
+			action.iconName = icon
+		}
+		entries = append(entries, action)
+	}
+	return entries, nil
+}
+
+// shownOnDesktop applies OnlyShowIn and NotShowIn to XDG_CURRENT_DESKTOP
+func shownOnDesktop(entry map[string]string) bool {
+	current := strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":")
+	contains := func(list string) bool {
+		for _, desktop := range splitDesktopList(list) {
+			for _, c := range current {
+				if strings.EqualFold(desktop, c) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if only := entry["OnlyShowIn"]; only != "" && !contains(only) {
+		return false
+	}
+	return !contains(entry["NotShowIn"])
+}
+
+// localeNames returns the locale suffixes to try for "Name[de_DE]", most
+// specific first
+func localeNames() []string {
+	locale := ""
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale = os.Getenv(key); locale != "" {
+			break
+		}
+	}
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+	names := []string{locale}
+	if lang, _, ok := strings.Cut(locale, "_"); ok {
+		names = append(names, lang)
+	}
+	return names
+}
+
+func splitDesktopList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func unescapeDesktop(value string) string {
+	return strings.NewReplacer(`\s`, " ", `\n`, "\n", `\t`, "\t", `\r`, "\r", `\\`, `\`).Replace(value)
+}
+
+// desktopCommand removes the field codes from an Exec line. Hyprland runs
+// the result through the shell, whose quoting Exec lines already follow.
+func desktopCommand(execLine string) string {
+	command := launcherFieldCodes.ReplaceAllString(execLine, "")
+	command = strings.ReplaceAll(command, "%%", "%")
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// programName returns the program a command runs, skipping "env VAR=value"
+func programName(command string) string {
+	for _, field := range strings.Fields(command) {
+		field = strings.Trim(field, `"'`)
+		if field == "env" || strings.Contains(field, "=") {
+			continue
+		}
+		return filepath.Base(field)
+	}
+	return ""
+}
+
+// loadQuickApps reads Name=command lines, skipping blank lines and comments
+func loadQuickApps(path string) ([]AppEntry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []AppEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, command, ok := strings.Cut(line, "=")
+		name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+		if !ok || name == "" || command == "" {
+			continue
+		}
+		apps = append(apps, AppEntry{
+			ID:        "quickapps:" + name,
+			Name:      name,
+			Exec:      command,
+			Source:    "quickapps",
+			iconName:  programName(command),
+			primary:   searchWords(name),
+			secondary: searchWords(programName(command)),
+		})
+	}
+	return apps, nil
+}
+
+// mergeQuickApps adds quick apps to the desktop apps. A quick app that just
+// runs an installed app's program becomes an alias of it, so "open
+// terminal" finds kitty.desktop with its icon and actions.
+func mergeQuickApps(apps, quickApps []AppEntry) []AppEntry {
+	if apps == nil {
+		apps = []AppEntry{}
+	}
+	for _, quick := range quickApps {
+		merged := false
+		for i := range apps {
+			if apps[i].Source != "desktop" || quick.Exec != programName(apps[i].Exec) {
+				continue
+			}
+			apps[i].Aliases = append(apps[i].Aliases, quick.Name)
+			apps[i].primary = append(append([]string{}, apps[i].primary...), quick.primary...)
+			merged = true
+		}
+		if !merged {
+			apps = append(apps, quick)
+		}
+	}
+	return apps
+}
+
+// iconLocked resolves an icon name from the hicolor theme or pixmaps to a
+// data URL, caching the answer. Absolute paths are used as they are.
+func (ls *LauncherService) iconLocked(name string) string {
+	if name == "" {
+		return ""
+	}
+	if icon, ok := ls.icons[name]; ok {
+		return icon
+	}
+
+	var candidates []string
+	if filepath.IsAbs(name) {
+		candidates = append(candidates, name)
+	} else {
+		homeDir, _ := os.UserHomeDir()
+		bases := []string{filepath.Join(homeDir, ".icons")}
+		for _, dataDir := range xdgDataDirs() {
+			bases = append(bases, filepath.Join(dataDir, "icons"))
+		}
+		for _, base := range bases {
+			for _, size := range iconSizes {
+				for _, ext := range []string{".svg", ".png"} {
+					candidates = append(candidates, filepath.Join(base, "hicolor", size, "apps", name+ext))
+				}
+			}
+		}
+		for _, dataDir := range xdgDataDirs() {
+			for _, ext := range []string{".svg", ".png"} {
+				candidates = append(candidates, filepath.Join(dataDir, "pixmaps", name+ext))
+			}
+		}
+	}
+
+	icon := ""
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err != nil || info.Size() > launcherIconLimit {
+			continue
+		}
+		mime := ""
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".svg":
+			mime = "image/svg+xml"
+		case ".png":
+			mime = "image/png"
+		default:
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			icon = "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
+			break
+		}
+	}
+	ls.icons[name] = icon
+	return icon
+}
@@ -0,0 +1,489 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// diagnosticParsers maps a ToolSpec parser name to the function reading its output
+var diagnosticParsers = map[string]func(output []byte) ([]Diagnostic, error){
+	"shellcheck":    parseShellcheck,
+	"ruff":          parseRuff,
+	"golangci-lint": parseGolangciLint,
+	"govet":         parseGoVet,
+	"eslint":        parseEslint,
+	"stylelint":     parseStylelint,
+	"markdownlint":  parseMarkdownlint,
+	"compiler":      func(output []byte) ([]Diagnostic, error) { return parseCompilerOutput(output), nil },
+}
+
+// Check runs a real linter against filePath and returns normalized diagnostics
+func (ls *LinterService) Check(ctx context.Context, filePath string) (LinterResult, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return LinterResult{}, err
+	}
+
+	tool, err := ls.registry.Linter(absPath)
+	if err != nil {
+		return LinterResult{}, err
+	}
+
+	diagnostics, output, err := runLinter(ctx, tool)
+	if err != nil {
+		return LinterResult{
+			Output:     output,
+			FilePath:   filePath,
+			LinterUsed: tool.spec.Name,
+			Mode:       "check",
+		}, err
+	}
+	return checkResult(filePath, absPath, tool, diagnostics), nil
+}
+
+// runLinter runs a resolved linter once and parses everything it reported.
+// On failure the linter's stderr is returned alongside the error.
+func runLinter(ctx context.Context, tool resolvedTool) ([]Diagnostic, string, error) {
+	parse, ok := diagnosticParsers[tool.spec.Parser]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown diagnostic parser %q for %s", tool.spec.Parser, tool.spec.Name)
+	}
+
+	cmd := exec.CommandContext(ctx, tool.spec.Command, tool.args...)
+	cmd.Dir = tool.dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Linters exit non-zero when they find issues, so only the parse decides failure
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, "", ctx.Err()
+	}
+	if _, ok := runErr.(*exec.ExitError); runErr != nil && !ok {
+		return nil, "", fmt.Errorf("failed to run %s: %w", tool.spec.Name, runErr)
+	}
+
+	raw := stdout.Bytes()
+	if tool.spec.Combined {
+		raw = append(raw, stderr.Bytes()...)
+	}
+
+	diagnostics, err := parse(raw)
+	if err != nil {
+		return nil, stderr.String(), fmt.Errorf("%s output could not be parsed: %w", tool.spec.Name, err)
+	}
+	return diagnostics, "", nil
+}
+
+// checkResult builds the result for one file out of a linter's diagnostics
+func checkResult(filePath, absPath string, tool resolvedTool, diagnostics []Diagnostic) LinterResult {
+	diagnostics = diagnosticsForFile(diagnostics, absPath, tool.dir)
+
+	result := LinterResult{
+		FilePath:    filePath,
+		LinterUsed:  tool.spec.Name,
+		ConfigUsed:  tool.config,
+		Mode:        "check",
+		Diagnostics: diagnostics,
+		Output:      formatDiagnostics(diagnostics),
+	}
+	for _, d := range diagnostics {
+		switch d.Severity {
+		case "error":
+			result.ErrorCount++
+		case "warning":
+			result.WarningCount++
+		}
+	}
+	return result
+}
+
+// diagnosticsForFile drops findings for other files, which package-level
+// linters like go vet report alongside the requested one
+func diagnosticsForFile(diagnostics []Diagnostic, absPath, dir string) []Diagnostic {
+	var filtered []Diagnostic
+	for _, d := range diagnostics {
+		file := d.File
+		if file != "" && !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if file == "" || filepath.Clean(file) == absPath {
+			d.File = absPath
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+func formatDiagnostics(diagnostics []Diagnostic) string {
+	if len(diagnostics) == 0 {
+		return "No issues found"
+	}
+
+	var b strings.Builder
+	for _, d := range diagnostics {
+		fmt.Fprintf(&b, "%s:%d:%d: %s: %s", filepath.Base(d.File), d.Line, d.Column, d.Severity, d.Message)
+		if d.Rule != "" {
+			fmt.Fprintf(&b, " [%s]", d.Rule)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func parseShellcheck(output []byte) ([]Diagnostic, error) {
+	var issues []struct {
+		File    string `json:"file"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		Level   string `json:"level"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(output, &issues); err != nil {
+		return nil, err
+	}
+
+	diagnostics := make([]Diagnostic, 0, len(issues))
+	for _, issue := range issues {
+		severity := issue.Level
+		if severity == "style" {
+			severity = "info"
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     issue.File,
+			Line:     issue.Line,
+			Column:   issue.Column,
+			Severity: severity,
+			Rule:     fmt.Sprintf("SC%d", issue.Code),
+			Message:  issue.Message,
+		})
+	}
+	return diagnostics, nil
+}
+
+func parseRuff(output []byte) ([]Diagnostic, error) {
+	var issues []struct {
+		Code     *string `json:"code"`
+		Message  string  `json:"message"`
+		Filename string  `json:"filename"`
+		Location struct {
+			Row    int `json:"row"`
+			Column int `json:"column"`
+		} `json:"location"`
+	}
+	if err := json.Unmarshal(output, &issues); err != nil {
+		return nil, err
+	}
+
+	diagnostics := make([]Diagnostic, 0, len(issues))
+	for _, issue := range issues {
+		// Ruff has no severities; syntax errors come without a code
+		severity := "warning"
+		rule := ""
+		if issue.Code == nil {
+			severity = "error"
+		} else {
+			rule = *issue.Code
+			if strings.HasPrefix(rule, "E9") {
+				severity = "error"
+			}
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     issue.Filename,
+			Line:     issue.Location.Row,
+			Column:   issue.Location.Column,
+			Severity: severity,
+			Rule:     rule,
+			Message:  issue.Message,
+		})
+	}
+	return diagnostics, nil
+}
+
+func parseGolangciLint(output []byte) ([]Diagnostic, error) {
+	var report struct {
+		Issues []struct {
+			FromLinter string `json:"FromLinter"`
+			Text       string `json:"Text"`
+			Severity   string `json:"Severity"`
+			Pos        struct {
+				Filename string `json:"Filename"`
+				Line     int    `json:"Line"`
+				Column   int    `json:"Column"`
+			} `json:"Pos"`
+		} `json:"Issues"`
+	}
+
+	// Only the first JSON document matters, anything after it is a text summary
+	if err := json.NewDecoder(bytes.NewReader(output)).Decode(&report); err != nil {
+		return nil, err
+	}
+
+	diagnostics := make([]Diagnostic, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		severity := strings.ToLower(issue.Severity)
+		if severity == "" {
+			severity = "warning"
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     issue.Pos.Filename,
+			Line:     issue.Pos.Line,
+			Column:   issue.Pos.Column,
+			Severity: severity,
+			Rule:     issue.FromLinter,
+			Message:  issue.Text,
+		})
+	}
+	return diagnostics, nil
+}
+
+func parseGoVet(output []byte) ([]Diagnostic, error) {
+	// go vet -json interleaves "# package" headers with one JSON object per package
+	var jsonOnly bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		jsonOnly.WriteString(line)
+		jsonOnly.WriteString("\n")
+	}
+
+	var diagnostics []Diagnostic
+	decoder := json.NewDecoder(&jsonOnly)
+	for decoder.More() {
+		var packages map[string]map[string]json.RawMessage
+		if err := decoder.Decode(&packages); err != nil {
+			// Build failures are printed as plain text rather than JSON
+			return parseCompilerOutput(output), nil
+		}
+
+		for _, analyzers := range packages {
+			for analyzer, raw := range analyzers {
+				var findings []struct {
+					Posn    string `json:"posn"`
+					Message string `json:"message"`
+				}
+				// Analyzer failures are reported as an object instead of a list
+				if err := json.Unmarshal(raw, &findings); err != nil {
+					continue
+				}
+				for _, f := range findings {
+					file, line, col := splitPosition(f.Posn)
+					diagnostics = append(diagnostics, Diagnostic{
+						File:     file,
+						Line:     line,
+						Column:   col,
+						Severity: "warning",
+						Rule:     analyzer,
+						Message:  f.Message,
+					})
+				}
+			}
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// compilerLine matches "file:line:col: message" style output, optionally
+// prefixed or followed by a severity as qmllint and gcc print it
+var compilerLine = regexp.MustCompile(`(?i)^(?:(warning|error|info):\s*)?(.+?):(\d+):(?:(\d+):)?\s*(?:(warning|error|note|info):\s*)?(.*)$`)
+
+// compilerRule picks up trailing "[rule]" and luacheck style "(W211)" codes
+var compilerRule = regexp.MustCompile(`^\(([EW]\d+)\)\s*|\s*\[([\w.-]+)\]$`)
+
+// parseCompilerOutput handles "file:line:col: message" lines
+func parseCompilerOutput(output []byte) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, line := range strings.Split(string(output), "\n") {
+		m := compilerLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		lineNo, _ := strconv.Atoi(m[3])
+		col, _ := strconv.Atoi(m[4])
+		message := m[6]
+
+		severity := strings.ToLower(m[1])
+		if severity == "" {
+			severity = strings.ToLower(m[5])
+		}
+
+		rule := ""
+		for _, r := range compilerRule.FindAllStringSubmatch(message, -1) {
+			rule = r[1] + r[2]
+		}
+		message = strings.TrimSpace(compilerRule.ReplaceAllString(message, ""))
+
+		if severity == "" {
+			switch {
+			case strings.HasPrefix(rule, "W"):
+				severity = "warning"
+			default:
+				severity = "error"
+			}
+		}
+		if severity == "note" {
+			severity = "info"
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     strings.TrimPrefix(strings.TrimPrefix(m[2], "vet: "), "./"),
+			Line:     lineNo,
+			Column:   col,
+			Severity: severity,
+			Rule:     rule,
+			Message:  message,
+		})
+	}
+	return diagnostics
+}
+
+func parseStylelint(output []byte) ([]Diagnostic, error) {
+	var files []struct {
+		Source   string `json:"source"`
+		Warnings []struct {
+			Line     int    `json:"line"`
+			Column   int    `json:"column"`
+			Rule     string `json:"rule"`
+			Severity string `json:"severity"`
+			Text     string `json:"text"`
+		} `json:"warnings"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(output)).Decode(&files); err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for _, file := range files {
+		for _, w := range file.Warnings {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:     file.Source,
+				Line:     w.Line,
+				Column:   w.Column,
+				Severity: w.Severity,
+				Rule:     w.Rule,
+				Message:  strings.TrimSuffix(w.Text, " ("+w.Rule+")"),
+			})
+		}
+	}
+	return diagnostics, nil
+}
+
+func parseMarkdownlint(output []byte) ([]Diagnostic, error) {
+	trimmed := bytes.TrimSpace(output)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	var issues []struct {
+		FileName        string   `json:"fileName"`
+		LineNumber      int      `json:"lineNumber"`
+		RuleNames       []string `json:"ruleNames"`
+		RuleDescription string   `json:"ruleDescription"`
+		ErrorDetail     *string  `json:"errorDetail"`
+		ErrorRange      []int    `json:"errorRange"`
+	}
+	if err := json.Unmarshal(trimmed, &issues); err != nil {
+		return nil, err
+	}
+
+	diagnostics := make([]Diagnostic, 0, len(issues))
+	for _, issue := range issues {
+		message := issue.RuleDescription
+		if issue.ErrorDetail != nil && *issue.ErrorDetail != "" {
+			message += ": " + *issue.ErrorDetail
+		}
+		col := 0
+		if len(issue.ErrorRange) > 0 {
+			col = issue.ErrorRange[0]
+		}
+		rule := ""
+		if len(issue.RuleNames) > 0 {
+			rule = strings.Join(issue.RuleNames, "/")
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     issue.FileName,
+			Line:     issue.LineNumber,
+			Column:   col,
+			Severity: "warning",
+			Rule:     rule,
+			Message:  message,
+		})
+	}
+	return diagnostics, nil
+}
+
+func parseEslint(output []byte) ([]Diagnostic, error) {
+	var files []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID   *string `json:"ruleId"`
+			Severity int     `json:"severity"`
+			Message  string  `json:"message"`
+			Line     int     `json:"line"`
+			Column   int     `json:"column"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(output, &files); err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for _, file := range files {
+		for _, msg := range file.Messages {
+			severity := "warning"
+			if msg.Severity == 2 {
+				severity = "error"
+			}
+			rule := ""
+			if msg.RuleID != nil {
+				rule = *msg.RuleID
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				File:     file.FilePath,
+				Line:     msg.Line,
+				Column:   msg.Column,
+				Severity: severity,
+				Rule:     rule,
+				Message:  msg.Message,
+			})
+		}
+	}
+	return diagnostics, nil
+}
+
+// splitPosition splits "path/file.go:12:5" into its parts
+func splitPosition(posn string) (string, int, int) {
+	parts := strings.Split(posn, ":")
+	if len(parts) < 3 {
+		return posn, 0, 0
+	}
+
+	col, colErr := strconv.Atoi(parts[len(parts)-1])
+	line, lineErr := strconv.Atoi(parts[len(parts)-2])
+	if lineErr != nil {
+		return posn, 0, 0
+	}
+	if colErr != nil {
+		col = 0
+	}
+
+	file := strings.TrimPrefix(strings.Join(parts[:len(parts)-2], ":"), "vet: ")
+	return strings.TrimPrefix(file, "./"), line, col
+}
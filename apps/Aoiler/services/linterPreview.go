@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pendingFormat is a formatter result waiting for the user to confirm it
+type pendingFormat struct {
+	path      string
+	linter    string
+	checksum  [32]byte
+	formatted []byte
+	createdAt time.Time
+}
+
+// pendingFormatTTL is how long an unconfirmed preview is kept around
+const pendingFormatTTL = time.Hour
+
+// Preview formats filePath without writing it and returns a unified diff.
+// The change is only written once ApplyFormat is called with the pending ID.
+func (ls *LinterService) Preview(ctx context.Context, filePath string) (LinterResult, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return LinterResult{}, err
+	}
+
+	original, err := os.ReadFile(absPath)
+	if err != nil {
+		return LinterResult{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	tool, err := ls.registry.Formatter(absPath)
+	if err != nil {
+		return LinterResult{}, err
+	}
+	name := tool.spec.Name
+
+	cmd := exec.CommandContext(ctx, tool.spec.Command, tool.args...)
+	cmd.Dir = tool.dir
+
+	var stdout, stderr bytes.Buffer
+	if tool.spec.Stdin {
+		cmd.Stdin = bytes.NewReader(original)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return LinterResult{
+			Output:     stderr.String(),
+			FilePath:   filePath,
+			LinterUsed: name,
+			Mode:       "format",
+		}, fmt.Errorf("%s failed: %s", name, strings.TrimSpace(stderr.String()))
+	}
+
+	formatted := stdout.Bytes()
+	if bytes.Equal(original, formatted) {
+		return LinterResult{
+			Output:     "Already formatted",
+			FilePath:   filePath,
+			LinterUsed: name,
+			ConfigUsed: tool.config,
+			Mode:       "format",
+		}, nil
+	}
+
+	diff, err := unifiedDiff(absPath, formatted)
+	if err != nil {
+		return LinterResult{}, err
+	}
+
+	id := newID()
+	ls.mu.Lock()
+	ls.prunePendingLocked()
+	ls.pending[id] = pendingFormat{
+		path:      absPath,
+		linter:    name,
+		checksum:  sha256.Sum256(original),
+		formatted: formatted,
+		createdAt: time.Now(),
+	}
+	ls.mu.Unlock()
+
+	return LinterResult{
+		Output:     stderr.String(),
+		FilePath:   filePath,
+		LinterUsed: name,
+		ConfigUsed: tool.config,
+		Mode:       "format",
+		Changed:    true,
+		Diff:       diff,
+		PendingID:  id,
+	}, nil
+}
+
+// ApplyFormat writes a previewed formatter result to disk. It refuses to
+// overwrite the file if it was modified after the preview was generated.
+// A preview that could not be applied stays pending so it can be discarded.
+func (ls *LinterService) ApplyFormat(id string) (LinterResult, error) {
+	// Take the entry out while writing so a second apply can't race this one
+	ls.mu.Lock()
+	pending, ok := ls.pending[id]
+	delete(ls.pending, id)
+	ls.mu.Unlock()
+
+	if !ok {
+		return LinterResult{}, fmt.Errorf("no pending format with id %s", id)
+	}
+
+	if err := applyPending(pending); err != nil {
+		ls.mu.Lock()
+		ls.pending[id] = pending
+		ls.mu.Unlock()
+		return LinterResult{}, err
+	}
+
+	return LinterResult{
+		Output:     "Formatting applied",
+		Fixed:      true,
+		FilePath:   pending.path,
+		LinterUsed: pending.linter,
+		Mode:       "format",
+		Changed:    true,
+	}, nil
+}
+
+func applyPending(pending pendingFormat) error {
+	current, err := os.ReadFile(pending.path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if sha256.Sum256(current) != pending.checksum {
+		return fmt.Errorf("file changed since preview, format it again: %s", pending.path)
+	}
+	return writeFileAtomic(pending.path, pending.formatted)
+}
+
+// DiscardFormat drops a pending preview without touching the file
+func (ls *LinterService) DiscardFormat(id string) bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	_, ok := ls.pending[id]
+	delete(ls.pending, id)
+	return ok
+}
+
+func (ls *LinterService) prunePendingLocked() {
+	for id, p := range ls.pending {
+		if time.Since(p.createdAt) > pendingFormatTTL {
+			delete(ls.pending, id)
+		}
+	}
+}
+
+// unifiedDiff diffs the file on disk against the formatted content on stdin
+func unifiedDiff(path string, formatted []byte) (string, error) {
+	name := filepath.Base(path)
+	cmd := exec.Command("diff", "-u", "--label", "a/"+name, "--label", "b/"+name, path, "-")
+	cmd.Stdin = bytes.NewReader(formatted)
+
+	output, err := cmd.Output()
+	// diff exits with 1 when the inputs differ, which is the expected case here
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		err = nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to diff formatted output: %w", err)
+	}
+	return string(output), nil
+}
+
+// writeFileAtomic replaces path with data via a temp file and rename so a
+// crash never leaves a half-written file behind. New files are created 0644.
+// A symlink is followed so the rename replaces its target, not the link.
+func writeFileAtomic(path string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".aoiler-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ToolSpec describes a formatter or linter invocation. Args and ConfigArgs are
+// templates: {file} is the absolute file path, {dir} its directory, {config}
+// the detected project config file and {root} the directory containing it,
+// or {dir} when there is no config.
+type ToolSpec struct {
+	Name        string   `json:"name"`
+	Command     string   `json:"command"`
+	Args        []string `json:"args"`
+	ConfigFiles []string `json:"configFiles,omitempty"`
+	ConfigArgs  []string `json:"configArgs,omitempty"`
+	// Stdin formatters read the source on stdin, others read {file} themselves.
+	// Either way the formatted result is expected on stdout.
+	Stdin bool `json:"stdin,omitempty"`
+	// Parser names the diagnostic format a linter prints, see diagnosticParsers
+	Parser string `json:"parser,omitempty"`
+	// Combined parses stderr after stdout, for tools that report on stderr
+	Combined bool `json:"combined,omitempty"`
+}
+
+// LanguageSpec maps file extensions to formatters and linters, tried in order
+type LanguageSpec struct {
+	Name       string     `json:"name"`
+	Extensions []string   `json:"extensions,omitempty"`
+	Formatters []ToolSpec `json:"formatters,omitempty"`
+	Linters    []ToolSpec `json:"linters,omitempty"`
+}
+
+// LinterRegistry resolves which tools handle a given file
+type LinterRegistry struct {
+	languages []LanguageSpec
+	byExt     map[string]int
+}
+
+// resolvedTool is a ToolSpec with its templates expanded for one file
+type resolvedTool struct {
+	spec   ToolSpec
+	args   []string
+	dir    string
+	config string
+}
+
+// dirScoped reports whether the tool lints a whole directory per run, so its
+// output is the same for every file in that directory
+func (t resolvedTool) dirScoped() bool {
+	for _, args := range [][]string{t.spec.Args, t.spec.ConfigArgs} {
+		for _, arg := range args {
+			if strings.Contains(arg, "{file}") {
+				return false
+			}
+		}
+	}
+	return !t.spec.Stdin
+}
+
+// ToolMissingError reports that none of the configured tools are installed
+type ToolMissingError struct {
+	Kind  string
+	Tools []string
+}
+
+func (e *ToolMissingError) Error() string {
+	return fmt.Sprintf("%s not installed (tried %s)", e.Kind, strings.Join(e.Tools, ", "))
+}
+
+var prettierConfigs = []string{
+	".prettierrc", ".prettierrc.json", ".prettierrc.yaml", ".prettierrc.yml",
+	".prettierrc.toml", ".prettierrc.js", ".prettierrc.cjs", ".prettierrc.mjs",
+	"prettier.config.js", "prettier.config.cjs", "prettier.config.mjs",
+}
+
+func prettierTool() ToolSpec {
+	return ToolSpec{
+		Name:        "prettier",
+		Command:     "prettier",
+		Args:        []string{"--stdin-filepath", "{file}"},
+		ConfigFiles: prettierConfigs,
+		ConfigArgs:  []string{"--config", "{config}"},
+		Stdin:       true,
+	}
+}
+
+// defaultLanguages is the built-in registry, extended or overridden by the
+// "linters" section of the config file
+func defaultLanguages() []LanguageSpec {
+	return []LanguageSpec{
+		{
+			Name:       "python",
+			Extensions: []string{".py", ".pyi"},
+			Formatters: []ToolSpec{
+				{Name: "black", Command: "black", Args: []string{"-q", "--stdin-filename", "{file}", "-"}, ConfigFiles: []string{"pyproject.toml"}, Stdin: true},
+				{Name: "ruff format", Command: "ruff", Args: []string{"format", "--stdin-filename", "{file}", "-"}, ConfigFiles: []string{"ruff.toml", ".ruff.toml", "pyproject.toml"}, Stdin: true},
+			},
+			Linters: []ToolSpec{
+				{Name: "ruff", Command: "ruff", Args: []string{"check", "--output-format=json", "{file}"}, ConfigFiles: []string{"ruff.toml", ".ruff.toml", "pyproject.toml"}, Parser: "ruff"},
+			},
+		},
+		{
+			Name:       "go",
+			Extensions: []string{".go"},
+			Formatters: []ToolSpec{
+				{Name: "gofmt", Command: "gofmt", Stdin: true},
+			},
+			Linters: []ToolSpec{
+				{Name: "golangci-lint", Command: "golangci-lint", Args: []string{"run", "--output.json.path=stdout", "--show-stats=false", "{dir}"}, ConfigFiles: []string{".golangci.yml", ".golangci.yaml", ".golangci.toml", ".golangci.json"}, Parser: "golangci-lint"},
+				// Older toolchains print the JSON report on stderr
+				{Name: "go vet", Command: "go", Args: []string{"vet", "-json", "{dir}"}, ConfigFiles: []string{"go.mod"}, Parser: "govet", Combined: true},
+			},
+		},
+		{
+			Name:       "shell",
+			Extensions: []string{".sh", ".bash"},
+			Formatters: []ToolSpec{
+				{Name: "shfmt", Command: "shfmt", Args: []string{"-filename", "{file}"}, ConfigFiles: []string{".editorconfig"}, Stdin: true},
+			},
+			Linters: []ToolSpec{
+				{Name: "shellcheck", Command: "shellcheck", Args: []string{"-f", "json", "{file}"}, ConfigFiles: []string{".shellcheckrc"}, Parser: "shellcheck"},
+			},
+		},
+		{
+			Name:       "javascript",
+			Extensions: []string{".js", ".ts", ".jsx", ".tsx", ".mjs", ".cjs"},
+			Formatters: []ToolSpec{prettierTool()},
+			Linters: []ToolSpec{
+				{Name: "eslint", Command: "eslint", Args: []string{"-f", "json", "{file}"}, ConfigFiles: []string{"eslint.config.js", "eslint.config.mjs", "eslint.config.cjs", "eslint.config.ts", ".eslintrc.json", ".eslintrc.js", ".eslintrc.cjs", ".eslintrc.yml", ".eslintrc.yaml"}, Parser: "eslint"},
+			},
+		},
+		{
+			Name:       "rust",
+			Extensions: []string{".rs"},
+			Formatters: []ToolSpec{
+				{Name: "rustfmt", Command: "rustfmt", Args: []string{"--edition", "2021"}, ConfigFiles: []string{"rustfmt.toml", ".rustfmt.toml"}, ConfigArgs: []string{"--config-path", "{config}"}, Stdin: true},
+			},
+		},
+		{
+			Name:       "lua",
+			Extensions: []string{".lua"},
+			Formatters: []ToolSpec{
+				{Name: "stylua", Command: "stylua", Args: []string{"--stdin-filepath", "{file}", "-"}, ConfigFiles: []string{"stylua.toml", ".stylua.toml"}, ConfigArgs: []string{"--config-path", "{config}"}, Stdin: true},
+			},
+			Linters: []ToolSpec{
+				{Name: "luacheck", Command: "luacheck", Args: []string{"--formatter", "plain", "--codes", "{file}"}, ConfigFiles: []string{".luacheckrc"}, Parser: "compiler"},
+			},
+		},
+		{
+			Name:       "toml",
+			Extensions: []string{".toml"},
+			Formatters: []ToolSpec{
+				{Name: "taplo", Command: "taplo", Args: []string{"fmt", "-"}, ConfigFiles: []string{"taplo.toml", ".taplo.toml"}, ConfigArgs: []string{"--config", "{config}"}, Stdin: true},
+			},
+		},
+		{
+			Name:       "json",
+			Extensions: []string{".json", ".jsonc"},
+			Formatters: []ToolSpec{
+				prettierTool(),
+				{Name: "jq", Command: "jq", Args: []string{"."}, Stdin: true},
+			},
+		},
+		{
+			Name:       "css",
+			Extensions: []string{".css", ".scss", ".sass", ".less"},
+			Formatters: []ToolSpec{prettierTool()},
+			Linters: []ToolSpec{
+				{Name: "stylelint", Command: "stylelint", Args: []string{"--formatter", "json", "{file}"}, ConfigFiles: []string{".stylelintrc", ".stylelintrc.json", ".stylelintrc.yaml", ".stylelintrc.yml", "stylelint.config.js", "stylelint.config.mjs"}, Parser: "stylelint", Combined: true},
+			},
+		},
+		{
+			Name:       "yaml",
+			Extensions: []string{".yaml", ".yml"},
+			Formatters: []ToolSpec{prettierTool()},
+		},
+		{
+			Name:       "qml",
+			Extensions: []string{".qml"},
+			Formatters: []ToolSpec{
+				{Name: "qmlformat", Command: "qmlformat", Args: []string{"{file}"}, ConfigFiles: []string{".qmlformat.ini"}},
+				{Name: "qmlformat6", Command: "qmlformat6", Args: []string{"{file}"}, ConfigFiles: []string{".qmlformat.ini"}},
+			},
+			Linters: []ToolSpec{
+				{Name: "qmllint", Command: "qmllint", Args: []string{"{file}"}, ConfigFiles: []string{".qmllint.ini"}, Parser: "compiler", Combined: true},
+				{Name: "qmllint6", Command: "qmllint6", Args: []string{"{file}"}, ConfigFiles: []string{".qmllint.ini"}, Parser: "compiler", Combined: true},
+			},
+		},
+		{
+			Name:       "markdown",
+			Extensions: []string{".md", ".markdown"},
+			Formatters: []ToolSpec{prettierTool()},
+			Linters: []ToolSpec{
+				{Name: "markdownlint", Command: "markdownlint", Args: []string{"--json", "{file}"}, ConfigFiles: []string{".markdownlint.json", ".markdownlint.jsonc", ".markdownlint.yaml", ".markdownlint.yml", ".markdownlintrc"}, Parser: "markdownlint", Combined: true},
+			},
+		},
+	}
+}
+
+// NewLinterRegistry builds the registry from the defaults plus user overrides.
+// An override with the name of a built-in language replaces whichever of its
+// extensions, formatters or linters it sets; unknown names add a new language.
+func NewLinterRegistry(overrides []LanguageSpec) *LinterRegistry {
+	languages := defaultLanguages()
+
+	for _, override := range overrides {
+		replaced := false
+		for i := range languages {
+			if !strings.EqualFold(languages[i].Name, override.Name) {
+				continue
+			}
+			if len(override.Extensions) > 0 {
+				languages[i].Extensions = override.Extensions
+			}
+			if len(override.Formatters) > 0 {
+				languages[i].Formatters = override.Formatters
+			}
+			if len(override.Linters) > 0 {
+				languages[i].Linters = override.Linters
+			}
+			replaced = true
+			break
+		}
+		if !replaced {
+			languages = append(languages, override)
+		}
+	}
+
+	registry := &LinterRegistry{
+		languages: languages,
+		byExt:     make(map[string]int),
+	}
+	for i, lang := range languages {
+		for _, ext := range lang.Extensions {
+			registry.byExt[strings.ToLower(ext)] = i
+		}
+	}
+	return registry
+}
+
+// Language returns the language spec handling path
+func (r *LinterRegistry) Language(path string) (LanguageSpec, bool) {
+	i, ok := r.byExt[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return LanguageSpec{}, false
+	}
+	return r.languages[i], true
+}
+
+// Supports reports whether any language handles path
+func (r *LinterRegistry) Supports(path string) bool {
+	_, ok := r.Language(path)
+	return ok
+}
+
+// HasTool reports whether path has a formatter ("format") or linter ("check") configured
+func (r *LinterRegistry) HasTool(path, mode string) bool {
+	lang, ok := r.Language(path)
+	if !ok {
+		return false
+	}
+	if mode == "check" {
+		return len(lang.Linters) > 0
+	}
+	return len(lang.Formatters) > 0
+}
+
+// Formatter returns the first installed formatter for absPath
+func (r *LinterRegistry) Formatter(absPath string) (resolvedTool, error) {
+	lang, ok := r.Language(absPath)
+	if !ok || len(lang.Formatters) == 0 {
+		return resolvedTool{}, fmt.Errorf("no formatter configured for file type: %s", filepath.Ext(absPath))
+	}
+	return resolveTool("formatter", lang.Formatters, absPath)
+}
+
+// Linter returns the first installed linter for absPath
+func (r *LinterRegistry) Linter(absPath string) (resolvedTool, error) {
+	lang, ok := r.Language(absPath)
+	if !ok || len(lang.Linters) == 0 {
+		return resolvedTool{}, fmt.Errorf("no linter configured for file type: %s", filepath.Ext(absPath))
+	}
+	return resolveTool("linter", lang.Linters, absPath)
+}
+
+func resolveTool(kind string, candidates []ToolSpec, absPath string) (resolvedTool, error) {
+	var tried []string
+	for _, spec := range candidates {
+		tried = append(tried, spec.Command)
+		if _, err := exec.LookPath(spec.Command); err != nil {
+			continue
+		}
+
+		dir := filepath.Dir(absPath)
+		config := findProjectConfig(dir, spec.ConfigFiles)
+
+		workDir := dir
+		if config != "" {
+			workDir = filepath.Dir(config)
+		}
+		replacer := placeholders(absPath, dir, config, workDir)
+		args := expandArgs(spec.Args, replacer)
+		if config != "" {
+			args = append(args, expandArgs(spec.ConfigArgs, replacer)...)
+		}
+
+		return resolvedTool{
+			spec:   spec,
+			args:   args,
+			dir:    workDir,
+			config: config,
+		}, nil
+	}
+	return resolvedTool{}, &ToolMissingError{Kind: kind, Tools: tried}
+}
+
+// placeholders substitutes every placeholder in one pass, so a path that
+// happens to contain "{dir}" is never expanded a second time
+func placeholders(file, dir, config, root string) *strings.Replacer {
+	return strings.NewReplacer(
+		"{file}", file,
+		"{dir}", dir,
+		"{config}", config,
+		"{root}", root,
+	)
+}
+
+func expandArgs(args []string, replacer *strings.Replacer) []string {
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		expanded = append(expanded, replacer.Replace(arg))
+	}
+	return expanded
+}
+
+// findProjectConfig walks up from dir looking for the first matching config
+// file, stopping at the home directory or the filesystem root
+func findProjectConfig(dir string, names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	for {
+		for _, name := range names {
+			candidate := filepath.Join(dir, name)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if dir == homeDir || parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
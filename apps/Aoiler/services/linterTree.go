@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LinterRunSummary aggregates a directory-wide lint or format run
+type LinterRunSummary struct {
+	RunID          string         `json:"runId"`
+	Root           string         `json:"root"`
+	Mode           string         `json:"mode"`
+	FilesProcessed int            `json:"filesProcessed"`
+	FilesChanged   int            `json:"filesChanged"`
+	FilesFailed    int            `json:"filesFailed"`
+	FilesSkipped   int            `json:"filesSkipped"`
+	Diagnostics    map[string]int `json:"diagnostics"`
+	ToolsMissing   []string       `json:"toolsMissing,omitempty"`
+	Cancelled      bool           `json:"cancelled,omitempty"`
+	DurationMs     int64          `json:"durationMs"`
+}
+
+// LinterRunResult is returned once a directory run finishes. Per-file results
+// are also streamed as "linter:file" events while the run is in progress.
+type LinterRunResult struct {
+	Summary LinterRunSummary `json:"summary"`
+	Files   []LinterResult   `json:"files"`
+}
+
+// LinterFileEvent is emitted for every file as soon as its tool finishes
+type LinterFileEvent struct {
+	RunID     string       `json:"runId"`
+	Processed int          `json:"processed"`
+	Total     int          `json:"total"`
+	Result    LinterResult `json:"result"`
+}
+
+// RunTree lints or formats every supported file under root in parallel,
+// skipping anything ignored by git
+func (ls *LinterService) RunTree(ctx context.Context, root, mode string) (LinterRunResult, error) {
+	start := time.Now()
+
+	all, err := listProjectFiles(root)
+	if err != nil {
+		return LinterRunResult{}, fmt.Errorf("failed to list files in %s: %w", root, err)
+	}
+
+	var files []string
+	for _, path := range all {
+		if ls.registry.HasTool(path, mode) {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+
+	summary := LinterRunSummary{
+		RunID:       newID(),
+		Root:        root,
+		Mode:        mode,
+		Diagnostics: map[string]int{"error": 0, "warning": 0, "info": 0},
+	}
+	ls.emitter.emit("linter:start", map[string]interface{}{
+		"runId": summary.RunID,
+		"root":  root,
+		"mode":  mode,
+		"total": len(files),
+	})
+
+	jobs := make(chan lintBatch)
+	results := make(chan linterOutcome)
+
+	var wg sync.WaitGroup
+	for i := 0; i < ls.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				for _, outcome := range ls.runBatch(ctx, batch, mode) {
+					results <- outcome
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, batch := range ls.batchFiles(files, mode) {
+			jobs <- batch
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	missing := make(map[string]bool)
+	collected := make([]LinterResult, 0, len(files))
+	for outcome := range results {
+		result := outcome.result
+		collected = append(collected, result)
+		summary.FilesProcessed++
+
+		var toolErr *ToolMissingError
+		switch {
+		case errors.As(outcome.err, &toolErr):
+			summary.FilesSkipped++
+			for _, tool := range toolErr.Tools {
+				missing[tool] = true
+			}
+		case outcome.err != nil:
+			summary.FilesFailed++
+		case result.Changed:
+			summary.FilesChanged++
+		}
+		for _, d := range result.Diagnostics {
+			summary.Diagnostics[d.Severity]++
+		}
+
+		ls.emitter.emit("linter:file", LinterFileEvent{
+			RunID:     summary.RunID,
+			Processed: summary.FilesProcessed,
+			Total:     len(files),
+			Result:    result,
+		})
+		reportProgress(ctx, float64(summary.FilesProcessed)/float64(len(files)),
+			fmt.Sprintf("%d of %d files", summary.FilesProcessed, len(files)))
+	}
+
+	for tool := range missing {
+		summary.ToolsMissing = append(summary.ToolsMissing, tool)
+	}
+	sort.Strings(summary.ToolsMissing)
+	sort.Slice(collected, func(i, j int) bool { return collected[i].FilePath < collected[j].FilePath })
+
+	summary.Cancelled = ctx.Err() != nil
+	summary.DurationMs = time.Since(start).Milliseconds()
+	ls.emitter.emit("linter:done", summary)
+
+	return LinterRunResult{Summary: summary, Files: collected}, nil
+}
+
+// linterOutcome keeps the typed error next to the result for the summary
+type linterOutcome struct {
+	result LinterResult
+	err    error
+}
+
+// lintBatch is a unit of work for the RunTree workers. A shared batch holds
+// every file that one directory-scoped linter run covers.
+type lintBatch struct {
+	paths  []string
+	tool   resolvedTool
+	shared bool
+}
+
+// batchFiles groups files whose linter takes {dir} rather than {file}, like
+// go vet, so each directory is linted once instead of once per file
+func (ls *LinterService) batchFiles(files []string, mode string) []lintBatch {
+	var batches []lintBatch
+	shared := make(map[string]int)
+	for _, path := range files {
+		if mode == "check" {
+			absPath, _ := filepath.Abs(path)
+			if tool, err := ls.registry.Linter(absPath); err == nil && tool.dirScoped() {
+				key := tool.dir + "\x00" + tool.spec.Command + "\x00" + strings.Join(tool.args, "\x00")
+				if i, ok := shared[key]; ok {
+					batches[i].paths = append(batches[i].paths, path)
+					continue
+				}
+				shared[key] = len(batches)
+				batches = append(batches, lintBatch{paths: []string{path}, tool: tool, shared: true})
+				continue
+			}
+		}
+		batches = append(batches, lintBatch{paths: []string{path}})
+	}
+	return batches
+}
+
+// runBatch runs a shared linter once and splits its diagnostics per file
+func (ls *LinterService) runBatch(ctx context.Context, batch lintBatch, mode string) []linterOutcome {
+	if !batch.shared {
+		return []linterOutcome{ls.runFile(ctx, batch.paths[0], mode)}
+	}
+
+	var diagnostics []Diagnostic
+	var output string
+	err := ctx.Err()
+	if err == nil {
+		diagnostics, output, err = runLinter(ctx, batch.tool)
+	}
+
+	outcomes := make([]linterOutcome, 0, len(batch.paths))
+	for _, path := range batch.paths {
+		if err != nil {
+			outcomes = append(outcomes, linterOutcome{result: LinterResult{
+				Output:     output,
+				FilePath:   path,
+				LinterUsed: batch.tool.spec.Name,
+				Mode:       mode,
+				Error:      err.Error(),
+			}, err: err})
+			continue
+		}
+		absPath, _ := filepath.Abs(path)
+		outcomes = append(outcomes, linterOutcome{result: checkResult(path, absPath, batch.tool, diagnostics)})
+	}
+	return outcomes
+}
+
+func (ls *LinterService) runFile(ctx context.Context, path, mode string) linterOutcome {
+	var result LinterResult
+	var err error
+	switch {
+	case ctx.Err() != nil:
+		// Files still queued when the run is cancelled are not started
+		err = ctx.Err()
+	case mode == "check":
+		result, err = ls.Check(ctx, path)
+	default:
+		result, err = ls.Preview(ctx, path)
+	}
+
+	if err != nil {
+		result.FilePath = path
+		result.Mode = mode
+		result.Error = err.Error()
+	}
+	return linterOutcome{result: result, err: err}
+}
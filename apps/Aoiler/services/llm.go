@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -142,7 +143,7 @@ func (llm *LLMService) detectProvider() LLMProvider {
 }
 
 // Query sends a query to the configured LLM provider
-func (llm *LLMService) Query(query string) (LLMResult, error) {
+func (llm *LLMService) Query(ctx context.Context, query string) (LLMResult, error) {
 	if llm.provider == ProviderDefault {
 		return LLMResult{
 			Response: "No LLM API key configured. Please set one of:\n- OPENAI_API_KEY\n- CLAUDE_API_KEY\n- GEMINI_API_KEY",
@@ -152,11 +153,11 @@ func (llm *LLMService) Query(query string) (LLMResult, error) {
 
 	switch llm.provider {
 	case ProviderOpenAI:
-		return llm.queryOpenAI(query)
+		return llm.queryOpenAI(ctx, query)
 	case ProviderClaude:
-		return llm.queryClaude(query)
+		return llm.queryClaude(ctx, query)
 	case ProviderGemini:
-		return llm.queryGemini(query)
+		return llm.queryGemini(ctx, query)
 	default:
 		return LLMResult{
 			Response: "Unknown provider",
@@ -166,7 +167,7 @@ func (llm *LLMService) Query(query string) (LLMResult, error) {
 }
 
 // queryOpenAI sends a query to OpenAI API
-func (llm *LLMService) queryOpenAI(query string) (LLMResult, error) {
+func (llm *LLMService) queryOpenAI(ctx context.Context, query string) (LLMResult, error) {
 	url := "https://api.openai.com/v1/chat/completions"
 
 	reqBody := OpenAIRequest{
@@ -185,7 +186,7 @@ func (llm *LLMService) queryOpenAI(query string) (LLMResult, error) {
 		return LLMResult{Success: false}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return LLMResult{Success: false}, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -230,7 +231,7 @@ func (llm *LLMService) queryOpenAI(query string) (LLMResult, error) {
 }
 
 // queryClaude sends a query to Claude API
-func (llm *LLMService) queryClaude(query string) (LLMResult, error) {
+func (llm *LLMService) queryClaude(ctx context.Context, query string) (LLMResult, error) {
 	url := "https://api.anthropic.com/v1/messages"
 
 	reqBody := ClaudeRequest{
@@ -249,7 +250,7 @@ func (llm *LLMService) queryClaude(query string) (LLMResult, error) {
 		return LLMResult{Success: false}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return LLMResult{Success: false}, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -295,7 +296,7 @@ func (llm *LLMService) queryClaude(query string) (LLMResult, error) {
 }
 
 // queryGemini sends a query to Gemini API
-func (llm *LLMService) queryGemini(query string) (LLMResult, error) {
+func (llm *LLMService) queryGemini(ctx context.Context, query string) (LLMResult, error) {
 	model := llm.defaultModel[ProviderGemini]
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
 		model, llm.geminiKey)
@@ -315,7 +316,7 @@ func (llm *LLMService) queryGemini(query string) (LLMResult, error) {
 		return LLMResult{Success: false}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return LLMResult{Success: false}, fmt.Errorf("failed to create request: %w", err)
 	}
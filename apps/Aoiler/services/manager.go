@@ -1,7 +1,10 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -12,6 +15,22 @@ type Intent struct {
 	Params      map[string]string
 }
 
+// Keywords that route a query to each service. The help service builds its
+// typo vocabulary from the same lists.
+var (
+	fileSearchKeywords = []string{"find", "where is", "locate", "search for", "look for"}
+	probeKeywords      = []string{"probe", "media info", "mediainfo", "codecs", "streams in"}
+	// "compress ", "shrink " and "resize " are whole words so "compressed.png" isn't a request
+	converterKeywords = []string{"convert", "transcode", "change format", "encode", "extract audio", "compress ", "shrink ", "resize "}
+	organizerKeywords = []string{"organize", "clean", "sort", "tyr"}
+	linterKeywords    = []string{"lint", "format", "check code", "fix code"}
+	ocrKeywords       = []string{"ocr", "extract text", "read screen", "read text", "capture text", "screenshot text"}
+	launcherKeywords  = []string{"open", "launch", "start", "run"}
+)
+
+// helpPattern matches "help", "help ocr", "commands" and "what can you do"
+var helpPattern = regexp.MustCompile(`(?i)^(?:help|commands|what can you do)\s*(\w*)\s*\??$`)
+
 // ServiceManager manages all services
 type ServiceManager struct {
 	fileSearch *FileSearchService
@@ -19,27 +38,172 @@ type ServiceManager struct {
 	linter     *LinterService
 	ocr        *OCRService
 	converter  *ConverterService
+	document   *DocumentService
+	clipboard  *ClipboardService
+	calculator *CalculatorService
+	launcher   *LauncherService
+	desktop    *DesktopService
+	screenshot *ScreenshotService
+	notes      *NotesService
 	llm        *LLMService
+	jobs       *JobManager
+	history    *QueryHistory
+	help       *HelpService
+	emitter    EventEmitter
 }
 
 // NewServiceManager creates a new service manager
 func NewServiceManager() *ServiceManager {
-	return &ServiceManager{
+	cfg, err := LoadConfig()
+	if err != nil {
+		warnf("could not load config: %v", err)
+	}
+
+	sm := &ServiceManager{
 		fileSearch: NewFileSearchService(),
 		organizer:  NewOrganizerService(),
-		linter:     NewLinterService(),
-		ocr:        NewOCRService(),
-		converter:  NewConverterService(),
+		linter:     NewLinterService(cfg.Linters, cfg.LintWorkers),
+		ocr:        NewOCRService(cfg.OCR, cfg.OCRHistory),
+		converter:  NewConverterService(cfg.Converter),
+		document:   NewDocumentService(cfg.Document),
+		clipboard:  NewClipboardService(cfg.Clipboard),
+		calculator: NewCalculatorService(),
+		launcher:   NewLauncherService(cfg.Launcher),
+		desktop:    NewDesktopService(),
+		screenshot: NewScreenshotService(cfg.Screenshot),
+		notes:      NewNotesService(cfg.Notes),
 		llm:        NewLLMService(),
+		jobs:       NewJobManager(cfg.Jobs),
+		history:    NewQueryHistory(cfg.QueryHistory),
+	}
+	sm.help = NewHelpService(sm.serviceHelp())
+	sm.jobs.onFinish = func(job JobInfo) {
+		if err := sm.history.Record(job); err != nil {
+			warnf("could not save query history: %v", err)
+		}
+	}
+	return sm
+}
+
+// serviceHelp collects what each registered service says about itself, for
+// suggestions and typo correction. Services that can't run are left out.
+func (sm *ServiceManager) serviceHelp() []ServiceHelp {
+	help := []ServiceHelp{
+		sm.fileSearch.Help(),
+		sm.organizer.Help(),
+		sm.linter.Help(),
+		sm.ocr.Help(),
+		sm.converter.Help(),
+		sm.calculator.Help(),
+		sm.launcher.Help(),
+		sm.desktop.Help(),
+		sm.screenshot.Help(),
+		sm.notes.Help(),
+	}
+	if sm.document.Available() {
+		help = append(help, sm.document.Help())
+	}
+	if sm.clipboard.Available() {
+		help = append(help, sm.clipboard.Help())
 	}
+	return append(help, sm.llm.Help())
 }
 
-// ClassifyIntent uses keyword matching to determine intent
+// ClassifyIntent uses keyword matching to determine intent. Chained queries
+// like "ocr | llm summarize" are pipelines whose stages are classified as
+// they run.
 func (sm *ServiceManager) ClassifyIntent(query string) Intent {
-	lowerQuery := strings.ToLower(query)
+	if stages := sm.splitPipeline(query); len(stages) > 1 {
+		return Intent{
+			ServiceName: "pipeline",
+			Confidence:  0.9,
+			Params:      map[string]string{"query": query, "stages": strconv.Itoa(len(stages))},
+		}
+	}
+	return sm.classifyStage(query)
+}
+
+// classifyStage classifies a single query without looking for pipelines
+func (sm *ServiceManager) classifyStage(query string) Intent {
+	// Match keywords against the words only, so "~/formats/scan.png" isn't a format request
+	lowerQuery := strings.ToLower(stripPaths(query))
+
+	// Help, only for topics it knows so "help me write a script" goes to the LLM
+	if m := helpPattern.FindStringSubmatch(strings.TrimSpace(query)); m != nil && (m[1] == "" || sm.help.HasTopic(m[1])) {
+		return Intent{
+			ServiceName: "help",
+			Confidence:  0.9,
+			Params:      map[string]string{"topic": m[1]},
+		}
+	}
+
+	// Clipboard history, checked first since entries can contain any word
+	if m := clipboardPattern.FindStringSubmatch(query); m != nil {
+		return Intent{
+			ServiceName: "clipboard",
+			Confidence:  0.9,
+			Params:      map[string]string{"terms": strings.TrimSpace(m[1])},
+		}
+	}
+
+	// Notes and snippets, also ahead of everything else since a note can
+	// say "convert the slides" or "find the receipt"
+	if sm.notes.Handles(query) {
+		return Intent{
+			ServiceName: "notes",
+			Confidence:  0.9,
+			Params:      map[string]string{"query": query},
+		}
+	}
+
+	// Calculations and unit conversions, ahead of the converter so
+	// "convert 5 km to miles" doesn't go to ffmpeg
+	if sm.calculator.Handles(query) {
+		return Intent{
+			ServiceName: "calculator",
+			Confidence:  0.9,
+			Params:      map[string]string{"query": query},
+		}
+	}
+
+	// Desktop commands like "volume 40" or "move firefox to workspace 3"
+	if sm.desktop.Handles(query) {
+		return Intent{
+			ServiceName: "desktop",
+			Confidence:  0.9,
+			Params:      map[string]string{"query": query},
+		}
+	}
+
+	// Screenshots and recordings, anchored so "screenshot text" stays OCR
+	if sm.screenshot.Handles(query) {
+		return Intent{
+			ServiceName: "screenshot",
+			Confidence:  0.9,
+			Params:      map[string]string{"query": query},
+		}
+	}
+
+	// Apps, only when one matches so "open ~/report.pdf" goes elsewhere
+	if sm.launcher.Handles(query) {
+		return Intent{
+			ServiceName: "launcher",
+			Confidence:  0.9,
+			Params:      map[string]string{"query": query},
+		}
+	}
+
+	// OCR history search, ahead of the keyword checks so the search terms in
+	// "ocr history find invoice" or "ocr history convert" stay search terms
+	if m := ocrHistoryPattern.FindStringSubmatch(lowerQuery); m != nil {
+		return Intent{
+			ServiceName: "ocr",
+			Confidence:  0.9,
+			Params:      map[string]string{"action": "history", "terms": strings.TrimSpace(m[1])},
+		}
+	}
 
 	// File search patterns
-	fileSearchKeywords := []string{"find", "where is", "locate", "search for", "look for"}
 	for _, keyword := range fileSearchKeywords {
 		if strings.Contains(lowerQuery, keyword) {
 			return Intent{
@@ -50,8 +214,38 @@ func (sm *ServiceManager) ClassifyIntent(query string) Intent {
 		}
 	}
 
+	// Media probe, checked before conversion so "probe clip.mkv" only inspects it
+	for _, keyword := range probeKeywords {
+		if strings.Contains(lowerQuery, keyword) {
+			return Intent{
+				ServiceName: "converter",
+				Confidence:  0.9,
+				Params:      map[string]string{"query": query, "action": "probe"},
+			}
+		}
+	}
+
+	// Converter patterns, ahead of the organizer and linter so file names like
+	// "clean_take.wav" or "to mp4 format" don't steal the query
+	for _, keyword := range converterKeywords {
+		if strings.Contains(lowerQuery+" ", keyword) {
+			// Documents go to pandoc, LibreOffice and poppler instead of ffmpeg
+			if isDocumentQuery(query) {
+				return Intent{
+					ServiceName: "document",
+					Confidence:  0.9,
+					Params:      map[string]string{"query": query},
+				}
+			}
+			return Intent{
+				ServiceName: "converter",
+				Confidence:  0.9,
+				Params:      map[string]string{"query": query},
+			}
+		}
+	}
+
 	// Organizer patterns
-	organizerKeywords := []string{"organize", "clean", "sort", "tyr"}
 	for _, keyword := range organizerKeywords {
 		if strings.Contains(lowerQuery, keyword) {
 			params := make(map[string]string)
@@ -68,38 +262,43 @@ func (sm *ServiceManager) ClassifyIntent(query string) Intent {
 		}
 	}
 
-	// Linter patterns
-	linterKeywords := []string{"lint", "format", "check code", "fix code"}
-	for _, keyword := range linterKeywords {
-		if strings.Contains(lowerQuery, keyword) {
-			return Intent{
-				ServiceName: "linter",
-				Confidence:  0.9,
-				Params:      map[string]string{"query": query},
-			}
-		}
-	}
-
-	// OCR patterns
-	ocrKeywords := []string{"ocr", "extract text", "read screen", "capture text", "screenshot text"}
+	// OCR patterns, ahead of the linter so "ocr table format markdown" isn't
+	// a format request. File names are left out so "format ocr.py" still is.
+	ocrQuery := stripFileNames(lowerQuery)
 	for _, keyword := range ocrKeywords {
-		if strings.Contains(lowerQuery, keyword) {
+		if strings.Contains(ocrQuery, keyword) {
+			opts := parseOCROptions(query)
+			params := map[string]string{
+				"path": extractPath(query),
+				"lang": opts.Language,
+			}
+			if opts.PSM > 0 {
+				params["psm"] = strconv.Itoa(opts.PSM)
+			}
+			params["action"], params["format"] = parseOCRAction(query)
+			if strings.Contains(lowerQuery, "force") || strings.Contains(lowerQuery, "again") {
+				params["force"] = "true"
+			}
 			return Intent{
 				ServiceName: "ocr",
 				Confidence:  0.9,
-				Params:      map[string]string{},
+				Params:      params,
 			}
 		}
 	}
 
-	// Converter patterns
-	converterKeywords := []string{"convert", "transcode", "change format", "encode"}
-	for _, keyword := range converterKeywords {
+	// Linter patterns
+	for _, keyword := range linterKeywords {
 		if strings.Contains(lowerQuery, keyword) {
+			mode := "format"
+			if (strings.Contains(lowerQuery, "lint") || strings.Contains(lowerQuery, "check")) &&
+				!strings.Contains(lowerQuery, "format") && !strings.Contains(lowerQuery, "fix") {
+				mode = "check"
+			}
 			return Intent{
-				ServiceName: "converter",
+				ServiceName: "linter",
 				Confidence:  0.9,
-				Params:      map[string]string{"query": query},
+				Params:      map[string]string{"query": query, "mode": mode},
 			}
 		}
 	}
@@ -112,26 +311,162 @@ func (sm *ServiceManager) ClassifyIntent(query string) Intent {
 	}
 }
 
-// RouteToService routes the query to appropriate service
-func (sm *ServiceManager) RouteToService(intent Intent, query string) (interface{}, error) {
+// Submit classifies a query and runs it as a background job. The result is
+// delivered through "job:update" events and Jobs().Get.
+func (sm *ServiceManager) Submit(query string) JobInfo {
+	intent := sm.ClassifyIntent(query)
+	return sm.jobs.Start(intent.ServiceName, query, intent.Params, func(ctx context.Context) (interface{}, error) {
+		return sm.RouteToService(ctx, intent, query)
+	})
+}
+
+// RouteToService routes the query to appropriate service. Cancelling ctx
+// stops whatever tool or request the service is running.
+func (sm *ServiceManager) RouteToService(ctx context.Context, intent Intent, query string) (interface{}, error) {
 	switch intent.ServiceName {
 	case "filesearch":
-		return sm.fileSearch.Search(query)
+		return sm.fileSearch.Search(ctx, query)
 	case "organizer":
 		mode := intent.Params["mode"]
 		if mode == "" {
 			mode = "category"
 		}
-		return sm.organizer.Organize(query, mode)
+		return sm.organizer.Organize(ctx, query, mode)
 	case "linter":
-		return sm.linter.LintFormat(query)
+		mode := intent.Params["mode"]
+		if mode == "" {
+			mode = "format"
+		}
+		if root := expandHome(extractPath(query)); isDirectory(root) {
+			return sm.linter.RunTree(ctx, root, mode)
+		}
+		return sm.linter.Run(ctx, query, mode)
 	case "ocr":
-		return sm.ocr.ExtractText()
+		if intent.Params["action"] == "history" {
+			return sm.ocr.SearchHistory(intent.Params["terms"])
+		}
+		psm, _ := strconv.Atoi(intent.Params["psm"])
+		opts := OCROptions{Language: intent.Params["lang"], PSM: psm}
+
+		var result OCRResult
+		var err error
+		if root := expandHome(intent.Params["path"]); isDirectory(root) {
+			return sm.ocr.ExtractTextFromDir(ctx, root, opts, intent.Params["force"] == "true")
+		}
+		if path := intent.Params["path"]; path != "" {
+			result, err = sm.ocr.ExtractTextFromFile(ctx, expandHome(path), opts)
+		} else {
+			result, err = sm.ocr.ExtractText(ctx, opts)
+		}
+		if err != nil {
+			return result, err
+		}
+		// A failed follow-up like wl-copy mustn't lose the text that was read
+		if err := sm.ocr.ApplyAction(&result, intent.Params["action"], intent.Params["format"]); err != nil {
+			result.ActionError = err.Error()
+		}
+		return result, nil
 	case "converter":
-		return sm.converter.Convert(query)
+		if intent.Params["action"] == "probe" {
+			return sm.converter.Probe(ctx, query)
+		}
+		if isBatchQuery(query) {
+			return sm.converter.ConvertBatch(ctx, query)
+		}
+		return sm.converter.Convert(ctx, query)
+	case "document":
+		return sm.document.Convert(ctx, query)
 	case "llm":
-		return sm.llm.Query(query)
+		return sm.llm.Query(ctx, query)
+	case "pipeline":
+		return sm.RunPipeline(ctx, query)
+	case "clipboard":
+		return sm.clipboard.Search(ctx, intent.Params["terms"])
+	case "calculator":
+		return sm.calculator.Evaluate(query)
+	case "launcher":
+		return sm.launcher.Launch(ctx, query)
+	case "desktop":
+		return sm.desktop.Run(ctx, query)
+	case "screenshot":
+		return sm.screenshot.Capture(ctx, query)
+	case "notes":
+		return sm.notes.Run(ctx, query)
+	case "help":
+		return sm.help.Help(intent.Params["topic"])
 	default:
 		return nil, fmt.Errorf("unknown service: %s", intent.ServiceName)
 	}
 }
+
+// SetEmitter wires progress events from long-running services to the UI
+func (sm *ServiceManager) SetEmitter(emitter EventEmitter) {
+	sm.emitter = emitter
+	sm.linter.emitter = emitter
+	sm.ocr.emitter = emitter
+	sm.converter.emitter = emitter
+	sm.screenshot.emitter = emitter
+	sm.jobs.emitter = emitter
+}
+
+// Linter exposes the linter service so format previews can be applied later
+func (sm *ServiceManager) Linter() *LinterService {
+	return sm.linter
+}
+
+// OCR exposes the OCR service so stored captures can be managed from the UI
+func (sm *ServiceManager) OCR() *OCRService {
+	return sm.ocr
+}
+
+// Converter exposes the converter service so running jobs can be cancelled
+func (sm *ServiceManager) Converter() *ConverterService {
+	return sm.converter
+}
+
+// Jobs exposes running and finished queries for the jobs panel
+func (sm *ServiceManager) Jobs() *JobManager {
+	return sm.jobs
+}
+
+// History exposes past queries for search, replay and pinning
+func (sm *ServiceManager) History() *QueryHistory {
+	return sm.history
+}
+
+// Clipboard exposes the clipboard history so entries can be pasted, pinned
+// and deleted from the UI
+func (sm *ServiceManager) Clipboard() *ClipboardService {
+	return sm.clipboard
+}
+
+// Launcher exposes the app launcher so other matches can be started from the UI
+func (sm *ServiceManager) Launcher() *LauncherService {
+	return sm.launcher
+}
+
+// Desktop exposes the desktop service so disruptive changes can be confirmed
+func (sm *ServiceManager) Desktop() *DesktopService {
+	return sm.desktop
+}
+
+// Screenshot exposes screen capture so running recordings can be stopped
+func (sm *ServiceManager) Screenshot() *ScreenshotService {
+	return sm.screenshot
+}
+
+// Notes exposes saved notes and snippets so they can be pasted and deleted
+// from the UI
+func (sm *ServiceManager) Notes() *NotesService {
+	return sm.notes
+}
+
+// Help exposes suggestions, examples and typo corrections for the input box
+func (sm *ServiceManager) Help() *HelpService {
+	return sm.help
+}
+
+// Document exposes the document service so the UI can list available routes
+func (sm *ServiceManager) Document() *DocumentService {
+	return sm.document
+}
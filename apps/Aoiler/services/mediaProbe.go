@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MediaInfo is what ffprobe knows about a file
+type MediaInfo struct {
+	Path      string        `json:"path"`
+	Container string        `json:"container"`
+	Duration  float64       `json:"duration,omitempty"`
+	BitRate   int64         `json:"bitRate,omitempty"`
+	Size      int64         `json:"size,omitempty"`
+	Frames    int           `json:"frames,omitempty"`
+	Streams   []MediaStream `json:"streams"`
+}
+
+// MediaStream is a single video, audio or subtitle stream
+type MediaStream struct {
+	Index      int     `json:"index"`
+	Type       string  `json:"type"`
+	Codec      string  `json:"codec"`
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	FPS        float64 `json:"fps,omitempty"`
+	Channels   int     `json:"channels,omitempty"`
+	SampleRate int     `json:"sampleRate,omitempty"`
+	BitRate    int64   `json:"bitRate,omitempty"`
+	Language   string  `json:"language,omitempty"`
+	// CoverArt marks embedded album art, which ffprobe lists as video
+	CoverArt bool `json:"coverArt,omitempty"`
+}
+
+// ffprobeOutput mirrors the parts of `ffprobe -print_format json` we read.
+// ffprobe prints most numbers as strings.
+type ffprobeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+		Size       string `json:"size"`
+	} `json:"format"`
+	Streams []struct {
+		Index        int    `json:"index"`
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		Channels     int    `json:"channels"`
+		SampleRate   string `json:"sample_rate"`
+		BitRate      string `json:"bit_rate"`
+		NbFrames     string `json:"nb_frames"`
+		Tags         struct {
+			Language string `json:"language"`
+		} `json:"tags"`
+		Disposition struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+}
+
+// probeMedia runs ffprobe on a file
+func probeMedia(ctx context.Context, path string) (MediaInfo, error) {
+	output, err := exec.CommandContext(ctx, "ffprobe", "-v", "error",
+		"-print_format", "json", "-show_format", "-show_streams", path).Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			if isImageFormat(strings.TrimPrefix(filepath.Ext(path), ".")) {
+				return probeImage(path)
+			}
+			return MediaInfo{}, fmt.Errorf("ffprobe is not installed")
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return MediaInfo{}, fmt.Errorf("ffprobe failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return MediaInfo{}, err
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return MediaInfo{}, fmt.Errorf("unexpected ffprobe output: %w", err)
+	}
+
+	info := MediaInfo{
+		Path:      path,
+		Container: probe.Format.FormatName,
+		Streams:   []MediaStream{},
+	}
+	info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	info.BitRate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+	info.Size, _ = strconv.ParseInt(probe.Format.Size, 10, 64)
+
+	for _, s := range probe.Streams {
+		stream := MediaStream{
+			Index:    s.Index,
+			Type:     s.CodecType,
+			Codec:    s.CodecName,
+			Width:    s.Width,
+			Height:   s.Height,
+			FPS:      parseFrameRate(s.AvgFrameRate),
+			Channels: s.Channels,
+			Language: s.Tags.Language,
+			CoverArt: s.Disposition.AttachedPic == 1,
+		}
+		stream.SampleRate, _ = strconv.Atoi(s.SampleRate)
+		stream.BitRate, _ = strconv.ParseInt(s.BitRate, 10, 64)
+		if frames, err := strconv.Atoi(s.NbFrames); err == nil && s.CodecType == "video" && frames > info.Frames {
+			info.Frames = frames
+		}
+		info.Streams = append(info.Streams, stream)
+	}
+
+	// The gif demuxer doesn't report a frame count, count them ourselves
+	if strings.EqualFold(filepath.Ext(path), ".gif") && info.Frames == 0 {
+		if data, err := os.ReadFile(path); err == nil {
+			info.Frames = gifFrameCount(data)
+		}
+	}
+	return info, nil
+}
+
+// probeImage reads an image header in Go, so still image conversions can be
+// checked without ffprobe
+func probeImage(path string) (MediaInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MediaInfo{}, err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return MediaInfo{}, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	info := MediaInfo{
+		Path:      path,
+		Container: format,
+		Size:      int64(len(data)),
+		Streams: []MediaStream{{
+			Type:   "video",
+			Codec:  format,
+			Width:  cfg.Width,
+			Height: cfg.Height,
+		}},
+	}
+	if format == "gif" {
+		info.Frames = gifFrameCount(data)
+	}
+	return info, nil
+}
+
+// parseFrameRate turns ffprobe's "30000/1001" into 29.97
+func parseFrameRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		f, _ := strconv.ParseFloat(rate, 64)
+		return f
+	}
+	n, _ := strconv.ParseFloat(num, 64)
+	d, _ := strconv.ParseFloat(den, 64)
+	if d == 0 {
+		return 0
+	}
+	return n / d
+}
+
+// HasVideo reports whether there is a real video stream, not just cover art
+func (info MediaInfo) HasVideo() bool {
+	for _, s := range info.Streams {
+		if s.Type == "video" && !s.CoverArt {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAudio reports whether there is at least one audio stream
+func (info MediaInfo) HasAudio() bool {
+	for _, s := range info.Streams {
+		if s.Type == "audio" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPicture reports any video stream, cover art included
+func (info MediaInfo) HasPicture() bool {
+	for _, s := range info.Streams {
+		if s.Type == "video" {
+			return true
+		}
+	}
+	return false
+}
+
+// Animated reports a multi-frame image such as an animated GIF or WebP.
+// Videos have frames too but aren't images.
+func (info MediaInfo) Animated() bool {
+	return info.Frames > 1 && isImageFormat(strings.TrimPrefix(filepath.Ext(info.Path), "."))
+}
+
+// videoContainers hold video; audio-only input should go to an audio format
+var videoContainers = map[string]bool{
+	"mp4": true, "webm": true, "mkv": true, "avi": true, "mov": true,
+}
+
+// validateConversion catches requests that ffmpeg would either reject with a
+// cryptic error or quietly turn into something the user didn't want
+func validateConversion(info MediaInfo, opts ConversionOptions) error {
+	name := filepath.Base(info.Path)
+	target := opts.Format
+
+	switch {
+	case opts.AudioOnly && opts.NoAudio:
+		return fmt.Errorf("can't both keep only the audio and remove it")
+	case opts.NoAudio && audioFormats[target]:
+		return fmt.Errorf("can't remove the audio when converting to %s, an audio format", target)
+	case videoContainers[target] && !info.HasVideo() && !opts.AudioOnly:
+		return fmt.Errorf("%s has no video stream, convert it to an audio format such as mp3, opus or m4a instead", name)
+	case (audioFormats[target] || opts.AudioOnly) && !info.HasAudio():
+		return fmt.Errorf("%s has no audio stream to extract", name)
+	case isImageFormat(target) && !info.HasPicture():
+		return fmt.Errorf("%s has no picture to convert to %s", name, target)
+	case info.Animated() && isImageFormat(target) && target != "gif" && target != "webp":
+		return fmt.Errorf("%s is animated and %s would keep only the first frame; convert it to gif, webp, mp4 or webm instead", name, target)
+	case opts.Start > 0 && info.Duration > 0 && opts.Start >= info.Duration:
+		return fmt.Errorf("%s is only %s long, it can't start at %s", name, formatClock(info.Duration), formatClock(opts.Start))
+	}
+	return nil
+}
+
+// formatClock renders seconds as m:ss or h:mm:ss
+func formatClock(seconds float64) string {
+	s := int(seconds + 0.5)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s%3600/60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// Probe answers "probe clip.mkv" or "media info song.flac"
+func (cs *ConverterService) Probe(ctx context.Context, query string) (MediaInfo, error) {
+	path := expandHome(extractMediaPath(query))
+	if path == "" {
+		return MediaInfo{}, fmt.Errorf("no input file found")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return MediaInfo{}, fmt.Errorf("input file does not exist: %s", path)
+	}
+	return probeMedia(ctx, path)
+}
@@ -0,0 +1,498 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	notesSearchLimit = 50
+	// noteTitleLimit cuts a note's first line down to a title
+	noteTitleLimit = 60
+	snippetsDir    = "snippets"
+)
+
+// Notes commands, anchored at the start since a note can contain any word
+var (
+	// "note buy cables #shopping", "new note: call the landlord"
+	noteAddPattern = regexp.MustCompile(`(?is)^\s*(?:(?:add|new|take)\s+(?:a\s+)?)?note\s*:?\s+(.+)$`)
+	// "snippet save docker prune command", "save snippet ssh tunnel: ssh -L ..."
+	snippetSavePattern = regexp.MustCompile(`(?is)^\s*(?:snippet\s+(?:save|add|new)|(?:save|add|new)\s+snippet)\s+(.+)$`)
+	// "notes docker", "snippets #git", "search notes for cables"
+	notesSearchPattern = regexp.MustCompile(`(?i)^\s*(?:search\s+)?(notes|snippets)(?:\s+(?:for|about)\b)?(?:\s+(.*))?$`)
+	// Tags start with a letter so "fix #42" isn't tagged 42
+	noteTagPattern = regexp.MustCompile(`(?:^|\s)#(\p{L}[\p{L}\p{N}_-]*)`)
+	// snippetNamePattern splits "name: text", a colon in a URL doesn't count
+	snippetNamePattern = regexp.MustCompile(`(?s)^([^:\n]+?):\s+(.+)$`)
+)
+
+// NotesConfig sets where notes and snippets are kept
+type NotesConfig struct {
+	// Directory holds one Markdown file per note, snippets in its snippets
+	// folder (default $XDG_DOCUMENTS_DIR/Notes)
+	Directory string `json:"directory,omitempty"`
+}
+
+// Note is a Markdown note or snippet
+type Note struct {
+	// ID is the file's path relative to the notes directory
+	ID    string   `json:"id"`
+	Kind  string   `json:"kind"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags,omitempty"`
+	// Content is the note's text, for snippets the code without its fence
+	Content string    `json:"content"`
+	Path    string    `json:"path"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// NotesResult is a saved note, or the notes matching a search newest first
+type NotesResult struct {
+	// Action is saved or search
+	Action string `json:"action"`
+	Kind   string `json:"kind,omitempty"`
+	Terms  string `json:"terms,omitempty"`
+	Notes  []Note `json:"notes"`
+	Total  int    `json:"total"`
+}
+
+// NotesService keeps quick notes and snippets as Markdown files with their
+// title, tags and timestamps in YAML front matter, so any editor can open
+// them
+type NotesService struct {
+	cfg NotesConfig
+}
+
+func NewNotesService(cfg NotesConfig) *NotesService {
+	if cfg.Directory == "" {
+		cfg.Directory = filepath.Join(xdgUserDir("XDG_DOCUMENTS_DIR", "Documents"), "Notes")
+	}
+	cfg.Directory = expandHome(cfg.Directory)
+	return &NotesService{cfg: cfg}
+}
+
+// Handles reports whether a query saves or searches notes
+func (ns *NotesService) Handles(query string) bool {
+	return noteAddPattern.MatchString(query) || snippetSavePattern.MatchString(query) || notesSearchPattern.MatchString(query)
+}
+
+// Run saves a note or snippet, or searches them
+func (ns *NotesService) Run(ctx context.Context, query string) (NotesResult, error) {
+	if m := snippetSavePattern.FindStringSubmatch(query); m != nil {
+		return ns.saveSnippet(ctx, m[1])
+	}
+	if m := notesSearchPattern.FindStringSubmatch(query); m != nil {
+		kind := ""
+		if strings.EqualFold(m[1], "snippets") {
+			kind = "snippet"
+		}
+		return ns.Search(m[2], kind)
+	}
+	if m := noteAddPattern.FindStringSubmatch(query); m != nil {
+		return ns.saveNote(m[1])
+	}
+	return NotesResult{}, fmt.Errorf("not a notes command: %s", query)
+}
+
+// redactNotesQuery replaces the text a save command stores with "…" so it
+// stays out of the query history. A snippet keeps its name. ok is false for
+// searches and for snippets taken from the clipboard, which store nothing.
+func redactNotesQuery(query string) (string, bool) {
+	if m := snippetSavePattern.FindStringSubmatchIndex(query); m != nil {
+		if n := snippetNamePattern.FindStringSubmatch(query[m[2]:m[3]]); n != nil {
+			return query[:m[2]] + n[1] + ": …", true
+		}
+		return query, false
+	}
+	if notesSearchPattern.MatchString(query) {
+		return query, false
+	}
+	if m := noteAddPattern.FindStringSubmatchIndex(query); m != nil {
+		return query[:m[2]] + "…", true
+	}
+	return query, false
+}
+
+func (ns *NotesService) saveNote(text string) (NotesResult, error) {
+	text, tags := splitTags(text)
+	if text == "" {
+		return NotesResult{Action: "saved", Kind: "note"}, fmt.Errorf("the note is empty")
+	}
+	note, err := ns.write("note", noteTitle(text), tags, text)
+	if err != nil {
+		return NotesResult{Action: "saved", Kind: "note"}, err
+	}
+	return NotesResult{Action: "saved", Kind: "note", Notes: []Note{note}, Total: 1}, nil
+}
+
+// saveSnippet stores "name: text" as given, or the clipboard under the
+// name when there's no text
+func (ns *NotesService) saveSnippet(ctx context.Context, text string) (NotesResult, error) {
+	result := NotesResult{Action: "saved", Kind: "snippet"}
+	name, content := text, ""
+	if m := snippetNamePattern.FindStringSubmatch(text); m != nil {
+		name, content = m[1], m[2]
+	}
+	name, tags := splitTags(name)
+	content = strings.Trim(content, " \t\r\n")
+
+	if content == "" {
+		clip, err := readClipboardText(ctx)
+		if err != nil {
+			return result, fmt.Errorf("%v, give the snippet as \"snippet save name: text\"", err)
+		}
+		content = clip
+	}
+	if name == "" {
+		name = noteTitle(content)
+	}
+
+	note, err := ns.write("snippet", name, tags, content)
+	if err != nil {
+		return result, err
+	}
+	result.Notes = []Note{note}
+	result.Total = 1
+	return result, nil
+}
+
+// write creates a new note file, never replacing an existing one
+func (ns *NotesService) write(kind, title string, tags []string, content string) (Note, error) {
+	dir := ns.cfg.Directory
+	name := noteSlug(title) + ".md"
+	if kind == "snippet" {
+		dir = filepath.Join(dir, snippetsDir)
+	} else {
+		name = time.Now().Format("2006-01-02") + "-" + name
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Note{}, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	note := Note{Kind: kind, Title: title, Tags: tags, Content: content, Path: uniquePath(filepath.Join(dir, name)), Created: now, Updated: now}
+	note.ID, _ = filepath.Rel(ns.cfg.Directory, note.Path)
+	if err := writeFileAtomic(note.Path, []byte(formatNote(note))); err != nil {
+		return Note{}, fmt.Errorf("failed to save %s: %w", note.Path, err)
+	}
+	if kind == "snippet" {
+		note.Content = unfence(content)
+	}
+	return note, nil
+}
+
+// Search lists notes matching every term, newest first. Terms starting with
+// # match tags, the rest the title, tags and text. kind limits the search to
+// "note" or "snippet".
+func (ns *NotesService) Search(terms, kind string) (NotesResult, error) {
+	terms = strings.TrimSpace(terms)
+	result := NotesResult{Action: "search", Kind: kind, Terms: terms, Notes: []Note{}}
+	notes, err := ns.load()
+	if err != nil {
+		return result, err
+	}
+
+	var words, tags []string
+	for _, term := range strings.Fields(strings.ToLower(terms)) {
+		if strings.HasPrefix(term, "#") && len(term) > 1 {
+			tags = append(tags, term[1:])
+		} else {
+			words = append(words, term)
+		}
+	}
+
+	for _, note := range notes {
+		if kind != "" && note.Kind != kind {
+			continue
+		}
+		if !hasTags(note, tags) || !matchesAll(note.Title+"\n"+strings.Join(note.Tags, " ")+"\n"+note.Content, words) {
+			continue
+		}
+		result.Total++
+		if len(result.Notes) < notesSearchLimit {
+			result.Notes = append(result.Notes, note)
+		}
+	}
+	return result, nil
+}
+
+// Get reads one note by ID
+func (ns *NotesService) Get(id string) (Note, error) {
+	path, err := ns.pathFor(id)
+	if err != nil {
+		return Note{}, err
+	}
+	return ns.read(path)
+}
+
+// Delete removes a note or snippet file
+func (ns *NotesService) Delete(id string) (bool, error) {
+	path, err := ns.pathFor(id)
+	if err != nil {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// pathFor resolves an ID, refusing ones that leave the notes directory
+func (ns *NotesService) pathFor(id string) (string, error) {
+	path := filepath.Join(ns.cfg.Directory, id)
+	if rel, err := filepath.Rel(ns.cfg.Directory, path); err != nil || strings.HasPrefix(rel, "..") || filepath.Ext(path) != ".md" {
+		return "", fmt.Errorf("no note with id %s", id)
+	}
+	return path, nil
+}
+
+// load reads every Markdown file under the notes directory, newest first
+func (ns *NotesService) load() ([]Note, error) {
+	var notes []Note
+	err := filepath.WalkDir(ns.cfg.Directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == ns.cfg.Directory {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != ns.cfg.Directory && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".md" {
+			return nil
+		}
+		note, err := ns.read(path)
+		if err != nil {
+			warnf("skipping note %s: %v", path, err)
+			return nil
+		}
+		notes = append(notes, note)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes: %w", err)
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].Updated.After(notes[j].Updated)
+	})
+	return notes, nil
+}
+
+// read parses a note file. Files written by hand may lack front matter, then
+// the first heading or the file name is the title and the file's times are
+// the timestamps.
+func (ns *NotesService) read(path string) (Note, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Note{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Note{}, err
+	}
+
+	note := Note{Kind: "note", Path: path, Created: info.ModTime(), Updated: info.ModTime()}
+	note.ID, _ = filepath.Rel(ns.cfg.Directory, path)
+	if strings.HasPrefix(filepath.ToSlash(note.ID), snippetsDir+"/") {
+		note.Kind = "snippet"
+	}
+
+	body := parseFrontMatter(string(data), &note)
+	body = strings.Trim(body, "\r\n")
+	if note.Title == "" {
+		if heading, rest, ok := strings.Cut(body, "\n"); strings.HasPrefix(heading, "# ") {
+			note.Title = strings.TrimSpace(heading[2:])
+			if ok {
+				body = strings.TrimLeft(rest, "\r\n")
+			} else {
+				body = ""
+			}
+		} else {
+			note.Title = strings.TrimSuffix(filepath.Base(path), ".md")
+		}
+	}
+	if note.Kind == "snippet" {
+		body = unfence(body)
+	}
+	note.Content = body
+	return note, nil
+}
+
+// parseFrontMatter fills the note from a leading --- block and returns the
+// rest of the file. Only the keys Aoiler writes are read.
+func parseFrontMatter(data string, note *Note) string {
+	if !strings.HasPrefix(data, "---\n") && !strings.HasPrefix(data, "---\r\n") {
+		return data
+	}
+	_, rest, _ := strings.Cut(data, "\n")
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return data
+	}
+
+	for _, line := range strings.Split(rest[:end], "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `"'`)
+		}
+		switch strings.TrimSpace(key) {
+		case "title":
+			note.Title = value
+		case "tags":
+			for _, tag := range strings.Split(strings.Trim(value, "[]"), ",") {
+				if tag = strings.Trim(strings.TrimSpace(tag), `"'#`); tag != "" {
+					note.Tags = append(note.Tags, tag)
+				}
+			}
+		case "created":
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				note.Created = t
+			}
+		case "updated":
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				note.Updated = t
+			}
+		}
+	}
+
+	rest = rest[end+len("\n---"):]
+	_, body, _ := strings.Cut(rest, "\n")
+	return body
+}
+
+// formatNote renders a note as Markdown with YAML front matter. Snippets go
+// in a code fence long enough not to clash with backticks in them, unless
+// they were given as one.
+func formatNote(note Note) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %q\n", note.Title)
+	if len(note.Tags) > 0 {
+		fmt.Fprintf(&b, "tags: [%s]\n", strings.Join(note.Tags, ", "))
+	}
+	fmt.Fprintf(&b, "created: %s\n", note.Created.Format(time.RFC3339))
+	fmt.Fprintf(&b, "updated: %s\n", note.Updated.Format(time.RFC3339))
+	b.WriteString("---\n\n")
+
+	if note.Kind == "snippet" && unfence(note.Content) == note.Content {
+		fence := "```"
+		for strings.Contains(note.Content, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "%s\n%s\n%s\n", fence, note.Content, fence)
+	} else {
+		b.WriteString(note.Content + "\n")
+	}
+	return b.String()
+}
+
+// unfence returns the code of a body that is a single fenced block, or the
+// body unchanged
+func unfence(body string) string {
+	lines := strings.Split(body, "\n")
+	if len(lines) < 2 {
+		return body
+	}
+	open := strings.TrimSpace(lines[0])
+	fence := open[:len(open)-len(strings.TrimLeft(open, "`"))]
+	if len(fence) < 3 || strings.TrimSpace(lines[len(lines)-1]) != fence {
+		return body
+	}
+	return strings.Join(lines[1:len(lines)-1], "\n")
+}
+
+// splitTags takes the #tags out of text
+func splitTags(text string) (string, []string) {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, m := range noteTagPattern.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(m[1])
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	text = noteTagPattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(text), tags
+}
+
+func hasTags(note Note, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range note.Tags {
+			if strings.EqualFold(t, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// noteTitle is a note's first line, cut at a word boundary
+func noteTitle(text string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	title = strings.TrimSpace(title)
+	if len([]rune(title)) <= noteTitleLimit {
+		return title
+	}
+	cut := string([]rune(title)[:noteTitleLimit])
+	if i := strings.LastIndex(cut, " "); i > noteTitleLimit/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
+
+// noteSlug turns a title into a file name, "Buy cables!" into "buy-cables"
+func noteSlug(title string) string {
+	slug := strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "-")
+	if runes := []rune(slug); len(runes) > 48 {
+		slug = strings.TrimRight(string(runes[:48]), "-")
+	}
+	if slug == "" {
+		return "note"
+	}
+	return slug
+}
+
+// readClipboardText returns the clipboard's text with wl-paste
+func readClipboardText(ctx context.Context) (string, error) {
+	if _, err := exec.LookPath("wl-paste"); err != nil {
+		return "", fmt.Errorf("wl-paste is not installed")
+	}
+	output, err := exec.CommandContext(ctx, "wl-paste", "--no-newline", "--type", "text").Output()
+	if err != nil || strings.TrimSpace(string(output)) == "" {
+		return "", fmt.Errorf("the clipboard has no text")
+	}
+	return string(output), nil
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// OCREntity is something actionable found in recognized text
+type OCREntity struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// entityPatterns are checked in order; earlier types claim their matches so an
+// email's domain isn't reported again as a URL
+var entityPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{"url", regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)},
+	{"date", regexp.MustCompile(`(?i)\b(?:\d{4}-\d{2}-\d{2}|\d{1,2}[/.]\d{1,2}[/.]\d{2,4}|\d{1,2}\s+(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{4}|(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{1,2},?\s+\d{4})\b`)},
+	{"phone", regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{1,4}\)[\s.-]?)?\d{2,4}(?:[\s.-]\d{2,4}){1,3}`)},
+	{"color", regexp.MustCompile(`#(?:[0-9a-fA-F]{8}|[0-9a-fA-F]{6}|[0-9a-fA-F]{3})\b`)},
+}
+
+// extractEntities finds URLs, emails, phone numbers, dates and hex colors
+func extractEntities(text string) []OCREntity {
+	var entities []OCREntity
+	seen := make(map[string]bool)
+	remaining := text
+
+	for _, ep := range entityPatterns {
+		for _, match := range ep.pattern.FindAllString(remaining, -1) {
+			value := strings.TrimRight(match, ".,;:!?)]}")
+			if ep.kind == "phone" && !looksLikePhone(value) {
+				continue
+			}
+			key := ep.kind + "\x00" + value
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			entities = append(entities, OCREntity{Type: ep.kind, Value: value})
+		}
+		// Blank out matches so later, looser patterns don't pick them apart
+		remaining = ep.pattern.ReplaceAllStringFunc(remaining, func(m string) string {
+			return strings.Repeat(" ", len(m))
+		})
+	}
+	return entities
+}
+
+// looksLikePhone rejects short digit runs such as times or prices
+func looksLikePhone(value string) bool {
+	digits := 0
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15
+}
+
+// ApplyAction runs a follow-up action requested in the query on an OCR result:
+// "copy" puts the text on the clipboard, "url" opens the first detected link
+// and "table" rebuilds a table from the word boxes in the given format
+func (ocr *OCRService) ApplyAction(result *OCRResult, action, format string) error {
+	switch action {
+	case "":
+		return nil
+	case "copy":
+		if err := CopyToClipboard(result.Text); err != nil {
+			return err
+		}
+		result.Copied = true
+	case "url":
+		for _, entity := range result.Entities {
+			if entity.Type == "url" {
+				if err := OpenURL(entity.Value); err != nil {
+					return err
+				}
+				result.OpenedURL = entity.Value
+				return nil
+			}
+		}
+		return fmt.Errorf("no URL found in the extracted text")
+	case "table":
+		if format == "" {
+			format = "csv"
+		}
+		table, err := BuildTable(result.Words, format)
+		if err != nil {
+			return err
+		}
+		result.Table = table
+		result.TableFormat = format
+	default:
+		return fmt.Errorf("unknown OCR action: %s", action)
+	}
+	return nil
+}
+
+// parseOCRAction picks the follow-up action and table format out of a query
+func parseOCRAction(query string) (string, string) {
+	lowerQuery := strings.ToLower(stripPaths(query))
+
+	format := ""
+	if strings.Contains(lowerQuery, "markdown") || strings.Contains(lowerQuery, " md") {
+		format = "markdown"
+	} else if strings.Contains(lowerQuery, "csv") {
+		format = "csv"
+	}
+
+	switch {
+	case strings.Contains(lowerQuery, "table") || format != "":
+		return "table", format
+	case strings.Contains(lowerQuery, "open url") || strings.Contains(lowerQuery, "open link"):
+		return "url", ""
+	case strings.Contains(lowerQuery, "copy") || strings.Contains(lowerQuery, "clipboard"):
+		return "copy", ""
+	}
+	return "", ""
+}
+
+// CopyToClipboard puts text on the Wayland clipboard
+func CopyToClipboard(text string) error {
+	cmd := exec.Command("wl-copy")
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("wl-copy failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// OpenURL hands a link to the default browser. The frontend can pass any
+// string, so only web and mail links get through: xdg-open would just as
+// happily open a file:// path or another scheme's handler.
+func OpenURL(rawURL string) error {
+	rawURL = strings.TrimSpace(rawURL)
+	if strings.HasPrefix(rawURL, "-") {
+		return fmt.Errorf("not a link: %s", rawURL)
+	}
+	if !strings.Contains(rawURL, "://") && !strings.HasPrefix(strings.ToLower(rawURL), "mailto:") {
+		rawURL = "https://" + rawURL
+	}
+
+	link, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("not a link: %w", err)
+	}
+	switch link.Scheme {
+	case "http", "https":
+		if link.Host == "" {
+			return fmt.Errorf("not a link: %s", rawURL)
+		}
+	case "mailto":
+		if link.Opaque == "" {
+			return fmt.Errorf("not a link: %s", rawURL)
+		}
+	default:
+		return fmt.Errorf("only http, https and mailto links can be opened, not %s", link.Scheme)
+	}
+	return exec.Command("xdg-open", link.String()).Start()
+}
+
+// tableCell is a run of words on one row with no wide gap between them
+type tableCell struct {
+	text        string
+	left, right int
+}
+
+// BuildTable reconstructs a table from tesseract word boxes. Rows come from
+// words that overlap vertically, columns from cells that overlap horizontally
+// across rows. format is "csv" or "markdown".
+func BuildTable(words []OCRWord, format string) (string, error) {
+	if len(words) == 0 {
+		return "", fmt.Errorf("no words to build a table from")
+	}
+
+	rows := groupRows(words)
+	cellRows := make([][]tableCell, len(rows))
+	var allCells []tableCell
+	for i, row := range rows {
+		cellRows[i] = splitCells(row)
+		allCells = append(allCells, cellRows[i]...)
+	}
+
+	columns := findColumns(allCells)
+	grid := make([][]string, len(cellRows))
+	for i, cells := range cellRows {
+		grid[i] = make([]string, len(columns))
+		for _, cell := range cells {
+			col := columnFor(columns, cell)
+			if grid[i][col] != "" {
+				grid[i][col] += " "
+			}
+			grid[i][col] += cell.text
+		}
+	}
+
+	switch format {
+	case "markdown", "md":
+		return tableToMarkdown(grid), nil
+	case "csv", "":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(grid); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported table format: %s", format)
+	}
+}
+
+func medianHeight(words []OCRWord) int {
+	heights := make([]int, len(words))
+	for i, w := range words {
+		heights[i] = w.Height
+	}
+	sort.Ints(heights)
+	if h := heights[len(heights)/2]; h > 0 {
+		return h
+	}
+	return 1
+}
+
+// groupRows clusters words whose vertical centers are close, ignoring
+// tesseract's block numbering which often splits table columns apart
+func groupRows(words []OCRWord) [][]OCRWord {
+	sorted := append([]OCRWord(nil), words...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Top+sorted[i].Height/2 < sorted[j].Top+sorted[j].Height/2
+	})
+
+	tolerance := medianHeight(words) / 2
+	var rows [][]OCRWord
+	rowCenter := 0
+	for _, w := range sorted {
+		center := w.Top + w.Height/2
+		if len(rows) == 0 || center-rowCenter > tolerance {
+			rows = append(rows, []OCRWord{w})
+			rowCenter = center
+			continue
+		}
+		last := len(rows) - 1
+		rows[last] = append(rows[last], w)
+	}
+
+	for _, row := range rows {
+		sort.Slice(row, func(i, j int) bool { return row[i].Left < row[j].Left })
+	}
+	return rows
+}
+
+// splitCells breaks a row wherever the gap is wider than about two spaces
+func splitCells(row []OCRWord) []tableCell {
+	gap := medianHeight(row) * 3 / 2
+	var cells []tableCell
+	for _, w := range row {
+		if len(cells) > 0 && w.Left-cells[len(cells)-1].right <= gap {
+			last := &cells[len(cells)-1]
+			last.text += " " + w.Text
+			last.right = w.Left + w.Width
+			continue
+		}
+		cells = append(cells, tableCell{text: w.Text, left: w.Left, right: w.Left + w.Width})
+	}
+	return cells
+}
+
+// findColumns merges horizontally overlapping cells into column spans
+func findColumns(cells []tableCell) []tableCell {
+	sorted := append([]tableCell(nil), cells...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].left < sorted[j].left })
+
+	var columns []tableCell
+	for _, cell := range sorted {
+		if n := len(columns); n > 0 && cell.left <= columns[n-1].right {
+			if cell.right > columns[n-1].right {
+				columns[n-1].right = cell.right
+			}
+			continue
+		}
+		columns = append(columns, tableCell{left: cell.left, right: cell.right})
+	}
+	return columns
+}
+
+func columnFor(columns []tableCell, cell tableCell) int {
+	for i, col := range columns {
+		if cell.left >= col.left && cell.left <= col.right {
+			return i
+		}
+	}
+	return len(columns) - 1
+}
+
+func tableToMarkdown(grid [][]string) string {
+	var b strings.Builder
+	escape := func(s string) string { return strings.ReplaceAll(s, "|", "\\|") }
+
+	for i, row := range grid {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = escape(cell)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			sep := make([]string, len(row))
+			for j := range sep {
+				sep[j] = "---"
+			}
+			b.WriteString("| " + strings.Join(sep, " | ") + " |\n")
+		}
+	}
+	return b.String()
+}
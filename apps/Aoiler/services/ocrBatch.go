@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ocrFileTypes are the extensions folder OCR picks up
+var ocrFileTypes = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".tif": true, ".tiff": true,
+	".bmp": true, ".gif": true, ".webp": true, ".pnm": true, ".pbm": true,
+	".pgm": true, ".ppm": true, ".pdf": true,
+}
+
+// OCRBatchFile is the outcome for one image or PDF in a folder run
+type OCRBatchFile struct {
+	Path       string `json:"path"`
+	TextPath   string `json:"textPath,omitempty"`
+	Pages      int    `json:"pages,omitempty"`
+	WordCount  int    `json:"wordCount,omitempty"`
+	Confidence string `json:"confidence,omitempty"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// OCRBatchSummary aggregates a folder run
+type OCRBatchSummary struct {
+	RunID          string `json:"runId"`
+	Root           string `json:"root"`
+	FilesProcessed int    `json:"filesProcessed"`
+	FilesFailed    int    `json:"filesFailed"`
+	FilesSkipped   int    `json:"filesSkipped"`
+	PagesProcessed int    `json:"pagesProcessed"`
+	WordCount      int    `json:"wordCount"`
+	Cancelled      bool   `json:"cancelled,omitempty"`
+	DurationMs     int64  `json:"durationMs"`
+}
+
+// OCRBatchResult is returned once a folder run finishes. Per-file results are
+// also streamed as "ocr:file" events while the run is in progress.
+type OCRBatchResult struct {
+	Summary OCRBatchSummary `json:"summary"`
+	Files   []OCRBatchFile  `json:"files"`
+}
+
+// OCRBatchEvent is emitted for every file as soon as it has been read
+type OCRBatchEvent struct {
+	RunID     string       `json:"runId"`
+	Processed int          `json:"processed"`
+	Total     int          `json:"total"`
+	File      OCRBatchFile `json:"file"`
+}
+
+// ExtractTextFromDir reads every image and PDF under root in parallel and
+// writes the text next to each one as "<name>.<ext>.txt". Files whose sidecar
+// is newer than the source are skipped unless force is set. The folder is
+// remembered so file search looks through its sidecars, and every file also
+// lands in the OCR history.
+func (ocr *OCRService) ExtractTextFromDir(ctx context.Context, root string, opts OCROptions, force bool) (OCRBatchResult, error) {
+	start := time.Now()
+
+	all, err := listProjectFiles(root)
+	if err != nil {
+		return OCRBatchResult{}, fmt.Errorf("failed to list files in %s: %w", root, err)
+	}
+
+	var files []string
+	for _, path := range all {
+		if ocrFileTypes[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		return OCRBatchResult{}, fmt.Errorf("no images or PDFs found in %s", root)
+	}
+
+	if err := rememberOCRArchive(root); err != nil {
+		warnf("could not add %s to the OCR archives: %v", root, err)
+	}
+
+	summary := OCRBatchSummary{RunID: newID(), Root: root}
+	ocr.emitter.emit("ocr:start", map[string]interface{}{
+		"runId": summary.RunID,
+		"root":  root,
+		"total": len(files),
+	})
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = ocr.defaults.Workers
+	}
+
+	jobs := make(chan string)
+	results := make(chan OCRBatchFile)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				results <- ocr.readToSidecar(ctx, path, opts, force)
+			}
+		}()
+	}
+
+	go func() {
+		for _, path := range files {
+			jobs <- path
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	collected := make([]OCRBatchFile, 0, len(files))
+	for file := range results {
+		collected = append(collected, file)
+		summary.FilesProcessed++
+
+		switch {
+		case file.Skipped:
+			summary.FilesSkipped++
+		case file.Error != "":
+			summary.FilesFailed++
+		default:
+			summary.PagesProcessed += file.Pages
+			summary.WordCount += file.WordCount
+		}
+
+		ocr.emitter.emit("ocr:file", OCRBatchEvent{
+			RunID:     summary.RunID,
+			Processed: summary.FilesProcessed,
+			Total:     len(files),
+			File:      file,
+		})
+		reportProgress(ctx, float64(summary.FilesProcessed)/float64(len(files)),
+			fmt.Sprintf("%d of %d files", summary.FilesProcessed, len(files)))
+	}
+
+	sort.Slice(collected, func(i, j int) bool { return collected[i].Path < collected[j].Path })
+
+	summary.Cancelled = ctx.Err() != nil && summary.FilesProcessed-summary.FilesFailed < len(files)
+	summary.DurationMs = time.Since(start).Milliseconds()
+	ocr.emitter.emit("ocr:done", summary)
+
+	return OCRBatchResult{Summary: summary, Files: collected}, nil
+}
+
+func (ocr *OCRService) readToSidecar(ctx context.Context, path string, opts OCROptions, force bool) OCRBatchFile {
+	file := OCRBatchFile{Path: path, TextPath: path + ".txt"}
+	// Files still queued when the run is cancelled are not started
+	if ctx.Err() != nil {
+		file.TextPath = ""
+		file.Error = "cancelled"
+		return file
+	}
+
+	if !force && sidecarUpToDate(path, file.TextPath) {
+		file.Skipped = true
+		return file
+	}
+
+	result, err := ocr.readFile(ctx, path, opts, false)
+	if err != nil {
+		file.TextPath = ""
+		file.Error = err.Error()
+		return file
+	}
+
+	if err := os.WriteFile(file.TextPath, []byte(result.Text+"\n"), 0o644); err != nil {
+		file.TextPath = ""
+		file.Error = fmt.Sprintf("failed to write %s: %v", path+".txt", err)
+		return file
+	}
+
+	file.Pages = result.Pages
+	file.WordCount = result.WordCount
+	file.Confidence = result.Confidence
+	return file
+}
+
+// ocrArchivesPath lists the folders batch OCR has read. File search reads
+// the sidecars under them, which makes the folders a searchable archive.
+func ocrArchivesPath() string {
+	return filepath.Join(ocrHistoryDir(), "archives.json")
+}
+
+func loadOCRArchives() ([]string, error) {
+	data, err := os.ReadFile(ocrArchivesPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var roots []string
+	if err := json.Unmarshal(data, &roots); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ocrArchivesPath(), err)
+	}
+	return roots, nil
+}
+
+func rememberOCRArchive(root string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	roots, err := loadOCRArchives()
+	if err != nil {
+		return err
+	}
+	for _, known := range roots {
+		if known == root {
+			return nil
+		}
+	}
+	roots = append(roots, root)
+	sort.Strings(roots)
+
+	data, err := json.MarshalIndent(roots, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ocrHistoryDir(), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(ocrArchivesPath(), data)
+}
+
+// searchOCRArchives finds the first archived image or PDF whose sidecar text
+// contains every term. Matches holds the lines that mention them.
+func searchOCRArchives(ctx context.Context, terms []string) (FileSearchResult, bool) {
+	roots, err := loadOCRArchives()
+	if err != nil || len(terms) == 0 {
+		return FileSearchResult{}, false
+	}
+
+	var found FileSearchResult
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".txt") {
+				return nil
+			}
+			source := strings.TrimSuffix(path, ".txt")
+			if !ocrFileTypes[strings.ToLower(filepath.Ext(source))] {
+				return nil
+			}
+			info, err := os.Stat(source)
+			if err != nil {
+				return nil
+			}
+
+			text, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			lowerText := strings.ToLower(string(text))
+			for _, term := range terms {
+				if !strings.Contains(lowerText, term) {
+					return nil
+				}
+			}
+
+			found = FileSearchResult{
+				Path:    source,
+				Type:    "file",
+				Found:   true,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+				Matches: linesMentioning(string(text), terms, 3),
+			}
+			return filepath.SkipAll
+		})
+		if found.Found {
+			return found, true
+		}
+	}
+	return FileSearchResult{}, false
+}
+
+// linesMentioning returns up to limit trimmed lines containing any of terms
+func linesMentioning(text string, terms []string, limit int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lowerLine := strings.ToLower(line)
+		for _, term := range terms {
+			if strings.Contains(lowerLine, term) {
+				lines = append(lines, strings.TrimSpace(line))
+				break
+			}
+		}
+		if len(lines) == limit {
+			break
+		}
+	}
+	return lines
+}
+
+func sidecarUpToDate(source, sidecar string) bool {
+	srcInfo, err := os.Stat(source)
+	if err != nil {
+		return false
+	}
+	txtInfo, err := os.Stat(sidecar)
+	return err == nil && !txtInfo.ModTime().Before(srcInfo.ModTime())
+}
+
+func isPDF(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".pdf")
+}
+
+// recognizePDF rasterizes every page with pdftoppm and reads them in order,
+// saving the first page as the history thumbnail when record is set
+func (ocr *OCRService) recognizePDF(ctx context.Context, pdfPath string, opts OCROptions, record bool) (OCRResult, error) {
+	tmpDir, err := os.MkdirTemp("", "aoiler-pdf-")
+	if err != nil {
+		return OCRResult{Success: false, Mode: "file", Source: pdfPath}, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pages, err := rasterizePDF(ctx, pdfPath, tmpDir)
+	if err != nil {
+		return OCRResult{Success: false, Mode: "file", Source: pdfPath}, err
+	}
+
+	results := make([]OCRResult, 0, len(pages))
+	for _, page := range pages {
+		result, err := ocr.recognize(ctx, page, "file", opts)
+		// Blank pages are normal in scans, anything else stops the document
+		if err != nil && !errors.Is(err, errNoText) {
+			result.Source = pdfPath
+			return result, fmt.Errorf("page %d: %w", len(results)+1, err)
+		}
+		results = append(results, result)
+	}
+
+	merged := mergePages(results)
+	merged.Source = pdfPath
+	if merged.Text == "" {
+		return merged, errNoText
+	}
+
+	if record {
+		ocr.record(&merged, pages[0])
+	}
+	return merged, nil
+}
+
+// rasterizePDF renders each page to a PNG in dir and returns them in page order
+func rasterizePDF(ctx context.Context, pdfPath, dir string) ([]string, error) {
+	if _, err := exec.LookPath("pdftoppm"); err != nil {
+		return nil, fmt.Errorf("pdftoppm is not installed (poppler-utils)")
+	}
+
+	cmd := exec.CommandContext(ctx, "pdftoppm", "-r", "300", "-png", pdfPath, filepath.Join(dir, "page"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	// pdftoppm zero-pads page numbers, so name order is page order
+	pages, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("pdftoppm produced no pages for %s", pdfPath)
+	}
+	sort.Strings(pages)
+	return pages, nil
+}
+
+// mergePages joins per-page results into one, stacking word boxes so later
+// pages sit below earlier ones
+func mergePages(pages []OCRResult) OCRResult {
+	merged := OCRResult{Mode: "file", Pages: len(pages)}
+	var texts []string
+	offset := 0
+
+	for _, page := range pages {
+		if merged.Language == "" {
+			merged.Language, merged.PSM = page.Language, page.PSM
+		}
+		if page.Text != "" {
+			texts = append(texts, page.Text)
+		}
+
+		bottom := 0
+		for _, w := range page.Words {
+			if b := w.Top + w.Height; b > bottom {
+				bottom = b
+			}
+			w.Top += offset
+			merged.Words = append(merged.Words, w)
+		}
+		offset += bottom
+	}
+
+	merged.Text = strings.Join(texts, "\n\n")
+	merged.Success = merged.Text != ""
+	merged.WordCount = len(strings.Fields(merged.Text))
+	merged.MeanConfidence = meanConfidence(merged.Words)
+	merged.Confidence = confidenceLabel(merged.MeanConfidence, len(merged.Words))
+	merged.Entities = extractEntities(merged.Text)
+	return merged
+}
@@ -0,0 +1,455 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultOCRHistoryEntries = 1000
+	ocrHistorySearchLimit    = 50
+	ocrThumbnailSize         = 240
+)
+
+// ocrHistoryPattern matches "ocr history docker", capturing the search words
+var ocrHistoryPattern = regexp.MustCompile(`\bocr\s+history\b(.*)`)
+
+// OCRHistoryOptions controls how long OCR captures are kept
+type OCRHistoryOptions struct {
+	// Disabled turns off recording new captures
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxEntries caps the number of stored captures, oldest go first (default 1000)
+	MaxEntries int `json:"maxEntries,omitempty"`
+	// MaxAgeDays drops captures older than this, 0 keeps them forever
+	MaxAgeDays int `json:"maxAgeDays,omitempty"`
+}
+
+// OCRHistoryEntry is one stored capture
+type OCRHistoryEntry struct {
+	ID         string    `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Mode       string    `json:"mode"`
+	Source     string    `json:"source,omitempty"`
+	Language   string    `json:"language,omitempty"`
+	Text       string    `json:"text"`
+	WordCount  int       `json:"wordCount,omitempty"`
+	Confidence string    `json:"confidence,omitempty"`
+	// Thumbnail is the file name of the stored preview inside the thumbs directory
+	Thumbnail string `json:"thumbnail,omitempty"`
+	// Preview and Snippet are only filled in search results
+	Preview string `json:"preview,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// OCRHistoryResult lists captures matching a history search
+type OCRHistoryResult struct {
+	Query   string            `json:"query"`
+	Entries []OCRHistoryEntry `json:"entries"`
+	Total   int               `json:"total"`
+}
+
+// OCRHistory keeps past captures on disk under ~/.local/share/aoiler/ocr with
+// an in-memory word index for full-text search
+type OCRHistory struct {
+	opts    OCRHistoryOptions
+	dir     string
+	mu      sync.Mutex
+	loaded  bool
+	entries []OCRHistoryEntry
+	// index maps a lowercase word to the IDs of entries containing it
+	index map[string]map[string]int
+}
+
+func NewOCRHistory(opts OCRHistoryOptions) *OCRHistory {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = defaultOCRHistoryEntries
+	}
+	return &OCRHistory{opts: opts, dir: ocrHistoryDir()}
+}
+
+func ocrHistoryDir() string {
+	return filepath.Join(aoilerDataDir(), "ocr")
+}
+
+func (h *OCRHistory) indexPath() string {
+	return filepath.Join(h.dir, "history.json")
+}
+
+func (h *OCRHistory) thumbsDir() string {
+	return filepath.Join(h.dir, "thumbs")
+}
+
+// Add stores a successful capture together with a thumbnail of imagePath
+func (h *OCRHistory) Add(result OCRResult, imagePath string) (string, error) {
+	if h.opts.Disabled || !result.Success {
+		return "", nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.loadLocked(); err != nil {
+		return "", err
+	}
+
+	entry := OCRHistoryEntry{
+		ID:         newID(),
+		Timestamp:  time.Now(),
+		Mode:       result.Mode,
+		Source:     result.Source,
+		Language:   result.Language,
+		Text:       result.Text,
+		WordCount:  result.WordCount,
+		Confidence: result.Confidence,
+	}
+
+	if err := os.MkdirAll(h.thumbsDir(), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create OCR history directory: %w", err)
+	}
+	// A missing thumbnail isn't worth losing the capture over
+	if thumb, err := writeThumbnail(imagePath, filepath.Join(h.thumbsDir(), entry.ID+".jpg")); err == nil {
+		entry.Thumbnail = thumb
+	}
+
+	h.entries = append(h.entries, entry)
+	h.indexEntry(entry)
+	h.pruneLocked()
+
+	return entry.ID, h.saveLocked()
+}
+
+// Search returns captures containing every term, best matches first. Terms
+// match word prefixes, so "dock" finds "docker". No terms lists recent captures.
+func (h *OCRHistory) Search(query string) (OCRHistoryResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := OCRHistoryResult{Query: query, Entries: []OCRHistoryEntry{}}
+	if err := h.loadLocked(); err != nil {
+		return result, err
+	}
+
+	terms := tokenize(query)
+	scores := h.scoreLocked(terms)
+	type scored struct {
+		entry OCRHistoryEntry
+		score int
+	}
+	var matches []scored
+	for _, entry := range h.entries {
+		if score, ok := scores[entry.ID]; ok || len(terms) == 0 {
+			matches = append(matches, scored{entry, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].entry.Timestamp.After(matches[j].entry.Timestamp)
+	})
+
+	result.Total = len(matches)
+	for i, m := range matches {
+		if i >= ocrHistorySearchLimit {
+			break
+		}
+		entry := m.entry
+		entry.Snippet = snippetFor(entry.Text, terms)
+		entry.Preview = h.previewFor(entry)
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+// Delete removes a capture and its thumbnail
+func (h *OCRHistory) Delete(id string) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.loadLocked(); err != nil {
+		return false, err
+	}
+
+	for i, entry := range h.entries {
+		if entry.ID == id {
+			h.removeLocked(i)
+			return true, h.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// scoreLocked returns the entries where every term prefix-matches a word,
+// scored by how often the matched words occur. Exact words count double.
+func (h *OCRHistory) scoreLocked(terms []string) map[string]int {
+	var scores map[string]int
+	for _, term := range terms {
+		termScores := make(map[string]int)
+		for word, ids := range h.index {
+			if !strings.HasPrefix(word, term) {
+				continue
+			}
+			for id, count := range ids {
+				if word == term {
+					count *= 2
+				}
+				termScores[id] += count
+			}
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if extra, ok := termScores[id]; ok {
+				scores[id] += extra
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores
+}
+
+func (h *OCRHistory) loadLocked() error {
+	if h.loaded {
+		return nil
+	}
+
+	h.index = make(map[string]map[string]int)
+	data, err := os.ReadFile(h.indexPath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read OCR history: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &h.entries); err != nil {
+			return fmt.Errorf("invalid OCR history %s: %w", h.indexPath(), err)
+		}
+	}
+
+	for _, entry := range h.entries {
+		h.indexEntry(entry)
+	}
+	h.loaded = true
+
+	// Retention settings may have tightened since the last run
+	if h.pruneLocked() {
+		return h.saveLocked()
+	}
+	return nil
+}
+
+func (h *OCRHistory) saveLocked() error {
+	if err := os.MkdirAll(h.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create OCR history directory: %w", err)
+	}
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(h.indexPath(), data); err != nil {
+		return fmt.Errorf("failed to save OCR history: %w", err)
+	}
+	return nil
+}
+
+// pruneLocked applies the age and size limits, entries are kept oldest first
+func (h *OCRHistory) pruneLocked() bool {
+	pruned := false
+	if h.opts.MaxAgeDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -h.opts.MaxAgeDays)
+		for len(h.entries) > 0 && h.entries[0].Timestamp.Before(cutoff) {
+			h.removeLocked(0)
+			pruned = true
+		}
+	}
+	for len(h.entries) > h.opts.MaxEntries {
+		h.removeLocked(0)
+		pruned = true
+	}
+	return pruned
+}
+
+func (h *OCRHistory) removeLocked(i int) {
+	entry := h.entries[i]
+	if entry.Thumbnail != "" {
+		os.Remove(filepath.Join(h.thumbsDir(), entry.Thumbnail))
+	}
+	for _, word := range tokenize(indexedText(entry)) {
+		if ids, ok := h.index[word]; ok {
+			delete(ids, entry.ID)
+			if len(ids) == 0 {
+				delete(h.index, word)
+			}
+		}
+	}
+	h.entries = append(h.entries[:i], h.entries[i+1:]...)
+}
+
+func (h *OCRHistory) indexEntry(entry OCRHistoryEntry) {
+	for _, word := range tokenize(indexedText(entry)) {
+		if h.index[word] == nil {
+			h.index[word] = make(map[string]int)
+		}
+		h.index[word][entry.ID]++
+	}
+}
+
+// indexedText is what a search matches against, the source file name included
+func indexedText(entry OCRHistoryEntry) string {
+	if entry.Source == "" {
+		return entry.Text
+	}
+	return entry.Text + " " + filepath.Base(entry.Source)
+}
+
+// previewFor inlines the thumbnail as a data URL the webview can show directly
+func (h *OCRHistory) previewFor(entry OCRHistoryEntry) string {
+	if entry.Thumbnail == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(h.thumbsDir(), entry.Thumbnail))
+	if err != nil {
+		return ""
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// tokenize splits text into lowercase words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// snippetFor shows the text around the first matched term
+func snippetFor(text string, terms []string) string {
+	const context = 60
+	flat := strings.Join(strings.Fields(text), " ")
+	lower := strings.ToLower(flat)
+
+	pos := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, term); i >= 0 && (pos < 0 || i < pos) {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		pos = 0
+	}
+
+	start := pos - context
+	if start < 0 {
+		start = 0
+	}
+	end := pos + context*2
+	if end > len(flat) {
+		end = len(flat)
+	}
+	// Don't cut a multi-byte character in half
+	for start > 0 && !isRuneStart(flat[start]) {
+		start--
+	}
+	for end < len(flat) && !isRuneStart(flat[end]) {
+		end++
+	}
+
+	snippet := flat[start:end]
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(flat) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// writeThumbnail stores a downscaled JPEG copy of an image and returns its
+// file name
+func writeThumbnail(imagePath, thumbPath string) (string, error) {
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(src, ocrThumbnailSize), &jpeg.Options{Quality: 75}); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(thumbPath, buf.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	return filepath.Base(thumbPath), nil
+}
+
+// scaleDown fits an image into a size x size box by averaging source pixels
+func scaleDown(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := bounds.Min.Y + y*h/dh
+		y1 := bounds.Min.Y + (y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0 := bounds.Min.X + x*w/dw
+			x1 := bounds.Min.X + (x+1)*w/dw
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// OCROptions controls how tesseract reads an image
+type OCROptions struct {
+	// Language is a tesseract language code, several can be joined with "+"
+	Language string `json:"language,omitempty"`
+	// PSM is tesseract's page segmentation mode, 3 (automatic) by default
+	PSM int `json:"psm,omitempty"`
+	// Workers bounds parallel tesseract runs when reading a whole folder
+	Workers int `json:"workers,omitempty"`
+}
+
+// errNoText is returned when tesseract ran fine but found nothing to read
+var errNoText = errors.New("no text detected")
+
+// ocrLanguages maps spoken language names to tesseract traineddata codes
+var ocrLanguages = map[string]string{
+	"english":    "eng",
+	"german":     "deu",
+	"french":     "fra",
+	"spanish":    "spa",
+	"italian":    "ita",
+	"portuguese": "por",
+	"dutch":      "nld",
+	"polish":     "pol",
+	"czech":      "ces",
+	"swedish":    "swe",
+	"norwegian":  "nor",
+	"danish":     "dan",
+	"finnish":    "fin",
+	"turkish":    "tur",
+	"greek":      "ell",
+	"russian":    "rus",
+	"ukrainian":  "ukr",
+	"arabic":     "ara",
+	"hebrew":     "heb",
+	"hindi":      "hin",
+	"urdu":       "urd",
+	"persian":    "fas",
+	"chinese":    "chi_sim",
+	"japanese":   "jpn",
+	"korean":     "kor",
+	"vietnamese": "vie",
+	"thai":       "tha",
+	"indonesian": "ind",
+}
+
+// ocrSegmentationModes maps plain descriptions to tesseract --psm values
+var ocrSegmentationModes = map[string]int{
+	"auto":        3,
+	"column":      4,
+	"block":       6,
+	"single line": 7,
+	"single word": 8,
+	"sparse":      11,
+}
+
+var (
+	ocrLanguagePattern  = regexp.MustCompile(`\b(in|lang(?:uage)?)\s+([a-z_]+(?:\s*(?:\+|,|and)\s*[a-z_]+)*)`)
+	ocrLanguageSplitter = regexp.MustCompile(`\s*(?:\+|,|\band\b)\s*`)
+	ocrPSMPattern       = regexp.MustCompile(`\bpsm\s*(\d{1,2})\b`)
+)
+
+// parseOCROptions pulls language and page segmentation hints out of a query
+// such as "ocr ~/scan.png in german and english as single line"
+func parseOCROptions(query string) OCROptions {
+	lowerQuery := strings.ToLower(stripPaths(query))
+	var opts OCROptions
+
+	for _, m := range ocrLanguagePattern.FindAllStringSubmatch(lowerQuery, -1) {
+		// "lang xyz" accepts any code, "in xyz" only names and codes we know
+		explicit := m[1] != "in"
+		var codes []string
+		for _, name := range ocrLanguageSplitter.Split(m[2], -1) {
+			if code, ok := ocrLanguages[name]; ok {
+				codes = append(codes, code)
+			} else if explicit || isLanguageCode(name) {
+				codes = append(codes, name)
+			}
+		}
+		if len(codes) > 0 {
+			opts.Language = strings.Join(codes, "+")
+			break
+		}
+	}
+
+	if m := ocrPSMPattern.FindStringSubmatch(lowerQuery); m != nil {
+		opts.PSM, _ = strconv.Atoi(m[1])
+	} else {
+		for name, psm := range ocrSegmentationModes {
+			if strings.Contains(lowerQuery, name) {
+				opts.PSM = psm
+				break
+			}
+		}
+	}
+
+	return opts
+}
+
+// isLanguageCode accepts the raw tesseract codes of known languages like "deu"
+func isLanguageCode(code string) bool {
+	for _, known := range ocrLanguages {
+		if known == code {
+			return true
+		}
+	}
+	return false
+}
+
+// captureScreenArea lets the user pick a region with slurp and grabs it with grim
+func captureScreenArea(ctx context.Context) (string, error) {
+	geometry, err := exec.CommandContext(ctx, "slurp").Output()
+	if err != nil {
+		return "", fmt.Errorf("screenshot cancelled or failed")
+	}
+
+	tmp, err := os.CreateTemp("", "ocr_screenshot_*.png")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+
+	if output, err := exec.CommandContext(ctx, "grim", "-g", strings.TrimSpace(string(geometry)), tmp.Name()).CombinedOutput(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("grim failed: %s", strings.TrimSpace(string(output)))
+	}
+	return tmp.Name(), nil
+}
+
+// runTesseract produces the plain text and the per-word TSV data in one pass
+func runTesseract(ctx context.Context, imagePath string, opts OCROptions) (string, []OCRWord, error) {
+	tmpDir, err := os.MkdirTemp("", "aoiler-ocr-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	base := filepath.Join(tmpDir, "out")
+	cmd := exec.CommandContext(ctx, "tesseract", imagePath, base,
+		"-l", opts.Language, "--psm", strconv.Itoa(opts.PSM), "txt", "tsv")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", nil, fmt.Errorf("tesseract failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	text, err := os.ReadFile(base + ".txt")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read OCR text: %w", err)
+	}
+
+	words, err := parseTesseractTSV(base + ".tsv")
+	if err != nil {
+		return "", nil, err
+	}
+
+	if strings.TrimSpace(string(text)) == "" {
+		return "", words, errNoText
+	}
+	return string(text), words, nil
+}
+
+// checkLanguages gives a readable error for missing traineddata. The list of
+// installed languages is cached on the service and only read again when a
+// language seems missing, so newly installed traineddata is still found.
+func (ocr *OCRService) checkLanguages(language string) error {
+	ocr.langsMu.Lock()
+	defer ocr.langsMu.Unlock()
+
+	if len(missingLanguages(ocr.langs, language)) == 0 {
+		return nil
+	}
+
+	output, err := exec.Command("tesseract", "--list-langs").CombinedOutput()
+	if err != nil {
+		if _, lookErr := exec.LookPath("tesseract"); lookErr != nil {
+			return fmt.Errorf("tesseract is not installed")
+		}
+		// Let tesseract itself report the problem
+		return nil
+	}
+
+	ocr.langs = make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		ocr.langs[strings.TrimSpace(line)] = true
+	}
+
+	if missing := missingLanguages(ocr.langs, language); len(missing) > 0 {
+		return fmt.Errorf("tesseract language data not installed: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func missingLanguages(installed map[string]bool, language string) []string {
+	var missing []string
+	for _, code := range strings.Split(language, "+") {
+		if !installed[code] {
+			missing = append(missing, code)
+		}
+	}
+	return missing
+}
+
+// parseTesseractTSV reads word level rows (level 5) from tesseract's TSV output
+func parseTesseractTSV(path string) ([]OCRWord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCR data: %w", err)
+	}
+	defer f.Close()
+
+	var words []OCRWord
+	scanner := bufio.NewScanner(f)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+
+		text := strings.TrimSpace(fields[11])
+		conf, err := strconv.ParseFloat(fields[10], 64)
+		if text == "" || err != nil || conf < 0 {
+			continue
+		}
+
+		nums := make([]int, 10)
+		for i := 0; i < 10; i++ {
+			nums[i], _ = strconv.Atoi(fields[i])
+		}
+
+		words = append(words, OCRWord{
+			Text:       text,
+			Confidence: conf,
+			Block:      nums[2],
+			Paragraph:  nums[3],
+			Line:       nums[4],
+			Left:       nums[6],
+			Top:        nums[7],
+			Width:      nums[8],
+			Height:     nums[9],
+		})
+	}
+	return words, scanner.Err()
+}
+
+func meanConfidence(words []OCRWord) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+	var total float64
+	for _, w := range words {
+		total += w.Confidence
+	}
+	return total / float64(len(words))
+}
+
+// confidenceLabel buckets tesseract's mean word confidence (0-100)
+func confidenceLabel(mean float64, wordCount int) string {
+	switch {
+	case wordCount == 0:
+		return "none"
+	case mean >= 85:
+		return "high"
+	case mean >= 60:
+		return "medium"
+	default:
+		return "low"
+	}
+}
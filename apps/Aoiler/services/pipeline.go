@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pipeSeparator splits "ocr | llm summarize". Like "then", it only counts
+// in front of a service, since questions and notes can contain "|" too.
+var pipeSeparator = regexp.MustCompile(`\s*\|\s*`)
+
+// thenSeparator splits "find invoice.png then ocr". Plain questions use
+// "then" too, so splitPipeline only accepts it in front of a service.
+var thenSeparator = regexp.MustCompile(`(?i)\s+(?:and\s+)?then\s+`)
+
+// llmPrefix is dropped from a stage like "llm summarize" before the input
+// text is appended, the rest is the instruction
+var llmPrefix = regexp.MustCompile(`(?i)^(?:llm|ask)\b[:,]?\s*`)
+
+// PipelineValue is what flows from one stage to the next: a file path or
+// plain text
+type PipelineValue struct {
+	Kind string `json:"kind"`
+	Path string `json:"path,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// PipelineStage is one step of a pipeline with its own result
+type PipelineStage struct {
+	Query      string         `json:"query"`
+	Service    string         `json:"service"`
+	Input      *PipelineValue `json:"input,omitempty"`
+	Success    bool           `json:"success"`
+	Result     interface{}    `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	DurationMs int64          `json:"durationMs"`
+}
+
+// PipelineResult lists every stage that ran. The last one holds the final
+// output; a failed stage stops the pipeline.
+type PipelineResult struct {
+	Stages []PipelineStage `json:"stages"`
+	Total  int             `json:"total"`
+}
+
+// splitPipeline returns the stages of a chained query, or a single stage
+func (sm *ServiceManager) splitPipeline(query string) []string {
+	// "calc 0xff | 0x0f" is a bitwise or, not a chain
+	if sm.calculator.Handles(query) {
+		return []string{strings.TrimSpace(query)}
+	}
+
+	var stages []string
+	for _, part := range sm.splitBefore(query, pipeSeparator) {
+		stages = append(stages, sm.splitBefore(part, thenSeparator)...)
+	}
+
+	kept := stages[:0]
+	for _, stage := range stages {
+		if stage = strings.TrimSpace(stage); stage != "" {
+			kept = append(kept, stage)
+		}
+	}
+	return kept
+}
+
+// splitBefore splits at separator only where the next part names a service,
+// so "explain if then else in bash" and "note: call mom | important" stay
+// whole
+func (sm *ServiceManager) splitBefore(query string, separator *regexp.Regexp) []string {
+	matches := separator.FindAllStringIndex(query, -1)
+	var stages []string
+	start := 0
+	for _, m := range matches {
+		next := query[m[1]:]
+		if sm.classifyStage(next).ServiceName == "llm" && !llmPrefix.MatchString(next) {
+			continue
+		}
+		stages = append(stages, query[start:m[0]])
+		start = m[1]
+	}
+	return append(stages, query[start:])
+}
+
+// HidesWindow reports whether any stage of a query captures the screen, so
+// the window has to be hidden before the query starts
+func (sm *ServiceManager) HidesWindow(query string) bool {
+	for _, stage := range sm.splitPipeline(query) {
+		if sm.screenshot.HidesWindow(stage) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunPipeline runs each stage with the previous stage's output as its input.
+// Every stage is bounded by its own service's timeout, and the whole chain
+// by the pipeline job's timeout.
+func (sm *ServiceManager) RunPipeline(ctx context.Context, query string) (PipelineResult, error) {
+	stages := sm.splitPipeline(query)
+	result := PipelineResult{Stages: []PipelineStage{}, Total: len(stages)}
+
+	var input *PipelineValue
+	for i, stageQuery := range stages {
+		reportProgress(ctx, float64(i)/float64(len(stages)), fmt.Sprintf("stage %d of %d: %s", i+1, len(stages), stageQuery))
+
+		stage, intent, err := sm.prepareStage(stageQuery, input)
+		if err == nil {
+			start := time.Now()
+			stageCtx, cancel := context.WithCancel(ctx)
+			if timeout := sm.jobs.timeouts[intent.ServiceName]; timeout > 0 {
+				stageCtx, cancel = withTimeout(stageCtx, cancel, timeout)
+			}
+			stage.Result, err = sm.RouteToService(stageCtx, intent, stage.Query)
+			cancel()
+			stage.DurationMs = time.Since(start).Milliseconds()
+		}
+		// The last stage's result is the output, it isn't passed on
+		if err == nil && i < len(stages)-1 {
+			if input = pipelineOutput(stage.Result); input == nil {
+				err = fmt.Errorf("%s output can't be passed to another step", stage.Service)
+			}
+		}
+
+		if err != nil {
+			stage.Error = err.Error()
+			result.Stages = append(result.Stages, stage)
+			return result, fmt.Errorf("stage %d (%s): %w", i+1, stage.Service, err)
+		}
+		stage.Success = true
+		result.Stages = append(result.Stages, stage)
+	}
+	return result, nil
+}
+
+// prepareStage turns a stage and the previous output into a query and its
+// intent. Paths go right after the stage's verb, "ocr" becomes "ocr
+// ~/invoice.png"; text can only go to the LLM as the material for its
+// instruction.
+func (sm *ServiceManager) prepareStage(stageQuery string, input *PipelineValue) (PipelineStage, Intent, error) {
+	stage := PipelineStage{Query: stageQuery, Input: input}
+
+	if input == nil {
+		intent := sm.classifyStage(stageQuery)
+		stage.Service = intent.ServiceName
+		return stage, intent, nil
+	}
+
+	if input.Kind == "path" {
+		verb, rest, _ := strings.Cut(stageQuery, " ")
+		stage.Query = strings.TrimSpace(verb + " " + input.Path + " " + rest)
+		intent := sm.classifyStage(stage.Query)
+		stage.Service = intent.ServiceName
+		if intent.ServiceName == "llm" {
+			return stage, intent, fmt.Errorf("%s is a file, the LLM needs text; put ocr or another text step in between", input.Path)
+		}
+		return stage, intent, nil
+	}
+
+	// Classify the instruction alone so words in the text can't pick the service
+	intent := sm.classifyStage(stageQuery)
+	stage.Service = intent.ServiceName
+	if intent.ServiceName != "llm" && !llmPrefix.MatchString(stageQuery) {
+		return stage, intent, fmt.Errorf("%s can't take text as input, only the LLM can", intent.ServiceName)
+	}
+
+	instruction := strings.TrimSpace(llmPrefix.ReplaceAllString(stageQuery, ""))
+	if instruction == "" {
+		instruction = "Respond to the following"
+	}
+	stage.Service = "llm"
+	stage.Query = instruction + "\n\n" + input.Text
+	return stage, Intent{ServiceName: "llm", Confidence: 0.9, Params: map[string]string{"query": stage.Query}}, nil
+}
+
+// pipelineOutput adapts a stage result into the next stage's input, nil
+// when the result has nothing to pass on
+func pipelineOutput(result interface{}) *PipelineValue {
+	switch r := result.(type) {
+	case FileSearchResult:
+		if !r.Found {
+			return nil
+		}
+		return &PipelineValue{Kind: "path", Path: r.Path}
+	case ConverterResult:
+		return &PipelineValue{Kind: "path", Path: r.OutputPath}
+	case DocumentResult:
+		if r.OutputPath == "" {
+			return nil
+		}
+		return &PipelineValue{Kind: "path", Path: r.OutputPath}
+	case MediaInfo:
+		return &PipelineValue{Kind: "path", Path: r.Path}
+	case OCRResult:
+		return &PipelineValue{Kind: "text", Text: r.Text}
+	case LLMResult:
+		if !r.Success {
+			return nil
+		}
+		return &PipelineValue{Kind: "text", Text: r.Response}
+	case LinterResult:
+		return &PipelineValue{Kind: "text", Text: r.Output}
+	case OrganizerResult:
+		return &PipelineValue{Kind: "text", Text: r.Output}
+	case ClipboardResult:
+		// Search decodes the best match in full for this
+		if len(r.Entries) == 0 {
+			return nil
+		}
+		if entry := r.Entries[0]; entry.Kind == "image" {
+			return &PipelineValue{Kind: "path", Path: entry.Path}
+		}
+		return &PipelineValue{Kind: "text", Text: r.Entries[0].Text}
+	case CalculatorResult:
+		return &PipelineValue{Kind: "text", Text: r.Result}
+	case NotesResult:
+		if len(r.Notes) == 0 {
+			return nil
+		}
+		return &PipelineValue{Kind: "text", Text: r.Notes[0].Content}
+	case ScreenshotResult:
+		if r.Path == "" {
+			return nil
+		}
+		return &PipelineValue{Kind: "path", Path: r.Path}
+	case HelpResult:
+		return &PipelineValue{Kind: "text", Text: r.Text}
+	}
+	return nil
+}
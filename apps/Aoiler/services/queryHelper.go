@@ -1,251 +1,239 @@
 package services
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+	"unicode"
 )
 
+const maxSuggestions = 10
+
 // QuerySuggestion represents a suggested query pattern
 type QuerySuggestion struct {
 	Query       string   `json:"query"`
 	Description string   `json:"description"`
 	Category    string   `json:"category"`
+	Service     string   `json:"service"`
 	Examples    []string `json:"examples"`
 }
 
-// HelpService provides query suggestions and examples
-type HelpService struct{}
+// HelpResult answers a "help" query, for every service or one topic
+type HelpResult struct {
+	Topic       string            `json:"topic,omitempty"`
+	Text        string            `json:"text"`
+	Suggestions []QuerySuggestion `json:"suggestions"`
+}
 
-func NewHelpService() *HelpService {
-	return &HelpService{}
+// HelpService provides query suggestions, examples and typo corrections from
+// what the registered services describe about themselves
+type HelpService struct {
+	services []ServiceHelp
+	// vocabulary holds every keyword word; nextWords maps the first word of
+	// a multi-word keyword like "extract text" to the words that can follow it
+	vocabulary map[string]bool
+	nextWords  map[string][]string
 }
 
-// GetSuggestions returns relevant query suggestions based on partial input
-func (h *HelpService) GetSuggestions(partial string) []QuerySuggestion {
-	allSuggestions := h.getAllSuggestions()
+func NewHelpService(services []ServiceHelp) *HelpService {
+	h := &HelpService{
+		vocabulary: make(map[string]bool),
+		nextWords:  make(map[string][]string),
+	}
+	for _, service := range services {
+		suggestions := make([]QuerySuggestion, len(service.Suggestions))
+		for i, suggestion := range service.Suggestions {
+			suggestion.Category = service.Category
+			suggestion.Service = service.Service
+			suggestions[i] = suggestion
+		}
+		service.Suggestions = suggestions
+		h.services = append(h.services, service)
+
+		for _, keyword := range service.Keywords {
+			words := strings.Fields(strings.ToLower(keyword))
+			for i, word := range words {
+				h.vocabulary[word] = true
+				if i > 0 {
+					h.nextWords[words[i-1]] = append(h.nextWords[words[i-1]], word)
+				}
+			}
+		}
+	}
+	return h
+}
 
+// GetSuggestions returns suggestions for partial input, those starting with
+// it first. Input with a typo is matched as if it were corrected.
+func (h *HelpService) GetSuggestions(partial string) []QuerySuggestion {
+	partial = strings.TrimSpace(partial)
 	if partial == "" {
-		// Return popular/featured suggestions
 		return h.getFeaturedSuggestions()
 	}
 
-	lowerPartial := strings.ToLower(partial)
-	var matches []QuerySuggestion
-
-	for _, suggestion := range allSuggestions {
-		// Match against query, description, or category
-		if strings.Contains(strings.ToLower(suggestion.Query), lowerPartial) ||
-		   strings.Contains(strings.ToLower(suggestion.Description), lowerPartial) ||
-		   strings.Contains(strings.ToLower(suggestion.Category), lowerPartial) {
-			matches = append(matches, suggestion)
+	matches := h.match(strings.ToLower(partial))
+	if len(matches) == 0 {
+		if corrections := h.DetectTypos(partial); len(corrections) > 0 {
+			matches = h.match(strings.ToLower(corrections[0]))
 		}
 	}
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+	return matches
+}
 
-	// Limit to top 10
-	if len(matches) > 10 {
-		matches = matches[:10]
+// match ranks suggestions whose pattern or an example starts with the input
+// above those that only mention it
+func (h *HelpService) match(partial string) []QuerySuggestion {
+	type ranked struct {
+		suggestion QuerySuggestion
+		rank       int
+	}
+	var matches []ranked
+	for _, suggestion := range h.allSuggestions() {
+		texts := append([]string{suggestion.Query}, suggestion.Examples...)
+		rank := -1
+		for _, text := range texts {
+			text = strings.ToLower(text)
+			if strings.HasPrefix(text, partial) {
+				rank = 0
+				break
+			}
+			if strings.Contains(text, partial) {
+				rank = 1
+			}
+		}
+		// A letter or two would match most descriptions
+		if rank < 0 && len(partial) > 2 &&
+			(strings.Contains(strings.ToLower(suggestion.Description), partial) ||
+				strings.Contains(strings.ToLower(suggestion.Category), partial)) {
+			rank = 2
+		}
+		if rank >= 0 {
+			matches = append(matches, ranked{suggestion, rank})
+		}
 	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].rank < matches[j].rank })
 
-	return matches
+	suggestions := []QuerySuggestion{}
+	for _, m := range matches {
+		suggestions = append(suggestions, m.suggestion)
+	}
+	return suggestions
 }
 
 // GetByCategory returns all suggestions for a specific category
 func (h *HelpService) GetByCategory(category string) []QuerySuggestion {
-	allSuggestions := h.getAllSuggestions()
 	var matches []QuerySuggestion
-
-	for _, suggestion := range allSuggestions {
+	for _, suggestion := range h.allSuggestions() {
 		if strings.EqualFold(suggestion.Category, category) {
 			matches = append(matches, suggestion)
 		}
 	}
-
 	return matches
 }
 
-// GetCategories returns all available categories
+// GetCategories returns the categories of the registered services in order
 func (h *HelpService) GetCategories() []string {
-	return []string{
-		"File Search",
-		"Organization",
-		"Code Tools",
-		"OCR & Text",
-		"Media Conversion",
-		"General",
+	var categories []string
+	seen := make(map[string]bool)
+	for _, service := range h.services {
+		if !seen[service.Category] {
+			seen[service.Category] = true
+			categories = append(categories, service.Category)
+		}
 	}
+	return categories
 }
 
-// getFeaturedSuggestions returns the most useful suggestions
+// getFeaturedSuggestions returns the first suggestion of every service
 func (h *HelpService) getFeaturedSuggestions() []QuerySuggestion {
-	return []QuerySuggestion{
-		{
-			Query:       "find my config",
-			Description: "Search for configuration files",
-			Category:    "File Search",
-			Examples:    []string{"find my neovim config", "where is hyprland config"},
-		},
-		{
-			Query:       "organize downloads",
-			Description: "Organize files by category or name",
-			Category:    "Organization",
-			Examples:    []string{"organize ~/Downloads", "clean up ~/Desktop"},
-		},
-		{
-			Query:       "format code.py",
-			Description: "Auto-format code files",
-			Category:    "Code Tools",
-			Examples:    []string{"lint main.go", "format script.sh"},
-		},
-		{
-			Query:       "extract text from screen",
-			Description: "OCR from screenshot or image",
-			Category:    "OCR & Text",
-			Examples:    []string{"ocr", "read text from image.png"},
-		},
-		{
-			Query:       "convert video to mp4",
-			Description: "Convert media files between formats",
-			Category:    "Media Conversion",
-			Examples:    []string{"convert song.flac to mp3", "encode video.avi to webm"},
-		},
+	featured := []QuerySuggestion{}
+	for _, service := range h.services {
+		if len(service.Suggestions) > 0 {
+			featured = append(featured, service.Suggestions[0])
+		}
 	}
+	return featured
 }
 
-// getAllSuggestions returns the complete suggestion database
-func (h *HelpService) getAllSuggestions() []QuerySuggestion {
-	return []QuerySuggestion{
-		// File Search
-		{
-			Query:       "find my [filename]",
-			Description: "Search for files in ~/.config and home directory",
-			Category:    "File Search",
-			Examples:    []string{"find my bashrc", "find my nvim config", "where is alacritty.toml"},
-		},
-		{
-			Query:       "where is [config]",
-			Description: "Locate configuration files",
-			Category:    "File Search",
-			Examples:    []string{"where is my kitty config", "locate waybar config"},
-		},
-		{
-			Query:       "search for [term]",
-			Description: "Search for files matching terms",
-			Category:    "File Search",
-			Examples:    []string{"search for hypr", "look for zsh"},
-		},
-
-		// Organization
-		{
-			Query:       "organize [path]",
-			Description: "Organize files by category (default)",
-			Category:    "Organization",
-			Examples:    []string{"organize ~/Downloads", "organize .", "tyr ~/Desktop"},
-		},
-		{
-			Query:       "organize [path] by name",
-			Description: "Organize files alphabetically by filename",
-			Category:    "Organization",
-			Examples:    []string{"organize ~/Pictures by filename", "sort ~/Documents by name"},
-		},
-		{
-			Query:       "clean up [path]",
-			Description: "Tidy up a directory",
-			Category:    "Organization",
-			Examples:    []string{"clean up ~/Downloads", "tidy ~/workspace"},
-		},
-
-		// Code Tools
-		{
-			Query:       "format [file]",
-			Description: "Auto-format code file (Python, Go, JS/TS, Shell)",
-			Category:    "Code Tools",
-			Examples:    []string{"format main.py", "lint script.go", "fix code.js"},
-		},
-		{
-			Query:       "lint [file]",
-			Description: "Check and fix code formatting",
-			Category:    "Code Tools",
-			Examples:    []string{"lint app.ts", "check code.sh"},
-		},
-
-		// OCR
-		{
-			Query:       "ocr",
-			Description: "Capture screenshot and extract text",
-			Category:    "OCR & Text",
-			Examples:    []string{"ocr", "extract text", "read screen"},
-		},
-		{
-			Query:       "extract text from [image]",
-			Description: "Extract text from an image file",
-			Category:    "OCR & Text",
-			Examples:    []string{"ocr screenshot.png", "extract text from photo.jpg"},
-		},
-		{
-			Query:       "read text from screen",
-			Description: "Screenshot selection and OCR",
-			Category:    "OCR & Text",
-			Examples:    []string{"capture text", "screenshot text"},
-		},
-
-		// Media Conversion
-		{
-			Query:       "convert [file] to [format]",
-			Description: "Convert media files using ffmpeg",
-			Category:    "Media Conversion",
-			Examples:    []string{"convert video.webm to mp4", "convert song.flac to mp3"},
-		},
-		{
-			Query:       "transcode [file]",
-			Description: "Re-encode media file",
-			Category:    "Media Conversion",
-			Examples:    []string{"transcode movie.avi", "encode audio.wav to ogg"},
-		},
-		{
-			Query:       "change format [file] to [format]",
-			Description: "Change media format",
-			Category:    "Media Conversion",
-			Examples:    []string{"change format image.png to jpg"},
-		},
-
-		// General
-		{
-			Query:       "help",
-			Description: "Show available commands and examples",
-			Category:    "General",
-			Examples:    []string{"help", "what can you do", "commands"},
-		},
+func (h *HelpService) allSuggestions() []QuerySuggestion {
+	var all []QuerySuggestion
+	for _, service := range h.services {
+		all = append(all, service.Suggestions...)
 	}
+	return all
 }
 
 // GetQuickHelp returns a formatted help message
 func (h *HelpService) GetQuickHelp() string {
-	return `Available Commands:
-
-📁 File Search
-  • find my [file] - Search for files in ~/.config and home
-  • where is [config] - Locate configuration files
+	return "Available Commands:\n\n" + h.describe(h.services) + `💡 Tips:
+  • Tab/arrow keys for autocomplete on file paths
+  • Most commands support ~ for home directory
+  • Ask anything else and I'll help with LLM!`
+}
 
-🗂️  Organization
-  • organize [path] - Organize files by category
-  • organize [path] by name - Sort alphabetically
-  • clean up [path] - Tidy up directory
+// describe lists the suggestions of services grouped by category
+func (h *HelpService) describe(services []ServiceHelp) string {
+	var b strings.Builder
+	for _, category := range h.GetCategories() {
+		var lines []string
+		icon := ""
+		for _, service := range services {
+			if service.Category != category {
+				continue
+			}
+			icon = service.Icon
+			for _, suggestion := range service.Suggestions {
+				lines = append(lines, fmt.Sprintf("  • %s - %s", suggestion.Query, suggestion.Description))
+			}
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(&b, "%s %s\n%s\n\n", icon, category, strings.Join(lines, "\n"))
+		}
+	}
+	return b.String()
+}
 
-💻 Code Tools
-  • format [file] - Auto-format code (Python/Go/JS/Shell)
-  • lint [file] - Check and fix code style
+// Help answers a "help" query. A topic narrows it to the services whose name,
+// category or keywords match.
+func (h *HelpService) Help(topic string) (HelpResult, error) {
+	topic = strings.TrimSpace(topic)
+	if topic == "" {
+		return HelpResult{Text: h.GetQuickHelp(), Suggestions: h.allSuggestions()}, nil
+	}
 
-📸 OCR & Text
-  • ocr - Screenshot and extract text
-  • extract text from [image] - OCR from image file
+	services := h.servicesFor(topic)
+	if len(services) == 0 {
+		return HelpResult{Topic: topic}, fmt.Errorf("no help for %q, try one of: %s", topic, strings.Join(h.GetCategories(), ", "))
+	}
+	result := HelpResult{Topic: topic, Text: strings.TrimSpace(h.describe(services)), Suggestions: []QuerySuggestion{}}
+	for _, service := range services {
+		result.Suggestions = append(result.Suggestions, service.Suggestions...)
+	}
+	return result, nil
+}
 
-🎬 Media Conversion
-  • convert [file] to [format] - Convert media files
-  • Supports: mp4, webm, mp3, wav, png, jpg, etc.
+// HasTopic reports whether "help <topic>" names something help can describe
+func (h *HelpService) HasTopic(topic string) bool {
+	return len(h.servicesFor(topic)) > 0
+}
 
-💡 Tips:
-  • Tab/arrow keys for autocomplete on file paths
-  • Most commands support ~ for home directory
-  • Ask anything else and I'll help with LLM!`
+func (h *HelpService) servicesFor(topic string) []ServiceHelp {
+	topic = strings.ToLower(topic)
+	var matches []ServiceHelp
+	for _, service := range h.services {
+		// Short topics like "me" in "help me" would match inside category names
+		matched := service.Service == topic || len(topic) > 2 && strings.Contains(strings.ToLower(service.Category), topic)
+		for _, keyword := range service.Keywords {
+			matched = matched || strings.TrimSpace(keyword) == topic
+		}
+		if matched {
+			matches = append(matches, service)
+		}
+	}
+	return matches
 }
 
 // ExampleQueries provides categorized examples
@@ -255,90 +243,122 @@ type ExampleQueries struct {
 	Queries  []string `json:"queries"`
 }
 
-// GetExamplesByCategory returns organized example queries
+// GetExamplesByCategory returns the examples of every service, grouped by
+// category
 func (h *HelpService) GetExamplesByCategory() []ExampleQueries {
-	return []ExampleQueries{
-		{
-			Category: "File Search",
-			Icon:     "📁",
-			Queries: []string{
-				"find my neovim config",
-				"where is alacritty.toml",
-				"locate waybar config",
-				"search for hyprland",
-			},
-		},
-		{
-			Category: "Organization",
-			Icon:     "🗂️",
-			Queries: []string{
-				"organize ~/Downloads",
-				"organize . by name",
-				"clean up ~/Desktop",
-				"tyr ~/Documents",
-			},
-		},
-		{
-			Category: "Code Tools",
-			Icon:     "💻",
-			Queries: []string{
-				"format main.py",
-				"lint app.go",
-				"fix script.sh",
-				"check code.ts",
-			},
-		},
-		{
-			Category: "OCR & Text",
-			Icon:     "📸",
-			Queries: []string{
-				"ocr",
-				"extract text from screenshot.png",
-				"read screen",
-				"capture text",
-			},
-		},
-		{
-			Category: "Media Conversion",
-			Icon:     "🎬",
-			Queries: []string{
-				"convert video.webm to mp4",
-				"convert song.flac to mp3",
-				"transcode movie.avi",
-				"change format image.png to jpg",
-			},
-		},
+	var examples []ExampleQueries
+	for _, category := range h.GetCategories() {
+		group := ExampleQueries{Category: category, Queries: []string{}}
+		for _, service := range h.services {
+			if service.Category != category {
+				continue
+			}
+			if group.Icon == "" {
+				group.Icon = service.Icon
+			}
+			for _, suggestion := range service.Suggestions {
+				group.Queries = append(group.Queries, suggestion.Examples...)
+			}
+		}
+		examples = append(examples, group)
 	}
+	return examples
 }
 
-// DetectTypos suggests corrections for common misspellings
+// DetectTypos suggests a corrected query when a word naming a service is
+// misspelled, "convrt song.flac to mp3" becomes "convert song.flac to mp3".
+// Only the first word of each step is checked, plus the word after it when
+// the two form a keyword like "extract text", so free text is left alone.
+// Real words are never corrected: "task list" doesn't become "ask list".
 func (h *HelpService) DetectTypos(query string) []string {
-	typoMap := map[string]string{
-		"fnd":       "find",
-		"serach":    "search",
-		"organize":  "organize",
-		"organze":   "organize",
-		"formatt":   "format",
-		"lint":      "lint",
-		"conver":    "convert",
-		"convrt":    "convert",
-		"trnscode":  "transcode",
+	words := strings.Fields(query)
+	corrected := false
+	stepStart := true
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		if lower == "|" || lower == "then" {
+			stepStart = true
+			continue
+		}
+
+		var candidates []string
+		switch {
+		case stepStart:
+			for keyword := range h.vocabulary {
+				candidates = append(candidates, keyword)
+			}
+		case i > 0:
+			candidates = h.nextWords[strings.ToLower(words[i-1])]
+		}
+		stepStart = false
+
+		if fix := closestWord(lower, candidates); fix != "" {
+			words[i] = fix
+			corrected = true
+		}
 	}
 
-	words := strings.Fields(strings.ToLower(query))
-	var suggestions []string
-	hasTypo := false
+	if !corrected {
+		return []string{}
+	}
+	return []string{strings.Join(words, " ")}
+}
 
-	for i, word := range words {
-		if correction, exists := typoMap[word]; exists {
-			hasTypo = true
-			words[i] = correction
+// closestWord returns the candidate within typo distance of word, or "" when
+// word is already a candidate, a common word, isn't a plain word or nothing is
+// close enough
+func closestWord(word string, candidates []string) string {
+	if commonWords[word] || len([]rune(word)) < 3 || strings.IndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+		return ""
+	}
+
+	best, bestDistance := "", maxTypoDistance(word)+1
+	// Sorted so ties always resolve the same way
+	sorted := append([]string{}, candidates...)
+	sort.Strings(sorted)
+	for _, candidate := range sorted {
+		if candidate == word {
+			return ""
+		}
+		if d := editDistance(word, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
 		}
 	}
+	return best
+}
 
-	if hasTypo {
-		suggestions = append(suggestions, strings.Join(words, " "))
+// maxTypoDistance allows one edit in short words and two in long ones
+func maxTypoDistance(word string) int {
+	if len([]rune(word)) >= 8 {
+		return 2
 	}
+	return 1
+}
 
-	return suggestions
+// editDistance is the Damerau-Levenshtein (optimal string alignment) distance,
+// so a swapped pair like "serach" counts as one edit
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(min(d[i-1][j]+1, d[i][j-1]+1), d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
 }
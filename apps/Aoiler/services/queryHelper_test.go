@@ -0,0 +1,100 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"convert", "convert", 0},
+		{"", "ocr", 3},
+		{"convrt", "convert", 1},
+		{"serach", "search", 1},
+		{"lnit", "lint", 1},
+		{"screnshot", "screenshot", 1},
+		{"scerenshto", "screenshot", 2},
+		{"kitten", "sitting", 3},
+		{"ocr", "llm", 3},
+		{"größe", "grösse", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestClosestWord(t *testing.T) {
+	candidates := []string{"ask", "convert", "find", "lint", "screenshot", "search", "sort"}
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"convrt", "convert"},
+		{"fnid", "find"},
+		{"serach", "search"},
+		{"screnshto", "screenshot"},
+		// Already right
+		{"convert", ""},
+		// Too far off
+		{"cnvrtt", ""},
+		{"translate", ""},
+		// Too short to guess
+		{"fi", ""},
+		// Not a plain word
+		{"find2", ""},
+		{"~/lnit", ""},
+		// Real words a keyword is one edit away from
+		{"task", ""},
+		{"fine", ""},
+		{"list", ""},
+		{"short", ""},
+	}
+	for _, tt := range tests {
+		if got := closestWord(tt.word, candidates); got != tt.want {
+			t.Errorf("closestWord(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestDetectTypos(t *testing.T) {
+	h := NewHelpService([]ServiceHelp{
+		{Service: "filesearch", Keywords: []string{"find", "search for"}},
+		{Service: "converter", Keywords: []string{"convert", "extract audio"}},
+		{Service: "ocr", Keywords: []string{"ocr", "extract text"}},
+		{Service: "linter", Keywords: []string{"lint", "format"}},
+		{Service: "llm", Keywords: []string{"ask"}},
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"convrt song.flac to mp3", []string{"convert song.flac to mp3"}},
+		{"extract txet from ~/scan.png", []string{"extract text from ~/scan.png"}},
+		{"serach for bashrc", []string{"search for bashrc"}},
+		{"fnid invoice then ocr", []string{"find invoice then ocr"}},
+		{"find invoice.png | lnit", []string{"find invoice.png | lint"}},
+		// Only step starts are checked, so free text is left alone
+		{"convert song.flac to mp3", []string{}},
+		{"ask why the fnid command is slow", []string{}},
+		// Dictionary words stay as they are
+		{"task list for today", []string{}},
+		{"fine tune the model", []string{}},
+		{"list my files", []string{}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := h.DetectTypos(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DetectTypos(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultQueryHistoryEntries = 1000
+	queryHistorySearchLimit    = 100
+	querySummaryLength         = 160
+)
+
+// QueryHistoryOptions controls how long past queries are kept. Pinned
+// entries are never pruned.
+type QueryHistoryOptions struct {
+	// Disabled turns off recording new queries
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxEntries caps the number of stored queries, oldest go first (default 1000)
+	MaxEntries int `json:"maxEntries,omitempty"`
+	// MaxAgeDays drops queries older than this, 0 keeps them forever
+	MaxAgeDays int `json:"maxAgeDays,omitempty"`
+}
+
+// QueryHistoryEntry is one finished query
+type QueryHistoryEntry struct {
+	ID         string            `json:"id"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Query      string            `json:"query"`
+	Service    string            `json:"service"`
+	Params     map[string]string `json:"params,omitempty"`
+	Status     string            `json:"status"`
+	Success    bool              `json:"success"`
+	DurationMs int64             `json:"durationMs"`
+	Summary    string            `json:"summary,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Pinned     bool              `json:"pinned,omitempty"`
+	// Redacted entries had text taken out of the query, so they can't be rerun
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// QueryHistoryResult lists entries matching a history search, pinned first
+type QueryHistoryResult struct {
+	Terms   string              `json:"terms"`
+	Entries []QueryHistoryEntry `json:"entries"`
+	Total   int                 `json:"total"`
+}
+
+// QueryHistory keeps finished queries in ~/.local/share/aoiler/history.json.
+// The window, the daemon and `aoiler query --local` all write that file, so
+// every change re-reads it under a file lock instead of trusting memory.
+type QueryHistory struct {
+	opts    QueryHistoryOptions
+	path    string
+	mu      sync.Mutex
+	entries []QueryHistoryEntry
+}
+
+func NewQueryHistory(opts QueryHistoryOptions) *QueryHistory {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = defaultQueryHistoryEntries
+	}
+	return &QueryHistory{opts: opts, path: filepath.Join(aoilerDataDir(), "history.json")}
+}
+
+// Record stores a finished job. Running jobs are ignored.
+func (h *QueryHistory) Record(job JobInfo) error {
+	if h.opts.Disabled || !job.Done() {
+		return nil
+	}
+
+	// The query itself is already stored once
+	params := make(map[string]string, len(job.Params))
+	for key, value := range job.Params {
+		if key != "query" && value != "" {
+			params[key] = value
+		}
+	}
+
+	timestamp := job.StartedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	entry := QueryHistoryEntry{
+		ID:         job.ID,
+		Timestamp:  timestamp,
+		Query:      job.Query,
+		Service:    job.Service,
+		Params:     params,
+		Status:     job.Status,
+		Success:    job.Status == JobSucceeded,
+		DurationMs: job.DurationMs,
+		Summary:    truncate(SummarizeResult(job.Result), querySummaryLength),
+		Error:      job.Error,
+	}
+	// Notes and snippets can hold passwords or keys, only keep that one was saved
+	if job.Service == "notes" {
+		if query, ok := redactNotesQuery(job.Query); ok {
+			entry.Query = query
+			entry.Redacted = true
+			if r, ok := job.Result.(NotesResult); ok {
+				entry.Summary = "saved " + r.Kind
+			}
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.updateLocked(func() bool {
+		h.entries = append(h.entries, entry)
+		return true
+	})
+}
+
+// Search returns entries whose query, service or summary contain every
+// term, pinned entries first and then newest first. No terms lists everything.
+func (h *QueryHistory) Search(terms string) (QueryHistoryResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := QueryHistoryResult{Terms: terms, Entries: []QueryHistoryEntry{}}
+	if err := h.loadLocked(); err != nil {
+		return result, err
+	}
+
+	words := strings.Fields(strings.ToLower(terms))
+	var matches []QueryHistoryEntry
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		entry := h.entries[i]
+		text := strings.ToLower(entry.Query + " " + entry.Service + " " + entry.Summary)
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, entry)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Pinned && !matches[j].Pinned
+	})
+
+	result.Total = len(matches)
+	if len(matches) > queryHistorySearchLimit {
+		matches = matches[:queryHistorySearchLimit]
+	}
+	result.Entries = append(result.Entries, matches...)
+	return result, nil
+}
+
+// Recent returns up to limit distinct queries, newest first, for stepping
+// through with the arrow keys
+func (h *QueryHistory) Recent(limit int) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.loadLocked(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	queries := []string{}
+	for i := len(h.entries) - 1; i >= 0 && len(queries) < limit; i-- {
+		query := h.entries[i].Query
+		if !seen[query] && !h.entries[i].Redacted {
+			seen[query] = true
+			queries = append(queries, query)
+		}
+	}
+	return queries, nil
+}
+
+// Pin marks an entry as a favorite, or unmarks it
+func (h *QueryHistory) Pin(id string, pinned bool) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	found := false
+	err := h.updateLocked(func() bool {
+		for i := range h.entries {
+			if h.entries[i].ID == id {
+				h.entries[i].Pinned = pinned
+				found = true
+			}
+		}
+		return found
+	})
+	return found, err
+}
+
+// Delete removes an entry, pinned or not
+func (h *QueryHistory) Delete(id string) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	found := false
+	err := h.updateLocked(func() bool {
+		for i, entry := range h.entries {
+			if entry.ID == id {
+				h.entries = append(h.entries[:i], h.entries[i+1:]...)
+				found = true
+				break
+			}
+		}
+		return found
+	})
+	return found, err
+}
+
+// loadLocked re-reads the file, which another process may have changed.
+// Writes are atomic renames, so reading needs no file lock.
+func (h *QueryHistory) loadLocked() error {
+	h.entries = nil
+	data, err := os.ReadFile(h.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read query history: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &h.entries); err != nil {
+			return fmt.Errorf("invalid query history %s: %w", h.path, err)
+		}
+	}
+	// Retention settings may have tightened since the file was written
+	h.pruneLocked()
+	return nil
+}
+
+// updateLocked re-reads the history under an exclusive file lock, lets change
+// edit the entries and writes them back if it reports a change
+func (h *QueryHistory) updateLocked(change func() bool) error {
+	unlock, err := lockFile(h.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock query history: %w", err)
+	}
+	defer unlock()
+
+	if err := h.loadLocked(); err != nil {
+		return err
+	}
+	if !change() {
+		return nil
+	}
+	h.pruneLocked()
+	return h.saveLocked()
+}
+
+// lockFile takes an exclusive flock on path, which other processes honour,
+// and returns the function that releases it
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func (h *QueryHistory) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(h.path, data); err != nil {
+		return fmt.Errorf("failed to save query history: %w", err)
+	}
+	return nil
+}
+
+// pruneLocked applies the age and size limits to unpinned entries, which are
+// kept oldest first
+func (h *QueryHistory) pruneLocked() bool {
+	var cutoff time.Time
+	if h.opts.MaxAgeDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -h.opts.MaxAgeDays)
+	}
+
+	excess := len(h.entries) - h.opts.MaxEntries
+	kept := h.entries[:0]
+	pruned := false
+	for _, entry := range h.entries {
+		if !entry.Pinned && (excess > 0 || entry.Timestamp.Before(cutoff)) {
+			excess--
+			pruned = true
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	h.entries = kept
+	return pruned
+}
+
+// SummarizeResult describes a service result in one line, for the history
+// and for plain command-line output
+func SummarizeResult(result interface{}) string {
+	var summary string
+	switch r := result.(type) {
+	case FileSearchResult:
+		summary = r.Path
+	case OrganizerResult:
+		summary = firstLine(r.Output)
+	case LinterResult:
+		switch {
+		case r.Mode == "check":
+			summary = fmt.Sprintf("%s: %d errors, %d warnings", r.LinterUsed, r.ErrorCount, r.WarningCount)
+		case r.Changed:
+			summary = fmt.Sprintf("%s: %s would change", r.LinterUsed, filepath.Base(r.FilePath))
+		default:
+			summary = fmt.Sprintf("%s: already formatted", r.LinterUsed)
+		}
+	case LinterRunResult:
+		summary = fmt.Sprintf("%d files, %d changed, %d failed", r.Summary.FilesProcessed, r.Summary.FilesChanged, r.Summary.FilesFailed)
+	case OCRResult:
+		summary = strings.Join(strings.Fields(r.Text), " ")
+	case OCRBatchResult:
+		summary = fmt.Sprintf("%d files, %d pages, %d words", r.Summary.FilesProcessed, r.Summary.PagesProcessed, r.Summary.WordCount)
+	case OCRHistoryResult:
+		summary = fmt.Sprintf("%d captures", r.Total)
+	case ConverterResult:
+		summary = r.OutputPath
+	case ConversionBatchResult:
+		summary = fmt.Sprintf("converted %d of %d files to %s", r.Summary.FilesConverted, r.Summary.FilesProcessed, r.Summary.Format)
+	case MediaInfo:
+		summary = fmt.Sprintf("%s, %d streams", r.Container, len(r.Streams))
+		if r.Duration > 0 {
+			summary += ", " + formatClock(r.Duration)
+		}
+	case DocumentResult:
+		summary = r.OutputPath
+	case LLMResult:
+		summary = strings.Join(strings.Fields(r.Response), " ")
+	case ClipboardResult:
+		// Only a count, clipboard entries can hold passwords
+		summary = fmt.Sprintf("%d clipboard entries", r.Total)
+	case CalculatorResult:
+		summary = r.Expression + " = " + r.Result
+	case LauncherResult:
+		for _, app := range r.Apps {
+			if app.ID == r.Launched {
+				summary = "launched " + app.Name
+				if app.Action != "" {
+					summary += " · " + app.Action
+				}
+			}
+		}
+	case DesktopResult:
+		switch {
+		case r.PendingID != "":
+			summary = "waiting for confirmation: " + r.Description
+		case r.Action == "volume" && r.Muted:
+			summary = fmt.Sprintf("volume %d%% (muted)", r.Volume)
+		case r.Action == "volume":
+			summary = fmt.Sprintf("volume %d%%", r.Volume)
+		case r.Action == "themes":
+			summary = fmt.Sprintf("%d themes, current %s", len(r.Themes), r.CurrentTheme)
+		default:
+			summary = r.Description
+		}
+	case NotesResult:
+		if r.Action == "saved" && len(r.Notes) > 0 {
+			summary = "saved " + r.Kind + ": " + r.Notes[0].Title
+		} else {
+			summary = fmt.Sprintf("%d notes", r.Total)
+		}
+	case ScreenshotResult:
+		switch {
+		case r.Action == "stop":
+			summary = fmt.Sprintf("stopped %d recordings", r.Stopped)
+		case r.Clipboard:
+			summary = r.Mode + " screenshot copied"
+		case r.Action == "recording":
+			summary = fmt.Sprintf("%s (%s)", r.Path, formatClock(r.Seconds))
+		default:
+			summary = r.Path
+		}
+	case HelpResult:
+		summary = fmt.Sprintf("%d commands", len(r.Suggestions))
+	case PipelineResult:
+		services := make([]string, len(r.Stages))
+		for i, stage := range r.Stages {
+			services[i] = stage.Service
+		}
+		summary = strings.Join(services, " → ")
+		if n := len(r.Stages); n > 0 && r.Stages[n-1].Success {
+			summary += ": " + SummarizeResult(r.Stages[n-1].Result)
+		}
+	}
+	return summary
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+// truncate shortens text to at most n runes, marking the cut
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// aoilerDataDir is where Aoiler keeps history and other state
+func aoilerDataDir() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		homeDir, _ := os.UserHomeDir()
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(dataHome, "aoiler")
+}
@@ -0,0 +1,524 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// captureDelay lets the compositor hide Aoiler's window and refocus the
+	// previous one before grim or wf-recorder start
+	captureDelay = 300 * time.Millisecond
+	// recordingStopWait is how long wf-recorder gets to finish the file
+	// after being interrupted
+	recordingStopWait = 10 * time.Second
+)
+
+// Capture commands, anchored so "screenshot text" still goes to OCR
+var (
+	screenshotPattern = regexp.MustCompile(`(?i)^(?:take\s+(?:a\s+)?)?screenshot(?:\s+(?:of\s+)?(?:the\s+)?(screen|full\s*screen|window|active\s+window|region|area|selection))?(\s+(?:to|into)\s+(?:the\s+)?clipboard)?$`)
+	// "record screen for 30s", "record region with audio", "start recording window for 2 minutes"
+	recordPattern        = regexp.MustCompile(`(?i)^(?:start\s+)?record(?:ing)?\s+(?:the\s+)?(screen|full\s*screen|window|active\s+window|region|area|selection)(\s+with\s+(?:audio|sound))?(?:\s+for\s+(\d+(?:\.\d+)?)\s*(s|secs?|seconds?|m|mins?|minutes?))?(\s+with\s+(?:audio|sound))?$`)
+	stopRecordingPattern = regexp.MustCompile(`(?i)^(?:stop|end|finish)\s+(?:the\s+)?(?:screen\s+)?recordings?$`)
+)
+
+// ScreenshotConfig sets where captures are saved and how they are named.
+// Filenames are templates: {date} is replaced by 2006-01-02, {time} by
+// 15-04-05 and {mode} by screen, window or region. The extension picks the
+// format.
+type ScreenshotConfig struct {
+	// Directory defaults to $XDG_PICTURES_DIR/Screenshots
+	Directory string `json:"directory,omitempty"`
+	// Filename defaults to "Screenshot_{date}_{time}.png", like ScreenShot.sh
+	Filename string `json:"filename,omitempty"`
+	// RecordingDirectory defaults to $XDG_VIDEOS_DIR/Recordings
+	RecordingDirectory string `json:"recordingDirectory,omitempty"`
+	// RecordingFilename defaults to "Recording_{date}_{time}.mp4"
+	RecordingFilename string `json:"recordingFilename,omitempty"`
+}
+
+// ScreenshotResult reports a screenshot, a finished recording, or how many
+// recordings "stop recording" ended
+type ScreenshotResult struct {
+	// Action is screenshot, recording or stop
+	Action string `json:"action"`
+	// Mode is screen, window or region
+	Mode string `json:"mode,omitempty"`
+	// Path is empty for a screenshot that only went to the clipboard
+	Path      string `json:"path,omitempty"`
+	Clipboard bool   `json:"clipboard,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	// RecordingID stops a recording through StopRecording while it runs
+	RecordingID string  `json:"recordingId,omitempty"`
+	Seconds     float64 `json:"seconds,omitempty"`
+	Audio       bool    `json:"audio,omitempty"`
+	// Stopped counts the recordings a stop command ended
+	Stopped int `json:"stopped,omitempty"`
+}
+
+// captureRequest is one parsed capture command
+type captureRequest struct {
+	action    string
+	mode      string
+	clipboard bool
+	audio     bool
+	duration  time.Duration
+}
+
+// recording is a running wf-recorder that the UI can stop by ID
+type recording struct {
+	id      string
+	output  string
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stopped bool
+}
+
+// ScreenshotService takes screenshots with grim and records the screen with
+// wf-recorder, using slurp for regions and hyprctl for the active window
+type ScreenshotService struct {
+	cfg     ScreenshotConfig
+	emitter EventEmitter
+
+	mu         sync.Mutex
+	recordings map[string]*recording
+}
+
+func NewScreenshotService(cfg ScreenshotConfig) *ScreenshotService {
+	if cfg.Directory == "" {
+		cfg.Directory = filepath.Join(xdgUserDir("XDG_PICTURES_DIR", "Pictures"), "Screenshots")
+	}
+	if cfg.Filename == "" {
+		cfg.Filename = "Screenshot_{date}_{time}.png"
+	}
+	if cfg.RecordingDirectory == "" {
+		cfg.RecordingDirectory = filepath.Join(xdgUserDir("XDG_VIDEOS_DIR", "Videos"), "Recordings")
+	}
+	if cfg.RecordingFilename == "" {
+		cfg.RecordingFilename = "Recording_{date}_{time}.mp4"
+	}
+	cfg.Directory = expandHome(cfg.Directory)
+	cfg.RecordingDirectory = expandHome(cfg.RecordingDirectory)
+
+	if filepath.Ext(cfg.Filename) == "" {
+		cfg.Filename += ".png"
+	} else if grimFormat(cfg.Filename) == "" {
+		warnf("grim can't write %s, saving screenshots as png", filepath.Ext(cfg.Filename))
+		cfg.Filename = strings.TrimSuffix(cfg.Filename, filepath.Ext(cfg.Filename)) + ".png"
+	}
+	if filepath.Ext(cfg.RecordingFilename) == "" {
+		cfg.RecordingFilename += ".mp4"
+	}
+
+	return &ScreenshotService{cfg: cfg, recordings: make(map[string]*recording)}
+}
+
+// Available reports whether grim is installed
+func (ss *ScreenshotService) Available() bool {
+	_, err := exec.LookPath("grim")
+	return err == nil
+}
+
+// Handles reports whether a query is a screenshot or recording command
+func (ss *ScreenshotService) Handles(query string) bool {
+	_, ok := parseCaptureRequest(query)
+	return ok
+}
+
+// HidesWindow reports whether a query captures the screen, so the caller
+// can hide its own window first. Stopping a recording doesn't.
+func (ss *ScreenshotService) HidesWindow(query string) bool {
+	req, ok := parseCaptureRequest(query)
+	return ok && req.action != "stop"
+}
+
+// Capture runs a screenshot, recording or stop command
+func (ss *ScreenshotService) Capture(ctx context.Context, query string) (ScreenshotResult, error) {
+	req, ok := parseCaptureRequest(query)
+	if !ok {
+		return ScreenshotResult{}, fmt.Errorf("not a screenshot command: %s", query)
+	}
+	switch req.action {
+	case "stop":
+		return ScreenshotResult{Action: "stop", Stopped: ss.StopAll()}, nil
+	case "recording":
+		return ss.record(ctx, req)
+	}
+	return ss.screenshot(ctx, req)
+}
+
+func parseCaptureRequest(query string) (captureRequest, bool) {
+	query = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(query), ".!"))
+
+	if stopRecordingPattern.MatchString(query) {
+		return captureRequest{action: "stop"}, true
+	}
+	if m := screenshotPattern.FindStringSubmatch(query); m != nil {
+		return captureRequest{action: "screenshot", mode: captureMode(m[1]), clipboard: m[2] != ""}, true
+	}
+	if m := recordPattern.FindStringSubmatch(query); m != nil {
+		req := captureRequest{action: "recording", mode: captureMode(m[1]), audio: m[2] != "" || m[5] != ""}
+		if m[3] != "" {
+			amount, _ := strconv.ParseFloat(m[3], 64)
+			unit := time.Second
+			if strings.HasPrefix(strings.ToLower(m[4]), "m") {
+				unit = time.Minute
+			}
+			req.duration = time.Duration(amount * float64(unit))
+		}
+		return req, true
+	}
+	return captureRequest{}, false
+}
+
+// captureMode folds the ways to say what to capture into screen, window
+// and region
+func captureMode(word string) string {
+	word = strings.ToLower(word)
+	switch {
+	case strings.Contains(word, "window"):
+		return "window"
+	case word == "region" || word == "area" || word == "selection":
+		return "region"
+	}
+	return "screen"
+}
+
+func (ss *ScreenshotService) screenshot(ctx context.Context, req captureRequest) (ScreenshotResult, error) {
+	result := ScreenshotResult{Action: "screenshot", Mode: req.mode, Clipboard: req.clipboard}
+	if _, err := exec.LookPath("grim"); err != nil {
+		return result, fmt.Errorf("grim is not installed")
+	}
+	if req.clipboard {
+		if _, err := exec.LookPath("wl-copy"); err != nil {
+			return result, fmt.Errorf("wl-copy is not installed, can't copy the screenshot")
+		}
+	}
+
+	geometry, err := captureGeometry(ctx, req.mode)
+	if err != nil {
+		return result, err
+	}
+
+	args := []string{}
+	if geometry != "" {
+		args = append(args, "-g", geometry)
+	}
+
+	// Clipboard screenshots aren't saved, grim writes the image to stdout
+	if req.clipboard {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "grim", append(args, "-")...)
+		cmd.Stderr = &stderr
+		image, err := cmd.Output()
+		if err != nil {
+			return result, fmt.Errorf("grim failed: %s", strings.TrimSpace(stderr.String()))
+		}
+		copyCmd := exec.CommandContext(ctx, "wl-copy", "--type", "image/png")
+		copyCmd.Stdin = bytes.NewReader(image)
+		if output, err := copyCmd.CombinedOutput(); err != nil {
+			return result, fmt.Errorf("wl-copy failed: %s", strings.TrimSpace(string(output)))
+		}
+		result.Size = int64(len(image))
+		return result, nil
+	}
+
+	path, err := capturePath(ss.cfg.Directory, ss.cfg.Filename, req.mode)
+	if err != nil {
+		return result, err
+	}
+	args = append(args, "-t", grimFormat(path), path)
+	if output, err := exec.CommandContext(ctx, "grim", args...).CombinedOutput(); err != nil {
+		os.Remove(path)
+		return result, fmt.Errorf("grim failed: %s", strings.TrimSpace(string(output)))
+	}
+	result.Path = path
+	if info, err := os.Stat(path); err == nil {
+		result.Size = info.Size()
+	}
+	return result, nil
+}
+
+// record runs wf-recorder until the duration is up, StopRecording is called
+// or the job ends. Cancelling the job discards the file, a stop or the job
+// timeout keeps it.
+func (ss *ScreenshotService) record(ctx context.Context, req captureRequest) (ScreenshotResult, error) {
+	result := ScreenshotResult{Action: "recording", Mode: req.mode, Audio: req.audio}
+	if _, err := exec.LookPath("wf-recorder"); err != nil {
+		return result, fmt.Errorf("wf-recorder is not installed")
+	}
+
+	geometry, err := captureGeometry(ctx, req.mode)
+	if err != nil {
+		return result, err
+	}
+	path, err := capturePath(ss.cfg.RecordingDirectory, ss.cfg.RecordingFilename, req.mode)
+	if err != nil {
+		return result, err
+	}
+
+	// wf-recorder asks which output to record when there are several, so
+	// full screen recordings pick the focused monitor
+	args := []string{"-f", path}
+	if geometry != "" {
+		args = append(args, "-g", geometry)
+	} else if monitor := focusedMonitor(ctx); monitor != "" {
+		args = append(args, "-o", monitor)
+	}
+	if req.audio {
+		args = append(args, "-a")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "wf-recorder", args...)
+	cmd.Stderr = &stderr
+	// An interrupt lets wf-recorder write the trailer so the file plays
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = recordingStopWait
+	// Interrupt it too if Aoiler dies, rather than leave it recording
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGINT}
+
+	rec := &recording{id: newID(), output: path, cmd: cmd}
+	if err := cmd.Start(); err != nil {
+		return result, fmt.Errorf("failed to start wf-recorder: %w", err)
+	}
+	started := time.Now()
+	result.RecordingID = rec.id
+
+	ss.mu.Lock()
+	ss.recordings[rec.id] = rec
+	ss.mu.Unlock()
+	ss.emitter.emit("recording:start", map[string]interface{}{
+		"recordingId": rec.id,
+		"output":      path,
+		"duration":    req.duration.Seconds(),
+	})
+
+	waited := make(chan error, 1)
+	go func() { waited <- cmd.Wait() }()
+
+	var limit <-chan time.Time
+	if req.duration > 0 {
+		timer := time.NewTimer(req.duration)
+		defer timer.Stop()
+		limit = timer.C
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var waitErr error
+wait:
+	for {
+		select {
+		case waitErr = <-waited:
+			break wait
+		case <-limit:
+			rec.stop()
+		case <-ticker.C:
+			elapsed := time.Since(started).Seconds()
+			if req.duration > 0 {
+				reportProgress(ctx, elapsed/req.duration.Seconds(), fmt.Sprintf("recording %s of %s", formatClock(elapsed), formatClock(req.duration.Seconds())))
+			} else {
+				reportProgress(ctx, -1, "recording "+formatClock(elapsed))
+			}
+		}
+	}
+	result.Seconds = time.Since(started).Seconds()
+
+	ss.mu.Lock()
+	delete(ss.recordings, rec.id)
+	ss.mu.Unlock()
+
+	rec.mu.Lock()
+	stopped := rec.stopped
+	rec.mu.Unlock()
+
+	err = nil
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		err = fmt.Errorf("recording cancelled")
+	case waitErr != nil && !stopped && ctx.Err() == nil:
+		err = fmt.Errorf("wf-recorder failed: %s", lastLine(stderr.String()))
+	}
+	if err == nil {
+		if info, statErr := os.Stat(path); statErr != nil || info.Size() == 0 {
+			err = fmt.Errorf("wf-recorder wrote nothing: %s", lastLine(stderr.String()))
+		} else {
+			result.Size = info.Size()
+		}
+	}
+
+	ss.emitter.emit("recording:done", map[string]interface{}{
+		"recordingId": rec.id,
+		"success":     err == nil,
+	})
+	if err != nil {
+		os.Remove(path)
+		return result, err
+	}
+	result.Path = path
+	return result, nil
+}
+
+// stop interrupts wf-recorder so it finishes the file
+func (r *recording) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped || r.cmd.Process == nil {
+		return
+	}
+	r.stopped = true
+	r.cmd.Process.Signal(os.Interrupt)
+}
+
+// StopRecording ends a running recording by ID, keeping what was recorded.
+// It returns false if the ID is unknown or the recording already ended.
+func (ss *ScreenshotService) StopRecording(id string) bool {
+	ss.mu.Lock()
+	rec, ok := ss.recordings[id]
+	ss.mu.Unlock()
+	if ok {
+		rec.stop()
+	}
+	return ok
+}
+
+// StopAll ends every running recording and returns how many there were
+func (ss *ScreenshotService) StopAll() int {
+	ss.mu.Lock()
+	recordings := make([]*recording, 0, len(ss.recordings))
+	for _, rec := range ss.recordings {
+		recordings = append(recordings, rec)
+	}
+	ss.mu.Unlock()
+
+	for _, rec := range recordings {
+		rec.stop()
+	}
+	return len(recordings)
+}
+
+// captureGeometry returns the grim/wf-recorder geometry for a mode, empty
+// for the whole screen
+func captureGeometry(ctx context.Context, mode string) (string, error) {
+	switch mode {
+	case "region":
+		if _, err := exec.LookPath("slurp"); err != nil {
+			return "", fmt.Errorf("slurp is not installed, it's needed to select a region")
+		}
+		geometry, err := exec.CommandContext(ctx, "slurp").Output()
+		if err != nil || strings.TrimSpace(string(geometry)) == "" {
+			return "", fmt.Errorf("selection cancelled")
+		}
+		return strings.TrimSpace(string(geometry)), nil
+	case "window":
+		return activeWindowGeometry(ctx)
+	}
+
+	select {
+	case <-time.After(captureDelay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return "", nil
+}
+
+// activeWindowGeometry asks Hyprland where the focused window is, after
+// giving focus a moment to return from Aoiler's own window
+func activeWindowGeometry(ctx context.Context) (string, error) {
+	if _, err := exec.LookPath("hyprctl"); err != nil {
+		return "", fmt.Errorf("hyprctl is not installed, window captures need Hyprland")
+	}
+	select {
+	case <-time.After(captureDelay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	output, err := exec.CommandContext(ctx, "hyprctl", "activewindow", "-j").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not get the active window from hyprctl")
+	}
+	var window struct {
+		At   []int `json:"at"`
+		Size []int `json:"size"`
+	}
+	if err := json.Unmarshal(output, &window); err != nil || len(window.At) != 2 || len(window.Size) != 2 || window.Size[0] == 0 || window.Size[1] == 0 {
+		return "", fmt.Errorf("no active window to capture")
+	}
+	return fmt.Sprintf("%d,%d %dx%d", window.At[0], window.At[1], window.Size[0], window.Size[1]), nil
+}
+
+// focusedMonitor returns the name of the focused output, empty if Hyprland
+// can't tell
+func focusedMonitor(ctx context.Context) string {
+	output, err := exec.CommandContext(ctx, "hyprctl", "monitors", "-j").Output()
+	if err != nil {
+		return ""
+	}
+	var monitors []struct {
+		Name    string `json:"name"`
+		Focused bool   `json:"focused"`
+	}
+	if err := json.Unmarshal(output, &monitors); err != nil {
+		return ""
+	}
+	for _, monitor := range monitors {
+		if monitor.Focused {
+			return monitor.Name
+		}
+	}
+	return ""
+}
+
+// capturePath expands a filename template in dir, adding _2, _3 and so on
+// when two captures land in the same second
+func capturePath(dir, template, mode string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	now := time.Now()
+	name := strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("15-04-05"),
+		"{mode}", mode,
+	).Replace(template)
+	name = strings.ReplaceAll(name, string(filepath.Separator), "-")
+	return uniquePath(filepath.Join(dir, name)), nil
+}
+
+// grimFormat maps a file extension to grim's -t type, empty if grim can't
+// write it
+func grimFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return "png"
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	case ".ppm":
+		return "ppm"
+	}
+	return ""
+}
+
+// xdgUserDir reads a user directory like XDG_PICTURES_DIR from the
+// environment, falling back to a folder in the home directory
+func xdgUserDir(env, fallback string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return expandHome(dir)
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, fallback)
+}
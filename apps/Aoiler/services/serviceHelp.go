@@ -0,0 +1,421 @@
+package services
+
+// ServiceHelp describes one service for the help panel, the suggestions
+// shown while typing and typo correction. Every service registered with the
+// ServiceManager provides one.
+type ServiceHelp struct {
+	Service     string `json:"service"`
+	Category    string `json:"category"`
+	Icon        string `json:"icon"`
+	Description string `json:"description"`
+	// Keywords are the words that route a query to the service
+	Keywords    []string          `json:"keywords"`
+	Suggestions []QuerySuggestion `json:"suggestions"`
+}
+
+func (fs *FileSearchService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "filesearch",
+		Category:    "File Search",
+		Icon:        "📁",
+		Description: "Find files and directories",
+		Keywords:    fileSearchKeywords,
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "find my [filename]",
+				Description: "Search for files in ~/.config and home directory",
+				Examples:    []string{"find my bashrc", "find my nvim config", "where is alacritty.toml"},
+			},
+			{
+				Query:       "where is [config]",
+				Description: "Locate configuration files",
+				Examples:    []string{"where is my kitty config", "locate waybar config"},
+			},
+			{
+				Query:       "search for [term]",
+				Description: "Search for files matching terms",
+				Examples:    []string{"search for hypr", "look for zsh"},
+			},
+			{
+				Query:       "find [words in a scan]",
+				Description: "Search the text of folders read with \"ocr [folder]\"",
+				Examples:    []string{"find invoice acme", "search for receipt 2024"},
+			},
+		},
+	}
+}
+
+func (o *OrganizerService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "organizer",
+		Category:    "Organization",
+		Icon:        "🗂️",
+		Description: "Organize files with Tyr",
+		Keywords:    organizerKeywords,
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "organize [path]",
+				Description: "Organize files by category (default)",
+				Examples:    []string{"organize ~/Downloads", "organize .", "tyr ~/Desktop"},
+			},
+			{
+				Query:       "organize [path] by name",
+				Description: "Organize files alphabetically by filename",
+				Examples:    []string{"organize ~/Pictures by filename", "sort ~/Documents by name"},
+			},
+			{
+				Query:       "clean up [path]",
+				Description: "Tidy up a directory",
+				Examples:    []string{"clean up ~/Downloads", "clean ~/Desktop"},
+			},
+		},
+	}
+}
+
+func (ls *LinterService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "linter",
+		Category:    "Code Tools",
+		Icon:        "💻",
+		Description: "Lint and format code files",
+		Keywords:    linterKeywords,
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "format [file]",
+				Description: "Auto-format code (Python, Go, JS/TS, Shell, Rust, Lua, QML, ...)",
+				Examples:    []string{"format main.py", "format script.sh", "fix code app.js"},
+			},
+			{
+				Query:       "lint [file]",
+				Description: "Run linters and list diagnostics",
+				Examples:    []string{"lint app.ts", "check code main.go"},
+			},
+			{
+				Query:       "lint [directory]",
+				Description: "Lint or format a whole project, respecting .gitignore",
+				Examples:    []string{"lint ~/projects/foo", "format ."},
+			},
+		},
+	}
+}
+
+func (ocr *OCRService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "ocr",
+		Category:    "OCR & Text",
+		Icon:        "📸",
+		Description: "Extract text from screen area",
+		Keywords:    append(append([]string{}, ocrKeywords...), "history"),
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "ocr",
+				Description: "Screenshot a selection and extract its text",
+				Examples:    []string{"ocr", "read screen", "capture text"},
+			},
+			{
+				Query:       "ocr [image] in [language]",
+				Description: "Extract text from an image file",
+				Examples:    []string{"ocr ~/scan.png in german", "extract text from ~/photo.jpg psm 6"},
+			},
+			{
+				Query:       "ocr table as [csv|markdown]",
+				Description: "Rebuild a table from the captured text",
+				Examples:    []string{"ocr table as csv", "ocr ~/invoice.png table markdown"},
+			},
+			{
+				Query:       "ocr and copy",
+				Description: "Extract text and copy it, or open the first link",
+				Examples:    []string{"ocr and copy", "ocr open link"},
+			},
+			{
+				Query:       "ocr [folder]",
+				Description: "Read every image and PDF in a folder into .txt files",
+				Examples:    []string{"ocr ~/Scans", "ocr ~/Scans again", "ocr ~/invoice.pdf"},
+			},
+			{
+				Query:       "ocr history [words]",
+				Description: "Search text from past captures",
+				Examples:    []string{"ocr history docker", "ocr history"},
+			},
+		},
+	}
+}
+
+func (cs *ConverterService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "converter",
+		Category:    "Media Conversion",
+		Icon:        "🎬",
+		Description: "Convert media files with ffmpeg",
+		Keywords:    append(append([]string{}, converterKeywords...), probeKeywords...),
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "convert [file] to [format]",
+				Description: "Convert audio, video and images",
+				Examples:    []string{"convert video.webm to mp4", "convert song.flac to mp3", "transcode movie.avi to mkv"},
+			},
+			{
+				Query:       "convert [file] to [format] [options]",
+				Description: "Scale, trim, change codec, bitrate or fps while converting",
+				Examples:    []string{"convert clip.mkv to gif 480p first 10 seconds", "convert ~/a.mkv to mp4 h265 crf 28 from 1:30 to 2:00"},
+			},
+			{
+				Query:       "extract audio from [file] as [format] [bitrate]",
+				Description: "Keep only the audio track",
+				Examples:    []string{"extract audio from talk.mp4 as opus 96k"},
+			},
+			{
+				Query:       "compress [file] for [preset] under [size]",
+				Description: "Two-pass encode that fits a file size, or apply a preset",
+				Examples:    []string{"compress ~/video.mp4 for discord under 25MB", "convert ~/talk.wav for podcast"},
+			},
+			{
+				Query:       "resize [image] to [size]",
+				Description: "Resize, re-encode and strip metadata from images",
+				Examples:    []string{"resize ~/photo.jpg to 50%", "convert ~/scan.tiff to jpg width 1200 quality 80"},
+			},
+			{
+				Query:       "convert [glob or folder] to [format] into [dir]",
+				Description: "Convert many files at once",
+				Examples:    []string{"convert ~/Music/*.flac to mp3", "convert all wav in ~/Recordings to opus into ~/opus skip existing"},
+			},
+			{
+				Query:       "probe [file]",
+				Description: "Show streams, codecs, duration, resolution and bitrate",
+				Examples:    []string{"probe ~/clip.mkv", "media info song.flac"},
+			},
+		},
+	}
+}
+
+func (ds *DocumentService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "document",
+		Category:    "Documents",
+		Icon:        "📄",
+		Description: "Convert documents with pandoc, LibreOffice and poppler",
+		Keywords:    []string{"convert"},
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "convert [document] to [format]",
+				Description: "Convert Markdown, HTML, office files and PDFs",
+				Examples:    []string{"convert notes.md to pdf", "convert report.docx to md"},
+			},
+			{
+				Query:       "convert [pdf] to [png|txt] pages [range]",
+				Description: "Render PDF pages to images or extract their text",
+				Examples:    []string{"convert slides.pdf to png pages 1-3", "convert paper.pdf to txt"},
+			},
+		},
+	}
+}
+
+func (llm *LLMService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "llm",
+		Category:    "General",
+		Icon:        "✨",
+		Description: "Query LLM for assistance",
+		Keywords:    []string{"llm", "ask", "help", "commands"},
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "[anything else]",
+				Description: "Ask the LLM, anything no service claims goes here",
+				Examples:    []string{"explain if then else in bash", "ask what does chmod 600 mean"},
+			},
+			{
+				Query:       "[step] | [step] or [step] then [step]",
+				Description: "Chain services, each step gets the previous step's output",
+				Examples:    []string{"find invoice.png then ocr | llm summarize", "ocr | llm translate to english"},
+			},
+			{
+				Query:       "help [topic]",
+				Description: "Show available commands and examples",
+				Examples:    []string{"help", "help ocr", "what can you do"},
+			},
+		},
+	}
+}
+
+func (cs *ClipboardService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "clipboard",
+		Category:    "Clipboard",
+		Icon:        "📋",
+		Description: "Search, paste and pin clipboard history",
+		Keywords:    []string{"clip", "clipboard", "cliphist"},
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "clip [words]",
+				Description: "Search the clipboard history, then paste, copy, pin or delete",
+				Examples:    []string{"clip docker", "clipboard history ssh", "clip"},
+			},
+			{
+				Query:       "clip | [step]",
+				Description: "Pass the latest entry, or the best match, to another service",
+				Examples:    []string{"clip | llm explain this", "clip png | ocr"},
+			},
+		},
+	}
+}
+
+func (cs *CalculatorService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "calculator",
+		Category:    "Calculator",
+		Icon:        "🧮",
+		Description: "Calculate and convert units offline",
+		Keywords:    []string{"calc", "calculate", "hex", "binary", "days until", "days since"},
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "[expression]",
+				Description: "Arithmetic, functions, hex and binary, percentages",
+				Examples:    []string{"(3 + 4) * 2^10", "15% of 240", "240 + 19%", "0xff & 0b1010", "sqrt(2) * pi"},
+			},
+			{
+				Query:       "[name] = [expression]",
+				Description: "Store a variable, ans is the last result",
+				Examples:    []string{"rate = 0.19", "1200 * rate", "ans / 12"},
+			},
+			{
+				Query:       "[number] [unit] to [unit]",
+				Description: "Convert length, mass, data sizes, temperature and time",
+				Examples:    []string{"convert 5 km to miles", "72 f to c", "700 MiB in GB", "90 min to hours"},
+			},
+			{
+				Query:       "[date] + [duration]",
+				Description: "Date arithmetic with today, now and YYYY-MM-DD",
+				Examples:    []string{"today + 90 days", "2026-12-24 - today", "days until 2026-12-24", "255 to hex"},
+			},
+		},
+	}
+}
+
+func (ls *LauncherService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "launcher",
+		Category:    "Apps",
+		Icon:        "🚀",
+		Description: "Launch applications and their actions",
+		Keywords:    launcherKeywords,
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "open [app]",
+				Description: "Launch an installed app or quick app, most used first",
+				Examples:    []string{"open gimp", "launch terminal", "start files"},
+			},
+			{
+				Query:       "open [app] [action]",
+				Description: "Run one of the app's actions",
+				Examples:    []string{"launch firefox private window", "open kitty new window"},
+			},
+		},
+	}
+}
+
+func (ds *DesktopService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "desktop",
+		Category:    "Desktop",
+		Icon:        "🖥️",
+		Description: "Control volume, themes, wallpaper and windows",
+		// Short words like "mute" or "close" are left out, typo correction
+		// would turn "cute" or "clone" into them
+		Keywords: []string{"volume", "themes", "wallpaper", "dark mode", "light mode", "workspace", "waybar"},
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "volume [0-100|up|down]",
+				Description: "Set, raise or lower the volume, or mute it",
+				Examples:    []string{"volume 40", "volume up", "mute"},
+			},
+			{
+				Query:       "switch to [theme] theme",
+				Description: "Apply a Hecate theme, or go back to colors from the wallpaper",
+				Examples:    []string{"switch to Nord theme", "themes", "switch to dynamic theme"},
+			},
+			{
+				Query:       "set wallpaper [image]",
+				Description: "Change the wallpaper with waypaper",
+				Examples:    []string{"set wallpaper ~/Pictures/wallpapers/forest.png", "random wallpaper"},
+			},
+			{
+				Query:       "enable [dark|light] mode",
+				Description: "Switch GTK and libadwaita apps between dark and light",
+				Examples:    []string{"enable dark mode", "light mode"},
+			},
+			{
+				Query:       "move [window] to workspace [n]",
+				Description: "Move, focus or close windows and switch workspaces",
+				Examples:    []string{"move firefox to workspace 3", "focus kitty", "workspace 2", "close spotify"},
+			},
+			{
+				Query:       "reload waybar",
+				Description: "Make waybar reread its config and styles",
+				Examples:    []string{"reload waybar"},
+			},
+		},
+	}
+}
+
+func (ss *ScreenshotService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "screenshot",
+		Category:    "Screenshots",
+		Icon:        "📸",
+		Description: "Take screenshots and record the screen",
+		// "record" and "recording" are left out, typo correction would turn
+		// "records" or "according" into them
+		Keywords: []string{"screenshot"},
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "screenshot [screen|window|region]",
+				Description: "Save a screenshot with grim, selecting a region with slurp",
+				Examples:    []string{"screenshot window", "screenshot region", "take a screenshot"},
+			},
+			{
+				Query:       "screenshot [region] to clipboard",
+				Description: "Copy a screenshot without saving it",
+				Examples:    []string{"screenshot region to clipboard", "screenshot window to clipboard"},
+			},
+			{
+				Query:       "record [screen|window|region] for [30s]",
+				Description: "Record with wf-recorder until the time is up or it's stopped",
+				Examples:    []string{"record screen for 30s", "record region with audio", "record screen for 10s then convert to gif"},
+			},
+			{
+				Query:       "stop recording",
+				Description: "End running recordings and keep what was recorded",
+				Examples:    []string{"stop recording"},
+			},
+		},
+	}
+}
+
+func (ns *NotesService) Help() ServiceHelp {
+	return ServiceHelp{
+		Service:     "notes",
+		Category:    "Notes",
+		Icon:        "📝",
+		Description: "Save and search notes and snippets",
+		// "note" and "notes" are left out, typo correction would turn "node"
+		// or "nodes" into them
+		Keywords: []string{"snippet", "snippets"},
+		Suggestions: []QuerySuggestion{
+			{
+				Query:       "note [text] [#tag]",
+				Description: "Save a quick note as Markdown, #words become tags",
+				Examples:    []string{"note buy cables #shopping", "note call the landlord about the heating"},
+			},
+			{
+				Query:       "snippet save [name]",
+				Description: "Save the clipboard as a snippet, or give the text after a colon",
+				Examples:    []string{"snippet save docker prune command #docker", "snippet save ssh tunnel: ssh -L 8080:localhost:80 server"},
+			},
+			{
+				Query:       "notes [terms] [#tag]",
+				Description: "Search notes and snippets, then copy or paste one",
+				Examples:    []string{"notes docker", "snippets #git", "notes"},
+			},
+		},
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// unit is a measure of one dimension; factor converts it to the dimension's
+// base unit (meter, gram, byte, second). Temperatures use offsets instead.
+type unit struct {
+	dimension string
+	factor    float64
+	symbol    string
+}
+
+// units maps lowercase names, plurals and symbols to their unit. Data sizes
+// follow SI: "kb" is 1000 bytes and "kib" 1024.
+var units = map[string]unit{}
+
+func init() {
+	define := func(dimension string, factor float64, symbol string, names ...string) {
+		for _, name := range append(names, symbol) {
+			units[strings.ToLower(name)] = unit{dimension: dimension, factor: factor, symbol: symbol}
+		}
+	}
+
+	define("length", 1e-3, "mm", "millimeter", "millimeters", "millimetre", "millimetres")
+	define("length", 1e-2, "cm", "centimeter", "centimeters", "centimetre", "centimetres")
+	define("length", 1, "m", "meter", "meters", "metre", "metres")
+	define("length", 1e3, "km", "kilometer", "kilometers", "kilometre", "kilometres", "kms")
+	define("length", 0.0254, "in", "inch", "inches")
+	define("length", 0.3048, "ft", "foot", "feet")
+	define("length", 0.9144, "yd", "yard", "yards")
+	define("length", 1609.344, "mi", "mile", "miles")
+	define("length", 1852, "nmi", "nautical mile", "nautical miles")
+
+	define("mass", 1e-3, "mg", "milligram", "milligrams")
+	define("mass", 1, "g", "gram", "grams")
+	define("mass", 1e3, "kg", "kilogram", "kilograms", "kilo", "kilos")
+	define("mass", 1e6, "t", "tonne", "tonnes", "metric ton", "metric tons")
+	define("mass", 28.349523125, "oz", "ounce", "ounces")
+	define("mass", 453.59237, "lb", "lbs", "pound", "pounds")
+	define("mass", 6350.29318, "st", "stone", "stones")
+
+	define("data", 0.125, "bit", "bits")
+	define("data", 1, "B", "byte", "bytes")
+	define("data", 1e3, "kB", "kilobyte", "kilobytes")
+	define("data", 1e6, "MB", "megabyte", "megabytes")
+	define("data", 1e9, "GB", "gigabyte", "gigabytes")
+	define("data", 1e12, "TB", "terabyte", "terabytes")
+	define("data", 1e15, "PB", "petabyte", "petabytes")
+	define("data", 1<<10, "KiB", "kibibyte", "kibibytes")
+	define("data", 1<<20, "MiB", "mebibyte", "mebibytes")
+	define("data", 1<<30, "GiB", "gibibyte", "gibibytes")
+	define("data", 1<<40, "TiB", "tebibyte", "tebibytes")
+	define("data", 1e3/8, "kbit", "kilobit", "kilobits")
+	define("data", 1e6/8, "Mbit", "megabit", "megabits")
+	define("data", 1e9/8, "Gbit", "gigabit", "gigabits")
+
+	define("temperature", 0, "°C", "c", "celsius", "degrees celsius", "degrees c")
+	define("temperature", 0, "°F", "f", "fahrenheit", "degrees fahrenheit", "degrees f")
+	define("temperature", 0, "K", "k", "kelvin", "kelvins")
+
+	define("time", 1e-3, "ms", "millisecond", "milliseconds")
+	define("time", 1, "s", "sec", "secs", "second", "seconds")
+	define("time", 60, "min", "mins", "minute", "minutes")
+	define("time", 3600, "h", "hr", "hrs", "hour", "hours")
+	define("time", 86400, "d", "day", "days")
+	define("time", 7*86400, "wk", "week", "weeks")
+	// Calendar averages, a month is a twelfth of a Gregorian year
+	define("time", 30.436875*86400, "mo", "month", "months")
+	define("time", 365.2425*86400, "yr", "year", "years")
+}
+
+func lookupUnit(name string) (unit, bool) {
+	u, ok := units[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		u, ok = units[strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "°"))]
+	}
+	return u, ok
+}
+
+// convertUnits answers "5 km to miles"
+func convertUnits(result CalculatorResult, amount, fromName, toName string) (CalculatorResult, error) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(amount, ",", ""), 64)
+	if err != nil {
+		return result, fmt.Errorf("invalid number %s", amount)
+	}
+	from, ok := lookupUnit(fromName)
+	if !ok {
+		return result, fmt.Errorf("unknown unit %q", fromName)
+	}
+	to, ok := lookupUnit(toName)
+	if !ok {
+		return result, fmt.Errorf("unknown unit %q", toName)
+	}
+	if from.dimension != to.dimension {
+		return result, fmt.Errorf("can't convert %s (%s) to %s (%s)", fromName, from.dimension, toName, to.dimension)
+	}
+
+	var converted float64
+	if from.dimension == "temperature" {
+		converted = fromKelvin(toKelvin(value, from.symbol), to.symbol)
+	} else {
+		converted = value * from.factor / to.factor
+	}
+	if err := checkFinite(converted); err != nil {
+		return result, err
+	}
+
+	result.Kind = "unit"
+	result.Value = converted
+	result.Result = fmt.Sprintf("%s %s", formatNumber(converted), to.symbol)
+	return result, nil
+}
+
+func toKelvin(value float64, symbol string) float64 {
+	switch symbol {
+	case "°C":
+		return value + 273.15
+	case "°F":
+		return (value-32)*5/9 + 273.15
+	}
+	return value
+}
+
+func fromKelvin(value float64, symbol string) float64 {
+	switch symbol {
+	case "°C":
+		return value - 273.15
+	case "°F":
+		return (value-273.15)*9/5 + 32
+	}
+	return value
+}
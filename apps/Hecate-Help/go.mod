@@ -5,6 +5,7 @@ go 1.22.0
 require (
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/wailsapp/wails/v2 v2.10.2
+	shared v0.0.0
 )
 
 require (
@@ -38,3 +39,5 @@ require (
 )
 
 // replace github.com/wailsapp/wails/v2 v2.10.2 => /home/dawu/go/pkg/mod
+
+replace shared => ../shared